@@ -7,6 +7,11 @@ import (
 
 	"github.com/rwrrioe/pythia/backend/internal/app"
 	config "github.com/rwrrioe/pythia/backend/internal/config/grpconn"
+	idempotencycfg "github.com/rwrrioe/pythia/backend/internal/config/idempotency"
+	routingcfg "github.com/rwrrioe/pythia/backend/internal/config/ocr_routing"
+	preprocesscfg "github.com/rwrrioe/pythia/backend/internal/config/preprocess"
+	queuecfg "github.com/rwrrioe/pythia/backend/internal/config/queue"
+	expirycfg "github.com/rwrrioe/pythia/backend/internal/config/session_expiry"
 )
 
 const (
@@ -34,6 +39,12 @@ func main() {
 		panic("failed to fetch ocr config")
 	}
 
+	ocrRouting, err := routingcfg.FetchConfig(ocrCfg.Addr)
+	if err != nil {
+		log.Error("failed to fetch ocr routing config")
+		panic("failed to fetch ocr routing config")
+	}
+
 	ssoCfg, err := config.FetchConfig(config.ConfigAttr{
 		CfgType: config.SSO,
 	})
@@ -42,7 +53,31 @@ func main() {
 		panic("failed to fetch ocr config")
 	}
 
-	app, err := app.New(ctx, log, appSecret, ssoCfg, ocrCfg)
+	imgCfg, err := preprocesscfg.FetchConfig()
+	if err != nil {
+		log.Error("failed to fetch image preprocessing config")
+		panic("failed to fetch image preprocessing config")
+	}
+
+	expiryCfg, err := expirycfg.FetchConfig()
+	if err != nil {
+		log.Error("failed to fetch session expiry config")
+		panic("failed to fetch session expiry config")
+	}
+
+	queueCfg, err := queuecfg.FetchConfig()
+	if err != nil {
+		log.Error("failed to fetch job queue config")
+		panic("failed to fetch job queue config")
+	}
+
+	idemCfg, err := idempotencycfg.FetchConfig()
+	if err != nil {
+		log.Error("failed to fetch idempotency config")
+		panic("failed to fetch idempotency config")
+	}
+
+	app, err := app.New(ctx, log, appSecret, ssoCfg, ocrCfg, ocrRouting, imgCfg, expiryCfg, queueCfg, idemCfg)
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/google/uuid"
+	"github.com/rwrrioe/pythia/backend/internal/auth/authn"
+	"github.com/rwrrioe/pythia/backend/internal/domain/requests"
+	"github.com/rwrrioe/pythia/backend/internal/lib/vocab_export"
+	service "github.com/rwrrioe/pythia/backend/internal/services"
+	"github.com/rwrrioe/pythia/backend/internal/storage/postgresql"
+)
+
+// export writes the vocabulary of a user in one of the plain formats, for support and data requests.
+// It reads the database settings from the environment like the app.
+//
+//	export -user 42 -format csv -o words.csv
+//	export -user 42 -format quizlet -deck <uuid> -term-sep " - " -card-sep semicolon
+func main() {
+	var (
+		uid      = flag.Int64("user", 0, "id of the user whose vocabulary is exported")
+		format   = flag.String("format", "csv", "one of "+strings.Join(vocab_export.Names(), ", "))
+		deckId   = flag.String("deck", "", "export the cards of this deck")
+		filterId = flag.String("filter", "", "export the cards of this smart filter")
+		query    = flag.String("q", "", "export the cards matching this card query")
+		termSep  = flag.String("term-sep", "", "quizlet separator between term and definition, tab by default")
+		cardSep  = flag.String("card-sep", "", "quizlet separator between cards, newline by default")
+		out      = flag.String("o", "", "output file, stdout by default")
+	)
+	flag.Parse()
+
+	// stdout may carry the export, the log goes to stderr
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	if err := run(log, *uid, requests.ExportVocabulary{
+		Format:  *format,
+		Query:   *query,
+		TermSep: *termSep,
+		CardSep: *cardSep,
+	}, *deckId, *filterId, *out); err != nil {
+		log.Error("export failed", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+}
+
+func run(log *slog.Logger, uid int64, req requests.ExportVocabulary, deckId, filterId, out string) error {
+	if uid <= 0 {
+		return fmt.Errorf("-user is required")
+	}
+	for _, id := range []struct {
+		flag, value string
+		dst         **uuid.UUID
+	}{{"deck", deckId, &req.DeckId}, {"filter", filterId, &req.FilterId}} {
+		if id.value == "" {
+			continue
+		}
+		v, err := uuid.Parse(id.value)
+		if err != nil {
+			return fmt.Errorf("-%s: %w", id.flag, err)
+		}
+		*id.dst = &v
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	pool, err := postgresql.New(ctx)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	export := service.NewExportService(
+		postgresql.NewFlashcardStorage(pool),
+		postgresql.NewDeckStorage(pool),
+		postgresql.NewFilterStorage(pool),
+		pool,
+	)
+
+	// the services read the user from the context as set by the auth middleware
+	ctx = authn.WithUserID(ctx, uid)
+
+	e, err := export.Vocabulary(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	// the formats buffer their output, the file is written as is
+	var w io.WriteCloser = os.Stdout
+	if out != "" {
+		if w, err = os.Create(out); err != nil {
+			return err
+		}
+	}
+
+	n, err := e.Write(ctx, w)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		if out != "" {
+			os.Remove(out)
+		}
+		return err
+	}
+
+	log.Info("vocabulary exported", slog.Int64("user", uid), slog.String("format", e.Format.Name), slog.Int("cards", n))
+
+	return nil
+}
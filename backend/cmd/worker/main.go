@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rwrrioe/pythia/backend/internal/app"
+	config "github.com/rwrrioe/pythia/backend/internal/config/grpconn"
+	routingcfg "github.com/rwrrioe/pythia/backend/internal/config/ocr_routing"
+	preprocesscfg "github.com/rwrrioe/pythia/backend/internal/config/preprocess"
+	queuecfg "github.com/rwrrioe/pythia/backend/internal/config/queue"
+)
+
+const (
+	envLocal = "local"
+	envDev   = "dev"
+	envProd  = "prod"
+)
+
+// worker runs the job queue consumers without the http api
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	env := os.Getenv("LOGGER_ENV")
+	log := setupLogger(env)
+	log.Info("starting worker", slog.Any("env", env))
+
+	ocrCfg, err := config.FetchConfig(config.ConfigAttr{
+		CfgType: config.OCR,
+	})
+	if err != nil {
+		log.Error("failed to fetch ocr config")
+		panic("failed to fetch ocr config")
+	}
+
+	ocrRouting, err := routingcfg.FetchConfig(ocrCfg.Addr)
+	if err != nil {
+		log.Error("failed to fetch ocr routing config")
+		panic("failed to fetch ocr routing config")
+	}
+
+	imgCfg, err := preprocesscfg.FetchConfig()
+	if err != nil {
+		log.Error("failed to fetch image preprocessing config")
+		panic("failed to fetch image preprocessing config")
+	}
+
+	queueCfg, err := queuecfg.FetchConfig()
+	if err != nil {
+		log.Error("failed to fetch job queue config")
+		panic("failed to fetch job queue config")
+	}
+
+	worker, err := app.NewWorker(ctx, log, ocrCfg, ocrRouting, imgCfg, queueCfg)
+	if err != nil {
+		panic(err)
+	}
+
+	// jobs interrupted by the shutdown stay pending and are claimed again after the visibility timeout
+	if err := worker.Run(ctx); err != nil {
+		panic(err)
+	}
+	log.Info("worker stopped")
+}
+
+func setupLogger(env string) *slog.Logger {
+	var log *slog.Logger
+
+	switch env {
+	case envLocal:
+		log = slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		)
+	case envDev:
+		log = slog.New(
+			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		)
+	case envProd:
+		log = slog.New(
+			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}),
+		)
+	}
+
+	return log
+}
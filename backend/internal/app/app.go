@@ -2,28 +2,35 @@ package app
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/rwrrioe/pythia/backend/internal/auth/authn"
-	"github.com/rwrrioe/pythia/backend/internal/auth/authz"
-	ocr_grpc_client "github.com/rwrrioe/pythia/backend/internal/clients/ocr/grpc"
+	ocr_router "github.com/rwrrioe/pythia/backend/internal/clients/ocr/router"
 	sso_grpc_client "github.com/rwrrioe/pythia/backend/internal/clients/sso/grpc"
 	config "github.com/rwrrioe/pythia/backend/internal/config/grpconn"
+	idempotencycfg "github.com/rwrrioe/pythia/backend/internal/config/idempotency"
+	routingcfg "github.com/rwrrioe/pythia/backend/internal/config/ocr_routing"
+	preprocesscfg "github.com/rwrrioe/pythia/backend/internal/config/preprocess"
+	queuecfg "github.com/rwrrioe/pythia/backend/internal/config/queue"
+	expirycfg "github.com/rwrrioe/pythia/backend/internal/config/session_expiry"
+	"github.com/rwrrioe/pythia/backend/internal/lib/logger/sl"
 	service "github.com/rwrrioe/pythia/backend/internal/services"
-	"github.com/rwrrioe/pythia/backend/internal/storage/postgresql"
-	taskstorage "github.com/rwrrioe/pythia/backend/internal/storage/redis/task_storage"
 	grpcconn "github.com/rwrrioe/pythia/backend/internal/transport/grpc"
 	"github.com/rwrrioe/pythia/backend/internal/transport/rest"
+	rest_handlers "github.com/rwrrioe/pythia/backend/internal/transport/rest/handlers"
+	"github.com/rwrrioe/pythia/backend/internal/transport/rest/idempotency"
 	"github.com/rwrrioe/pythia/backend/internal/transport/ws"
 	hub "github.com/rwrrioe/pythia/backend/internal/transport/ws/ws_hub"
 )
 
 type App struct {
-	ocrClient    *ocr_grpc_client.Client
+	ocrRouter    *ocr_router.Router
 	ssoClient    *sso_grpc_client.Client
 	wsHandlers   *ws.Handlers
 	restHandlers *rest.Handlers
@@ -35,6 +42,11 @@ func New(
 	log *slog.Logger,
 	appSecret string,
 	ssoConf, ocrConf *config.Config,
+	ocrRouting *routingcfg.Config,
+	imgConf *preprocesscfg.Config,
+	expiryConf *expirycfg.Config,
+	queueConf *queuecfg.Config,
+	idemConf *idempotencycfg.Config,
 ) (*App, error) {
 	const op = "App.New"
 
@@ -42,34 +54,13 @@ func New(
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", idempotency.Header},
+		ExposeHeaders:    []string{"Content-Length", idempotency.ReplayedHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
-	//init storage and repos
-	redisClient, err := taskstorage.NewRedisStorage(ctx, "redis:6379", time.Hour)
-	if err != nil {
-		return nil, fmt.Errorf("%s:%w", op, err)
-	}
-
-	pool, err := postgresql.New(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("%s:%w", op, err)
-	}
-
-	deckStorage := postgresql.NewDeckStorage(pool)
-	flStorage := postgresql.NewFlashcardStorage(pool)
-	ssStorage := postgresql.NewSessionStorage(pool)
-	txm := postgresql.NewTxManager(pool)
-	//init grpc-clients
-
-	ocrConn, err := grpcconn.New(log, grpcconn.Config{
-		Addr:         ocrConf.Addr,
-		Timeout:      ocrConf.Timeout,
-		RetriesCount: ocrConf.RetriesCount,
-	})
+	c, err := newCore(ctx, log, ocrConf, ocrRouting, imgConf, queueConf)
 	if err != nil {
 		return nil, fmt.Errorf("%s:%w", op, err)
 	}
@@ -83,53 +74,57 @@ func New(
 		return nil, fmt.Errorf("%s:%w", op, err)
 	}
 
-	ocrClient := ocr_grpc_client.New(ocrConn, log)
 	ssoClient := sso_grpc_client.New(ssoConn, log)
 
 	// init services
 
 	sso := authn.NewSSO(ssoClient, 1)
-	ocr := service.NewOCRService(ocrClient)
-	learn := service.NewLearnService(4)
-	cards := service.NewCardsService(flStorage, deckStorage, pool)
-	transl, err := service.NewTranslateService(ctx, "gemini-2.5-flash-lite")
-	stats := service.NewStatsService(ssStorage, deckStorage, flStorage, txm)
-	lib := service.NewLibraryService(ssStorage, pool, txm)
-	if err != nil {
-		return nil, fmt.Errorf("%s:%w", op, err)
-	}
-
-	authorizer := authz.NewAuthorizer(redisClient, log)
-
-	session, err := service.NewSessionService(
-		ocr,
-		transl,
-		learn,
-		cards,
-		redisClient,
-		txm,
-		pool,
-		ssStorage,
-		deckStorage,
-		flStorage,
-		authorizer,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("%s:%w", op, err)
+	stats := service.NewStatsService(c.ssStorage, c.deckStorage, c.flStorage, c.txm)
+	lib := service.NewLibraryService(c.ssStorage, c.docStorage, c.pool, c.txm)
+	decks := service.NewDecksService(c.deckStorage, c.flStorage, c.pool, c.txm)
+	filters := service.NewFiltersService(c.fltStorage, c.flStorage, c.session.Learn, c.pool, c.txm)
+	export := service.NewExportService(c.flStorage, c.deckStorage, c.fltStorage, c.pool)
+	jobs := service.NewJobService(c.queue, c.uploads, c.session)
+
+	if queueConf.WorkersEnabled {
+		w := newWorker(log, c, queueConf)
+		go func() {
+			if err := w.Run(ctx); err != nil {
+				log.Error("job workers stopped", sl.Err(err))
+			}
+		}()
 	}
 
 	// init handlers
 	hub := hub.NewWebSocketHub()
+
+	// events published by any instance reach the websockets connected to this one
+	go func() {
+		err := c.redis.SubscribeSessionEvents(ctx, func(sessionId uuid.UUID, payload json.RawMessage) {
+			hub.Notify(sessionId, payload)
+		})
+		if err != nil && ctx.Err() == nil {
+			log.Error("session events subscription stopped", sl.Err(err))
+		}
+	}()
+
+	expiry := service.NewExpiryScheduler(log, c.session, c.redis, expiryConf.Interval, expiryConf.Lease, expiryConf.Batch)
+	go expiry.Run(ctx)
+
 	wsHandlers := ws.New(hub)
 	ws.RegisterRoutes(router, wsHandlers)
-	restHandlers := rest.New(log, session, lib, cards, stats, sso, hub, redisClient)
+	restHandlers := rest.New(log, c.session, lib, c.cards, decks, filters, export, c.imports, jobs, stats, sso, hub, c.redis)
 	authMiddleware := authn.New(log, appSecret)
 	requireAuthMiddleware := authn.NewRequireAuth(log)
 
-	rest.RegisterRoutes(router, restHandlers, authMiddleware(), requireAuthMiddleware())
+	// the largest body an idempotent route takes, a full multi-page upload with room for the form
+	maxBody := max(rest_handlers.MaxUploadPages*imgConf.MaxBytes, service.MaxImportBytes) + rest_handlers.MaxFormOverhead
+	idempotencyMiddleware := idempotency.New(log, c.redis, idemConf.TTL, idemConf.LockTTL, maxBody)
+
+	rest.RegisterRoutes(router, restHandlers, authMiddleware(), requireAuthMiddleware(), idempotencyMiddleware())
 
 	return &App{
-		ocrClient:    ocrClient,
+		ocrRouter:    c.ocrRouter,
 		ssoClient:    ssoClient,
 		wsHandlers:   wsHandlers,
 		restHandlers: restHandlers,
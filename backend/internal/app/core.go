@@ -0,0 +1,178 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rwrrioe/pythia/backend/internal/auth/authz"
+	ocr_grpc_client "github.com/rwrrioe/pythia/backend/internal/clients/ocr/grpc"
+	ocr_router "github.com/rwrrioe/pythia/backend/internal/clients/ocr/router"
+	config "github.com/rwrrioe/pythia/backend/internal/config/grpconn"
+	routingcfg "github.com/rwrrioe/pythia/backend/internal/config/ocr_routing"
+	preprocesscfg "github.com/rwrrioe/pythia/backend/internal/config/preprocess"
+	queuecfg "github.com/rwrrioe/pythia/backend/internal/config/queue"
+	preprocessor "github.com/rwrrioe/pythia/backend/internal/lib/img_preprocessor"
+	"github.com/rwrrioe/pythia/backend/internal/lib/job_queue"
+	"github.com/rwrrioe/pythia/backend/internal/lib/lang_detector"
+	service "github.com/rwrrioe/pythia/backend/internal/services"
+	disk_storage "github.com/rwrrioe/pythia/backend/internal/storage/disk"
+	"github.com/rwrrioe/pythia/backend/internal/storage/postgresql"
+	taskstorage "github.com/rwrrioe/pythia/backend/internal/storage/redis/task_storage"
+	grpcconn "github.com/rwrrioe/pythia/backend/internal/transport/grpc"
+)
+
+// queueBlock is how long an idle worker waits on the stream before looking for abandoned jobs
+const queueBlock = 5 * time.Second
+
+// uploadsCleanInterval is how often the expired uploads are looked for
+const uploadsCleanInterval = time.Hour
+
+// core is shared by the api and the worker process
+type core struct {
+	redis       *taskstorage.RedisStorage
+	uploads     *disk_storage.UploadStorage
+	pool        *pgxpool.Pool
+	txm         *postgresql.TxManager
+	deckStorage *postgresql.DeckStorage
+	flStorage   *postgresql.FlashCardStorage
+	ssStorage   *postgresql.SessionStorage
+	docStorage  *postgresql.DocumentStorage
+	fltStorage  *postgresql.FilterStorage
+	ocrRouter   *ocr_router.Router
+	queue       *job_queue.Queue
+	cards       *service.FlashCardsService
+	session     *service.SessionService
+	imports     *service.ImportService
+}
+
+func newCore(
+	ctx context.Context,
+	log *slog.Logger,
+	ocrConf *config.Config,
+	ocrRouting *routingcfg.Config,
+	imgConf *preprocesscfg.Config,
+	queueConf *queuecfg.Config,
+) (*core, error) {
+	const op = "App.newCore"
+
+	//init storage and repos
+	redisClient, err := taskstorage.NewRedisStorage(ctx, "redis:6379", time.Hour)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	uploads, err := disk_storage.NewUploadStorage(log, queueConf.UploadDir, queueConf.UploadTTL)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	go uploads.Clean(ctx, uploadsCleanInterval)
+
+	pool, err := postgresql.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	deckStorage := postgresql.NewDeckStorage(pool)
+	flStorage := postgresql.NewFlashcardStorage(pool)
+	ssStorage := postgresql.NewSessionStorage(pool)
+	ocrCache := postgresql.NewOCRCacheStorage(pool)
+	docStorage := postgresql.NewDocumentStorage(pool)
+	fltStorage := postgresql.NewFilterStorage(pool)
+	userStorage := postgresql.NewUserStorage(pool)
+	txm := postgresql.NewTxManager(pool)
+	//init grpc-clients
+
+	ocrEngines := make([]ocr_router.Engine, 0, len(ocrRouting.Engines))
+	for _, e := range ocrRouting.Engines {
+		conn, err := grpcconn.New(log, grpcconn.Config{
+			Addr:         e.Addr,
+			Timeout:      ocrConf.Timeout,
+			RetriesCount: ocrConf.RetriesCount,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+
+		ocrEngines = append(ocrEngines, ocr_grpc_client.New(e.Name, conn, log))
+	}
+
+	ocrRouter, err := ocr_router.New(log, ocrEngines, ocrRouting.Routes, ocrRouting.MinConfidence)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	go ocrRouter.WatchHealth(ctx, ocrRouting.HealthInterval)
+
+	// init services
+
+	imgPreprocessor := preprocessor.New(preprocessor.Config{
+		MaxBytes:    imgConf.MaxBytes,
+		MaxPixels:   imgConf.MaxPixels,
+		MaxSide:     imgConf.MaxSide,
+		Grayscale:   imgConf.Grayscale,
+		Contrast:    imgConf.Contrast,
+		JPEGQuality: imgConf.JPEGQuality,
+	})
+	ocr := service.NewOCRService(log, ocrRouter, imgPreprocessor, ocrCache, pool, ocrRouting.MinConfidence, ocrRouting.DetectLang)
+	learn := service.NewLearnService(4)
+	cards := service.NewCardsService(flStorage, deckStorage, pool, txm)
+	transl, err := service.NewTranslateService(ctx, "gemini-2.5-flash-lite")
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	sessions := service.NewSessionRepository(redisClient, ssStorage, deckStorage, flStorage, pool)
+	authorizer := authz.NewAuthorizer(sessions, log)
+
+	detector, err := lang_detector.New()
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	session, err := service.NewSessionService(
+		ocr,
+		transl,
+		learn,
+		cards,
+		redisClient,
+		sessions,
+		txm,
+		pool,
+		ssStorage,
+		deckStorage,
+		flStorage,
+		docStorage,
+		userStorage,
+		authorizer,
+		detector,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	queue := job_queue.New(redisClient.Client(), log, job_queue.Config{
+		BackoffBase: queueConf.BackoffBase,
+		BackoffMax:  queueConf.BackoffMax,
+		Block:       queueBlock,
+	})
+	imports := service.NewImportService(flStorage, deckStorage, redisClient, uploads, queue, pool, txm)
+
+	return &core{
+		redis:       redisClient,
+		uploads:     uploads,
+		pool:        pool,
+		txm:         txm,
+		deckStorage: deckStorage,
+		flStorage:   flStorage,
+		ssStorage:   ssStorage,
+		docStorage:  docStorage,
+		fltStorage:  fltStorage,
+		ocrRouter:   ocrRouter,
+		queue:       queue,
+		cards:       cards,
+		session:     session,
+		imports:     imports,
+	}, nil
+}
@@ -0,0 +1,121 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	config "github.com/rwrrioe/pythia/backend/internal/config/grpconn"
+	routingcfg "github.com/rwrrioe/pythia/backend/internal/config/ocr_routing"
+	preprocesscfg "github.com/rwrrioe/pythia/backend/internal/config/preprocess"
+	queuecfg "github.com/rwrrioe/pythia/backend/internal/config/queue"
+	"github.com/rwrrioe/pythia/backend/internal/lib/job_queue"
+	service "github.com/rwrrioe/pythia/backend/internal/services"
+	"github.com/rwrrioe/pythia/backend/internal/workers"
+)
+
+type stage struct {
+	name    string
+	cfg     job_queue.StageConfig
+	handler job_queue.Handler
+}
+
+// Worker consumes the job queue, either inside the api process or on its own
+type Worker struct {
+	log    *slog.Logger
+	queue  *job_queue.Queue
+	stages []stage
+}
+
+// NewWorker builds a standalone worker process
+func NewWorker(
+	ctx context.Context,
+	log *slog.Logger,
+	ocrConf *config.Config,
+	ocrRouting *routingcfg.Config,
+	imgConf *preprocesscfg.Config,
+	queueConf *queuecfg.Config,
+) (*Worker, error) {
+	const op = "App.NewWorker"
+
+	c, err := newCore(ctx, log, ocrConf, ocrRouting, imgConf, queueConf)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return newWorker(log, c, queueConf), nil
+}
+
+func newWorker(log *slog.Logger, c *core, queueConf *queuecfg.Config) *Worker {
+	return &Worker{
+		log:   log,
+		queue: c.queue,
+		stages: []stage{
+			{
+				name: service.StageOCR,
+				cfg: job_queue.StageConfig{
+					Concurrency: queueConf.OCR.Concurrency,
+					Visibility:  queueConf.OCR.Visibility,
+					MaxAttempts: queueConf.MaxAttempts,
+				},
+				handler: workers.NewOCRWorker(log, c.session, c.uploads, c.redis),
+			},
+			{
+				name: service.StageTranslate,
+				cfg: job_queue.StageConfig{
+					Concurrency: queueConf.Translate.Concurrency,
+					Visibility:  queueConf.Translate.Visibility,
+					MaxAttempts: queueConf.MaxAttempts,
+				},
+				handler: workers.NewTranslateWorker(log, c.session, c.redis),
+			},
+			{
+				name: service.StageExamples,
+				cfg: job_queue.StageConfig{
+					Concurrency: queueConf.Examples.Concurrency,
+					Visibility:  queueConf.Examples.Visibility,
+					MaxAttempts: queueConf.MaxAttempts,
+				},
+				handler: workers.NewExamplesWorker(log, c.session, c.redis),
+			},
+			{
+				name: service.StageImport,
+				cfg: job_queue.StageConfig{
+					Concurrency: queueConf.Import.Concurrency,
+					Visibility:  queueConf.Import.Visibility,
+					MaxAttempts: queueConf.MaxAttempts,
+				},
+				handler: workers.NewImportWorker(log, c.imports),
+			},
+		},
+	}
+}
+
+// Run serves every stage until ctx is done, unfinished jobs are picked up again after a restart
+func (w *Worker) Run(ctx context.Context) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+
+	for _, st := range w.stages {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := w.queue.Run(ctx, st.name, st.cfg, st.handler); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("App.Worker.Run:%w", errs[0])
+	}
+
+	return nil
+}
@@ -48,8 +48,7 @@ func New(
 
 			log.Info("user authorized", slog.Any("claims", claims))
 
-			ctx := context.WithValue(c.Request.Context(), uidKey, claims.UserId)
-			c.Request = c.Request.WithContext(ctx)
+			c.Request = c.Request.WithContext(WithUserID(c.Request.Context(), claims.UserId))
 			c.Next()
 		}
 	}
@@ -65,6 +64,11 @@ func extractBearerToken(authHeader string) string {
 	return strings.TrimSpace(splitToken[1])
 }
 
+// WithUserID returns ctx acting for the user, as the middleware sets it for an authorized request
+func WithUserID(ctx context.Context, uid int64) context.Context {
+	return context.WithValue(ctx, uidKey, uid)
+}
+
 func UIDFromContext(ctx context.Context) (int64, bool) {
 	uid, ok := ctx.Value(uidKey).(int64)
 	return uid, ok
@@ -18,22 +18,27 @@ type AuthorizeService interface {
 	CanAccessSession(ctx context.Context, uid int64, sessionId uuid.UUID) error
 }
 
+// SessionReader is the redis session storage or the postgres-backed read-through repository
+type SessionReader interface {
+	GetSession(ctx context.Context, sessionId uuid.UUID) (*taskstorage.SessionDTO, bool, error)
+}
+
 type authorizer struct {
-	redis *taskstorage.RedisStorage
-	log   *slog.Logger
+	sessions SessionReader
+	log      *slog.Logger
 }
 
-func NewAuthorizer(redis *taskstorage.RedisStorage, log *slog.Logger) AuthorizeService {
+func NewAuthorizer(sessions SessionReader, log *slog.Logger) AuthorizeService {
 	return &authorizer{
-		redis: redis,
-		log:   log,
+		sessions: sessions,
+		log:      log,
 	}
 }
 
 func (a *authorizer) CanAccessSession(ctx context.Context, uid int64, sessionId uuid.UUID) error {
 	const op = "authz.authorizer.CanAccessSession"
 
-	ss, ok, err := a.redis.GetSession(ctx, sessionId)
+	ss, ok, err := a.sessions.GetSession(ctx, sessionId)
 	if err != nil {
 		return fmt.Errorf("%s:%w", op, err)
 	} else if !ok {
@@ -2,30 +2,74 @@ package ocr_grpc_client
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 
-	ocrv1 "github.com/rwrrioe/pythia_protos/gen/go/ocr"
+	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
+	ocrv1 "github.com/rwrrioe/pythia/shared/gen/go/ocr"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	healthv1 "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
 )
 
 type Client struct {
-	api ocrv1.OCRServiceClient
-	log *slog.Logger
+	name   string
+	cc     *grpc.ClientConn
+	api    ocrv1.OCRServiceClient
+	health healthv1.HealthClient
+	log    *slog.Logger
 }
 
 func New(
+	name string,
 	cc *grpc.ClientConn,
 	log *slog.Logger,
 ) *Client {
 	grpcClient := ocrv1.NewOCRServiceClient(cc)
 
 	return &Client{
-		api: grpcClient,
-		log: log,
+		name:   name,
+		cc:     cc,
+		api:    grpcClient,
+		health: healthv1.NewHealthClient(cc),
+		log:    log,
 	}
 }
 
-func (c *Client) ProcessImage(ctx context.Context, imageData []byte, lang string) ([]string, error) {
+func (c *Client) Name() string {
+	return c.name
+}
+
+// HealthCheck asks the standard gRPC health service, servers without it are healthy once the connection is ready
+func (c *Client) HealthCheck(ctx context.Context) error {
+	const op = "ocr_grpc.HealthCheck"
+
+	resp, err := c.health.Check(ctx, &healthv1.HealthCheckRequest{
+		Service: ocrv1.OCRService_ServiceDesc.ServiceName,
+	})
+	if err == nil {
+		if resp.GetStatus() != healthv1.HealthCheckResponse_SERVING {
+			return fmt.Errorf("%s: %s is %s", op, c.name, resp.GetStatus())
+		}
+		return nil
+	}
+
+	if status.Code(err) != codes.Unimplemented {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if st := c.cc.GetState(); st != connectivity.Ready {
+		return fmt.Errorf("%s: %s connection is %s", op, c.name, st)
+	}
+
+	return nil
+}
+
+func (c *Client) ProcessImage(ctx context.Context, imageData []byte, lang string) (*entities.OCRResult, error) {
 	resp, err := c.api.Recognize(ctx, &ocrv1.OCRRequest{
 		ImageData: []byte(imageData),
 		Lang:      lang,
@@ -34,5 +78,129 @@ func (c *Client) ProcessImage(ctx context.Context, imageData []byte, lang string
 		return nil, err
 	}
 
-	return resp.Text, nil
+	res := toOCRResult(resp)
+	res.Engine = c.name
+
+	return res, nil
+}
+
+// streamChunkSize keeps every message far below the default 4MB gRPC limit
+const streamChunkSize = 1 << 20
+
+// ProcessPages uploads the pages in chunks over RecognizeStream, onPage is called as soon as a page is recognized
+func (c *Client) ProcessPages(
+	ctx context.Context,
+	pages [][]byte,
+	lang string,
+	onPage func(page int, res *entities.OCRResult),
+) error {
+	const op = "ocr_grpc.ProcessPages"
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := c.api.RecognizeStream(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	sendErr := make(chan error, 1)
+	go func() {
+		sendErr <- sendPages(stream, pages, lang)
+	}()
+
+	received := 0
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		res := toOCRResult(msg.GetResult())
+		res.Engine = c.name
+
+		onPage(int(msg.GetPage()), res)
+		received++
+	}
+
+	if err := <-sendErr; err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if received != len(pages) {
+		return fmt.Errorf("%s: got %d of %d pages", op, received, len(pages))
+	}
+
+	return nil
+}
+
+func sendPages(stream ocrv1.OCRService_RecognizeStreamClient, pages [][]byte, lang string) error {
+	for i, p := range pages {
+		for off := 0; ; off += streamChunkSize {
+			end := min(off+streamChunkSize, len(p))
+
+			chunk := &ocrv1.OCRChunk{
+				Page:    int32(i),
+				Data:    p[off:end],
+				PageEnd: end == len(p),
+			}
+			if i == 0 && off == 0 {
+				chunk.Lang = lang
+				chunk.TotalPages = int32(len(pages))
+			}
+
+			if err := stream.Send(chunk); err != nil {
+				// the server closed the stream, its status is returned by Recv
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+				return err
+			}
+
+			if end == len(p) {
+				break
+			}
+		}
+	}
+
+	return stream.CloseSend()
+}
+
+func toOCRResult(resp *ocrv1.OCRResponse) *entities.OCRResult {
+	res := &entities.OCRResult{
+		ImageWidth:  int(resp.GetImageWidth()),
+		ImageHeight: int(resp.GetImageHeight()),
+	}
+
+	// older OCR servers only fill text, treat every line as certain and without geometry
+	if len(resp.GetLines()) == 0 {
+		res.Lines = make([]entities.OCRLine, 0, len(resp.GetText()))
+		for _, t := range resp.GetText() {
+			res.Lines = append(res.Lines, entities.OCRLine{Text: t, Confidence: 1})
+		}
+		return res
+	}
+
+	res.Lines = make([]entities.OCRLine, 0, len(resp.GetLines()))
+	for _, l := range resp.GetLines() {
+		line := entities.OCRLine{
+			Text:       l.GetText(),
+			Confidence: l.GetConfidence(),
+		}
+
+		if b := l.GetBox(); b != nil {
+			line.Box = &entities.BoundingBox{
+				X:      int(b.GetX()),
+				Y:      int(b.GetY()),
+				Width:  int(b.GetWidth()),
+				Height: int(b.GetHeight()),
+			}
+		}
+
+		res.Lines = append(res.Lines, line)
+	}
+
+	return res
 }
@@ -0,0 +1,238 @@
+package ocr_router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
+	"github.com/rwrrioe/pythia/backend/internal/lib/logger/sl"
+)
+
+// DefaultRoute is used for languages without their own route
+const DefaultRoute = "*"
+
+var (
+	ErrNoEngine      = errors.New("no ocr engine for language")
+	ErrUnknownEngine = errors.New("unknown ocr engine")
+)
+
+type Engine interface {
+	Name() string
+	ProcessImage(ctx context.Context, imageData []byte, lang string) (*entities.OCRResult, error)
+	ProcessPages(ctx context.Context, pages [][]byte, lang string, onPage func(page int, res *entities.OCRResult)) error
+	HealthCheck(ctx context.Context) error
+}
+
+// Router picks engines by language and falls back to the next one on error or low confidence
+type Router struct {
+	log           *slog.Logger
+	engines       map[string]Engine
+	routes        map[string][]string
+	minConfidence float32
+
+	mu      sync.RWMutex
+	healthy map[string]bool
+}
+
+func New(
+	log *slog.Logger,
+	engines []Engine,
+	routes map[string][]string,
+	minConfidence float32,
+) (*Router, error) {
+	const op = "ocr_router.New"
+
+	byName := make(map[string]Engine, len(engines))
+	healthy := make(map[string]bool, len(engines))
+	for _, e := range engines {
+		byName[e.Name()] = e
+		healthy[e.Name()] = true
+	}
+
+	for lang, names := range routes {
+		if len(names) == 0 {
+			return nil, fmt.Errorf("%s: route %q:%w", op, lang, ErrNoEngine)
+		}
+		for _, n := range names {
+			if _, ok := byName[n]; !ok {
+				return nil, fmt.Errorf("%s: route %q uses %q:%w", op, lang, n, ErrUnknownEngine)
+			}
+		}
+	}
+
+	return &Router{
+		log:           log,
+		engines:       byName,
+		routes:        routes,
+		minConfidence: minConfidence,
+		healthy:       healthy,
+	}, nil
+}
+
+func (r *Router) ProcessImage(ctx context.Context, imageData []byte, lang string) (*entities.OCRResult, error) {
+	const op = "ocr_router.Router.ProcessImage"
+
+	candidates := r.candidates(lang)
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%s: %q:%w", op, lang, ErrNoEngine)
+	}
+
+	var (
+		best *entities.OCRResult
+		errs []error
+	)
+
+	for _, e := range candidates {
+		res, err := e.ProcessImage(ctx, imageData, lang)
+		if err != nil {
+			r.log.Warn("ocr engine failed", slog.String("engine", e.Name()), slog.String("lang", lang), sl.Err(err))
+			errs = append(errs, fmt.Errorf("%s: %w", e.Name(), err))
+
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+
+		// a blank page has no confidence to improve on, another engine won't find text either
+		if len(res.Lines) == 0 {
+			return res, nil
+		}
+
+		conf := res.AvgConfidence()
+		if conf >= r.minConfidence {
+			return res, nil
+		}
+
+		r.log.Info("ocr engine returned low confidence",
+			slog.String("engine", e.Name()),
+			slog.String("lang", lang),
+			slog.Any("confidence", conf),
+		)
+		if best == nil || conf > best.AvgConfidence() {
+			best = res
+		}
+	}
+
+	// every engine was unsure, the most confident answer is still better than nothing
+	if best != nil {
+		return best, nil
+	}
+
+	return nil, fmt.Errorf("%s:%w", op, errors.Join(errs...))
+}
+
+// ProcessPages streams the pages to the first engine of the route.
+// Fallback only happens until the first page arrives, later failures are returned since
+// partial results were already handed to onPage. Confidence is not checked for streams.
+func (r *Router) ProcessPages(
+	ctx context.Context,
+	pages [][]byte,
+	lang string,
+	onPage func(page int, res *entities.OCRResult),
+) error {
+	const op = "ocr_router.Router.ProcessPages"
+
+	candidates := r.candidates(lang)
+	if len(candidates) == 0 {
+		return fmt.Errorf("%s: %q:%w", op, lang, ErrNoEngine)
+	}
+
+	var errs []error
+	for _, e := range candidates {
+		delivered := false
+		err := e.ProcessPages(ctx, pages, lang, func(page int, res *entities.OCRResult) {
+			delivered = true
+			onPage(page, res)
+		})
+		if err == nil {
+			return nil
+		}
+
+		r.log.Warn("ocr engine stream failed", slog.String("engine", e.Name()), slog.String("lang", lang), sl.Err(err))
+		errs = append(errs, fmt.Errorf("%s: %w", e.Name(), err))
+
+		if delivered || ctx.Err() != nil {
+			break
+		}
+	}
+
+	return fmt.Errorf("%s:%w", op, errors.Join(errs...))
+}
+
+// candidates returns the route engines, healthy ones first.
+// Unhealthy engines are kept at the end since the last health check may be stale.
+func (r *Router) candidates(lang string) []Engine {
+	names, ok := r.routes[lang]
+	if !ok {
+		names = r.routes[DefaultRoute]
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	healthy := make([]Engine, 0, len(names))
+	var down []Engine
+	for _, n := range names {
+		if r.healthy[n] {
+			healthy = append(healthy, r.engines[n])
+		} else {
+			down = append(down, r.engines[n])
+		}
+	}
+
+	return append(healthy, down...)
+}
+
+// WatchHealth checks every engine each interval until ctx is done
+func (r *Router) WatchHealth(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		r.checkHealth(ctx, interval)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Router) checkHealth(ctx context.Context, timeout time.Duration) {
+	for name, e := range r.engines {
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := e.HealthCheck(checkCtx)
+		cancel()
+
+		r.mu.Lock()
+		was := r.healthy[name]
+		r.healthy[name] = err == nil
+		r.mu.Unlock()
+
+		switch {
+		case err != nil && was:
+			r.log.Warn("ocr engine is unhealthy", slog.String("engine", name), sl.Err(err))
+		case err == nil && !was:
+			r.log.Info("ocr engine is healthy again", slog.String("engine", name))
+		}
+	}
+}
+
+// Health reports the last known state of every engine
+func (r *Router) Health() map[string]bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make(map[string]bool, len(r.healthy))
+	for n, ok := range r.healthy {
+		out[n] = ok
+	}
+
+	return out
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ilyakaznacheev/cleanenv"
+)
+
+type idempotencyCfg struct {
+	// how long a response is replayed for a repeated Idempotency-Key
+	TTL string `env:"IDEMPOTENCY_TTL" env-default:"24h"`
+	// how long a key stays locked by a request that never finished, e.g. after a crash
+	LockTTL string `env:"IDEMPOTENCY_LOCK_TTL" env-default:"5m"`
+}
+
+type Config struct {
+	TTL     time.Duration
+	LockTTL time.Duration
+}
+
+func FetchConfig() (*Config, error) {
+	const op = "config.idempotency.FetchConfig"
+
+	var cfg idempotencyCfg
+	if err := cleanenv.ReadEnv(&cfg); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	ttl, err := time.ParseDuration(cfg.TTL)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	lockTTL, err := time.ParseDuration(cfg.LockTTL)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	if ttl <= 0 || lockTTL <= 0 {
+		return nil, fmt.Errorf("%s:%s", op, "IDEMPOTENCY_TTL and IDEMPOTENCY_LOCK_TTL must be positive")
+	}
+
+	return &Config{
+		TTL:     ttl,
+		LockTTL: lockTTL,
+	}, nil
+}
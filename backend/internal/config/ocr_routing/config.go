@@ -0,0 +1,103 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ilyakaznacheev/cleanenv"
+)
+
+// DefaultEngine is the engine name used when OCR_ENGINES is empty
+const DefaultEngine = "default"
+
+type routingCfg struct {
+	// name=host:port pairs separated by commas
+	Engines string `env:"OCR_ENGINES" env-default:""`
+	// lang=engine[,engine...] routes separated by semicolons, * is the fallback route
+	Routes         string  `env:"OCR_ROUTES" env-default:""`
+	MinConfidence  float64 `env:"OCR_MIN_CONFIDENCE" env-default:"0.6"`
+	HealthInterval string  `env:"OCR_HEALTH_INTERVAL" env-default:"30s"`
+	// language of the first pass when neither the session nor the upload names one
+	DetectLang string `env:"OCR_DETECT_LANG" env-default:"en"`
+}
+
+type Engine struct {
+	Name string
+	Addr string
+}
+
+type Config struct {
+	Engines        []Engine
+	Routes         map[string][]string
+	MinConfidence  float32
+	HealthInterval time.Duration
+	DetectLang     string
+}
+
+// FetchConfig reads the OCR routing, without OCR_ENGINES every language goes to defaultAddr
+//
+//	OCR_ENGINES=paddle_de=ocr-de:50051,paddle_multi=ocr:50051
+//	OCR_ROUTES=de=paddle_de,paddle_multi;*=paddle_multi
+func FetchConfig(defaultAddr string) (*Config, error) {
+	const op = "config.ocr_routing.FetchConfig"
+
+	var cfg routingCfg
+	if err := cleanenv.ReadEnv(&cfg); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	interval, err := time.ParseDuration(cfg.HealthInterval)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	out := &Config{
+		Routes:         make(map[string][]string),
+		MinConfidence:  float32(cfg.MinConfidence),
+		HealthInterval: interval,
+		DetectLang:     strings.TrimSpace(cfg.DetectLang),
+	}
+
+	if strings.TrimSpace(cfg.Engines) == "" {
+		out.Engines = []Engine{{Name: DefaultEngine, Addr: defaultAddr}}
+	}
+
+	for _, pair := range splitList(cfg.Engines, ",") {
+		name, addr, ok := strings.Cut(pair, "=")
+		if !ok || name == "" || addr == "" {
+			return nil, fmt.Errorf("%s: invalid engine %q", op, pair)
+		}
+		out.Engines = append(out.Engines, Engine{Name: strings.TrimSpace(name), Addr: strings.TrimSpace(addr)})
+	}
+
+	for _, route := range splitList(cfg.Routes, ";") {
+		lang, engines, ok := strings.Cut(route, "=")
+		if !ok || lang == "" {
+			return nil, fmt.Errorf("%s: invalid route %q", op, route)
+		}
+		out.Routes[strings.TrimSpace(lang)] = splitList(engines, ",")
+	}
+
+	// without an explicit fallback every engine is tried in the declared order
+	if _, ok := out.Routes["*"]; !ok {
+		names := make([]string, 0, len(out.Engines))
+		for _, e := range out.Engines {
+			names = append(names, e.Name)
+		}
+		out.Routes["*"] = names
+	}
+
+	return out, nil
+}
+
+func splitList(s, sep string) []string {
+	var out []string
+	for _, v := range strings.Split(s, sep) {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/ilyakaznacheev/cleanenv"
+)
+
+type preprocessCfg struct {
+	MaxUploadMB int     `env:"IMG_MAX_UPLOAD_MB" env-default:"15"`
+	MaxPixels   int     `env:"IMG_MAX_PIXELS" env-default:"60000000"`
+	MaxSide     int     `env:"IMG_MAX_SIDE" env-default:"2048"`
+	Grayscale   bool    `env:"IMG_GRAYSCALE" env-default:"true"`
+	Contrast    float64 `env:"IMG_CONTRAST" env-default:"1.2"`
+	JPEGQuality int     `env:"IMG_JPEG_QUALITY" env-default:"90"`
+}
+
+type Config struct {
+	MaxBytes    int64
+	MaxPixels   int
+	MaxSide     int
+	Grayscale   bool
+	Contrast    float64
+	JPEGQuality int
+}
+
+func FetchConfig() (*Config, error) {
+	const op = "config.preprocess.FetchConfig"
+
+	var cfg preprocessCfg
+	if err := cleanenv.ReadEnv(&cfg); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	if cfg.MaxUploadMB <= 0 {
+		return nil, fmt.Errorf("%s:%s", op, "IMG_MAX_UPLOAD_MB must be positive")
+	}
+
+	return &Config{
+		MaxBytes:    int64(cfg.MaxUploadMB) << 20,
+		MaxPixels:   cfg.MaxPixels,
+		MaxSide:     cfg.MaxSide,
+		Grayscale:   cfg.Grayscale,
+		Contrast:    cfg.Contrast,
+		JPEGQuality: cfg.JPEGQuality,
+	}, nil
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ilyakaznacheev/cleanenv"
+)
+
+type queueCfg struct {
+	// runs the workers inside the api process, disable when they are deployed separately
+	WorkersEnabled bool `env:"QUEUE_WORKERS_ENABLED" env-default:"true"`
+
+	OCRConcurrency       int `env:"QUEUE_OCR_CONCURRENCY" env-default:"2"`
+	TranslateConcurrency int `env:"QUEUE_TRANSLATE_CONCURRENCY" env-default:"4"`
+	ExamplesConcurrency  int `env:"QUEUE_EXAMPLES_CONCURRENCY" env-default:"2"`
+	ImportConcurrency    int `env:"QUEUE_IMPORT_CONCURRENCY" env-default:"1"`
+	// the longest a job may run before another worker takes it over
+	OCRVisibility       string `env:"QUEUE_OCR_VISIBILITY" env-default:"10m"`
+	TranslateVisibility string `env:"QUEUE_TRANSLATE_VISIBILITY" env-default:"2m"`
+	ExamplesVisibility  string `env:"QUEUE_EXAMPLES_VISIBILITY" env-default:"2m"`
+	ImportVisibility    string `env:"QUEUE_IMPORT_VISIBILITY" env-default:"15m"`
+
+	MaxAttempts int    `env:"QUEUE_MAX_ATTEMPTS" env-default:"3"`
+	BackoffBase string `env:"QUEUE_BACKOFF_BASE" env-default:"5s"`
+	BackoffMax  string `env:"QUEUE_BACKOFF_MAX" env-default:"5m"`
+
+	// the uploads wait there for their jobs, the api and the workers must share the directory
+	UploadDir string `env:"QUEUE_UPLOAD_DIR" env-default:"/tmp/pythia-uploads"`
+	// an upload is kept that long for retries, then it's removed even if its job never finished
+	UploadTTL string `env:"QUEUE_UPLOAD_TTL" env-default:"24h"`
+}
+
+type Stage struct {
+	Concurrency int
+	Visibility  time.Duration
+}
+
+type Config struct {
+	WorkersEnabled bool
+	OCR            Stage
+	Translate      Stage
+	Examples       Stage
+	Import         Stage
+	MaxAttempts    int
+	BackoffBase    time.Duration
+	BackoffMax     time.Duration
+	UploadDir      string
+	UploadTTL      time.Duration
+}
+
+func FetchConfig() (*Config, error) {
+	const op = "config.queue.FetchConfig"
+
+	var cfg queueCfg
+	if err := cleanenv.ReadEnv(&cfg); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	durations := make([]time.Duration, 0, 7)
+	for _, v := range []string{cfg.OCRVisibility, cfg.TranslateVisibility, cfg.ExamplesVisibility, cfg.ImportVisibility, cfg.BackoffBase, cfg.BackoffMax, cfg.UploadTTL} {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("%s: durations must be positive, got %s", op, v)
+		}
+		durations = append(durations, d)
+	}
+
+	if cfg.OCRConcurrency <= 0 || cfg.TranslateConcurrency <= 0 || cfg.ExamplesConcurrency <= 0 || cfg.ImportConcurrency <= 0 || cfg.MaxAttempts <= 0 {
+		return nil, fmt.Errorf("%s:%s", op, "stage concurrencies and QUEUE_MAX_ATTEMPTS must be positive")
+	}
+
+	return &Config{
+		WorkersEnabled: cfg.WorkersEnabled,
+		OCR:            Stage{Concurrency: cfg.OCRConcurrency, Visibility: durations[0]},
+		Translate:      Stage{Concurrency: cfg.TranslateConcurrency, Visibility: durations[1]},
+		Examples:       Stage{Concurrency: cfg.ExamplesConcurrency, Visibility: durations[2]},
+		Import:         Stage{Concurrency: cfg.ImportConcurrency, Visibility: durations[3]},
+		MaxAttempts:    cfg.MaxAttempts,
+		BackoffBase:    durations[4],
+		BackoffMax:     durations[5],
+		UploadDir:      cfg.UploadDir,
+		UploadTTL:      durations[6],
+	}, nil
+}
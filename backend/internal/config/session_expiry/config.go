@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ilyakaznacheev/cleanenv"
+)
+
+type expiryCfg struct {
+	Interval string `env:"SESSION_EXPIRY_INTERVAL" env-default:"30s"`
+	// how long an instance owns the expired session it finalizes
+	Lease string `env:"SESSION_EXPIRY_LEASE" env-default:"5m"`
+	Batch int    `env:"SESSION_EXPIRY_BATCH" env-default:"20"`
+}
+
+type Config struct {
+	Interval time.Duration
+	Lease    time.Duration
+	Batch    int
+}
+
+func FetchConfig() (*Config, error) {
+	const op = "config.session_expiry.FetchConfig"
+
+	var cfg expiryCfg
+	if err := cleanenv.ReadEnv(&cfg); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	interval, err := time.ParseDuration(cfg.Interval)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	lease, err := time.ParseDuration(cfg.Lease)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	if interval <= 0 || lease <= 0 || cfg.Batch <= 0 {
+		return nil, fmt.Errorf("%s:%s", op, "SESSION_EXPIRY_INTERVAL, SESSION_EXPIRY_LEASE and SESSION_EXPIRY_BATCH must be positive")
+	}
+
+	return &Config{
+		Interval: interval,
+		Lease:    lease,
+		Batch:    cfg.Batch,
+	}, nil
+}
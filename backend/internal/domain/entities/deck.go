@@ -1,9 +1,27 @@
 package entities
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DeckSession is the deck a finished session saves its words to
+	DeckSession = "session"
+	// DeckUser is a deck curated by the user
+	DeckUser = "user"
+)
 
 type Deck struct {
-	Id         uuid.UUID
-	SessionId  int64
-	Flashcards []FlashCard
+	Id          uuid.UUID   `json:"id"`
+	Kind        string      `json:"kind"`
+	SessionId   *uuid.UUID  `json:"session_id,omitempty"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Language    int         `json:"lang_id"`
+	Position    int         `json:"position"`
+	CardsCount  int         `json:"cards_count"`
+	CreatedAt   time.Time   `json:"created_at"`
+	Flashcards  []FlashCard `json:"flashcards,omitempty"`
 }
@@ -0,0 +1,27 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Document is the source text of one upload, a multi-page upload is a single document
+type Document struct {
+	Id           uuid.UUID      `json:"id"`
+	SessionId    uuid.UUID      `json:"session_id"`
+	TaskId       string         `json:"task_id"`
+	Language     int            `json:"language"`
+	DetectedLang string         `json:"detected_lang"`
+	CreatedAt    time.Time      `json:"created_at"`
+	PagesCount   int            `json:"pages_count"`
+	Pages        []DocumentPage `json:"pages,omitempty"`
+}
+
+type DocumentPage struct {
+	Page      int       `json:"page"`
+	Text      []string  `json:"text"`
+	Lines     []OCRLine `json:"lines"`
+	ImageHash string    `json:"image_hash,omitempty"`
+	Words     []Word    `json:"words"`
+}
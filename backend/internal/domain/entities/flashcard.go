@@ -1,17 +1,78 @@
 package entities
 
-import "github.com/google/uuid"
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/text/unicode/norm"
+)
 
 type FlashCardDTO struct {
-	Word        string `json:"word"`
-	Translation string `json:"translation"`
-	Lang        string `json:"language"`
+	Id          uuid.UUID `json:"id,omitzero"`
+	Word        string    `json:"word"`
+	Translation string    `json:"translation"`
+	Description string    `json:"description,omitempty"`
+	Lang        string    `json:"language"`
 }
 
 type FlashCard struct {
-	Id     uuid.UUID
-	Word   string
-	Transl string
-	Desc   string
-	Lang   int
+	Id      uuid.UUID `json:"id"`
+	Word    string    `json:"word"`
+	Transl  string    `json:"translation"`
+	Desc    string    `json:"description"`
+	Example string    `json:"example"`
+	Lang    int       `json:"lang_id"`
+	Tags    []string  `json:"tags"`
+}
+
+// Automatic tags are namespaced by the prefix before the colon, user tags can't contain one
+const (
+	// TagMistake marks the flashcards answered wrong at least once
+	TagMistake      = "mistake"
+	TagPosPrefix    = "pos:"
+	TagLvlPrefix    = "level:"
+	TagSessPrefix   = "session:"
+	TagGenderPrefix = "gender:"
+)
+
+func PosTag(pos string) string              { return TagPosPrefix + strings.ToLower(pos) }
+func LevelTag(level string) string          { return TagLvlPrefix + strings.ToLower(level) }
+func SessionTag(sessionId uuid.UUID) string { return TagSessPrefix + sessionId.String() }
+func GenderTag(gender string) string        { return TagGenderPrefix + strings.ToLower(gender) }
+
+// NormalizeTag is the form a tag is stored and looked up in: NFC, lower case and single spaces
+func NormalizeTag(t string) string {
+	return strings.ToLower(strings.Join(strings.Fields(norm.NFC.String(t)), " "))
+}
+
+// Tag is a tag of the user's vocabulary with the number of flashcards carrying it
+type Tag struct {
+	Name  string `json:"name"`
+	Auto  bool   `json:"auto"`
+	Count int    `json:"count"`
+}
+
+// Review is one answer given for a flashcard in a quiz or a review
+type Review struct {
+	FlashcardId uuid.UUID  `json:"card_id"`
+	SessionId   *uuid.UUID `json:"session_id,omitempty"`
+	Correct     bool       `json:"correct"`
+	ReviewedAt  time.Time  `json:"reviewed_at"`
+}
+
+// CardSource is the session a flashcard was first found in, zero for a card added by hand
+type CardSource struct {
+	SessionId uuid.UUID
+	Name      string
+	StartedAt time.Time
+}
+
+// SmartFilter is a saved card query, its cards are found anew every time it is used
+type SmartFilter struct {
+	Id        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Query     string    `json:"query"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -0,0 +1,99 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// import file formats
+const (
+	ImportApkg = "apkg"
+	ImportCSV  = "csv"
+	ImportTSV  = "tsv"
+)
+
+// what happens to a word already in the vocabulary, the key is (user, word, language)
+const (
+	// ImportSkip keeps the existing card as it is
+	ImportSkip = "skip"
+	// ImportOverwrite replaces the existing fields with the imported ones
+	ImportOverwrite = "overwrite"
+	// ImportMerge fills in the empty fields and adds the new translations and tags
+	ImportMerge = "merge"
+)
+
+// actions taken on an imported row
+const (
+	ImportCreated   = "created"
+	ImportUpdated   = "updated"
+	ImportMerged    = "merged"
+	ImportSkipped   = "skipped"
+	ImportUnchanged = "unchanged"
+	ImportInvalid   = "invalid"
+)
+
+// ImportColumns maps the card fields to columns of the file, by header or field name or by 1-based position
+type ImportColumns struct {
+	Word        string `json:"word,omitempty"`
+	Translation string `json:"translation,omitempty"`
+	Example     string `json:"example,omitempty"`
+	Description string `json:"description,omitempty"`
+	Tags        string `json:"tags,omitempty"`
+	Gender      string `json:"gender,omitempty"`
+	// Lang holds language codes overriding the language of the import
+	Lang string `json:"lang,omitempty"`
+}
+
+type ImportOptions struct {
+	FileName  string        `json:"file_name"`
+	Format    string        `json:"format"`
+	LangId    int           `json:"lang_id"`
+	Mode      string        `json:"mode"`
+	DryRun    bool          `json:"dry_run"`
+	Header    bool          `json:"header"`
+	Delimiter string        `json:"delimiter,omitempty"`
+	Columns   ImportColumns `json:"columns"`
+	// DeckId is the user deck the imported cards are added to
+	DeckId *uuid.UUID `json:"deck_id,omitempty"`
+}
+
+type ImportCounts struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Merged    int `json:"merged"`
+	Skipped   int `json:"skipped"`
+	Unchanged int `json:"unchanged"`
+	Invalid   int `json:"invalid"`
+}
+
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// ImportPreviewRow shows what a row does to the vocabulary, Existing is the card before the import
+type ImportPreviewRow struct {
+	Row      int        `json:"row"`
+	Action   string     `json:"action"`
+	Card     FlashCard  `json:"card"`
+	Existing *FlashCard `json:"existing,omitempty"`
+}
+
+// Import is a vocabulary import job. A dry run reports the same counts and preview without saving anything.
+type Import struct {
+	Id        string             `json:"id"`
+	Status    string             `json:"status"`
+	Options   ImportOptions      `json:"options"`
+	Columns   []string           `json:"columns"`
+	Total     int                `json:"total"`
+	Processed int                `json:"processed"`
+	Counts    ImportCounts       `json:"counts"`
+	Errors    []ImportRowError   `json:"errors"`
+	Preview   []ImportPreviewRow `json:"preview"`
+	Error     string             `json:"error,omitempty"`
+	// ConfirmedBy is the import started by confirming this dry run
+	ConfirmedBy string    `json:"confirmed_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
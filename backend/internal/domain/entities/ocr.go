@@ -0,0 +1,89 @@
+package entities
+
+// BoundingBox is in pixels of the recognized image, not of the upload: the preprocessor turns the upload
+// upright by its EXIF orientation and scales it down to IMG_MAX_SIDE. A client showing the upload upright,
+// as browsers do, maps a box onto it by scaling with its displayed width / ImageWidth.
+type BoundingBox struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+type OCRLine struct {
+	Text       string       `json:"text"`
+	Confidence float32      `json:"confidence"`
+	Box        *BoundingBox `json:"box,omitempty"`
+	// Page is set for multi-page uploads, boxes are relative to that page
+	Page int `json:"page,omitempty"`
+}
+
+type OCRResult struct {
+	Lines []OCRLine `json:"lines"`
+	// the size of the recognized image, the space the boxes are in
+	ImageWidth  int    `json:"image_width"`
+	ImageHeight int    `json:"image_height"`
+	Engine      string `json:"engine,omitempty"`
+}
+
+func (r *OCRResult) Text() []string {
+	txt := make([]string, 0, len(r.Lines))
+	for _, l := range r.Lines {
+		txt = append(txt, l.Text)
+	}
+
+	return txt
+}
+
+func (r *OCRResult) AvgConfidence() float32 {
+	if len(r.Lines) == 0 {
+		return 0
+	}
+
+	var sum float32
+	for _, l := range r.Lines {
+		sum += l.Confidence
+	}
+
+	return sum / float32(len(r.Lines))
+}
+
+// Filter drops the lines recognized with confidence below minConfidence
+func (r *OCRResult) Filter(minConfidence float32) *OCRResult {
+	out := &OCRResult{
+		ImageWidth:  r.ImageWidth,
+		ImageHeight: r.ImageHeight,
+		Engine:      r.Engine,
+		Lines:       make([]OCRLine, 0, len(r.Lines)),
+	}
+
+	for _, l := range r.Lines {
+		if l.Confidence >= minConfidence {
+			out.Lines = append(out.Lines, l)
+		}
+	}
+
+	return out
+}
+
+// MergePages joins page results into one, the image size is taken from the first page
+func MergePages(pages []*OCRResult) *OCRResult {
+	out := &OCRResult{}
+	for i, p := range pages {
+		if p == nil {
+			continue
+		}
+		if out.ImageWidth == 0 {
+			out.ImageWidth = p.ImageWidth
+			out.ImageHeight = p.ImageHeight
+			out.Engine = p.Engine
+		}
+
+		for _, l := range p.Lines {
+			l.Page = i
+			out.Lines = append(out.Lines, l)
+		}
+	}
+
+	return out
+}
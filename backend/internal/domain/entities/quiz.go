@@ -1,7 +1,11 @@
 package entities
 
+import "github.com/google/uuid"
+
 type QuizQuestion struct {
 	Answer   string   `json:"answer"`
 	Question string   `json:"question"`
 	Options  []string `json:"options"`
+	// CardId is set when the question is built from a flashcard, answers are reported with it
+	CardId uuid.UUID `json:"card_id,omitzero"`
 }
@@ -12,8 +12,11 @@ type Session struct {
 	StartedAt time.Time
 	EndedAt   time.Time `json:"ended_at"`
 	Duration  time.Duration
+	ExpiresAt time.Time `json:"expires_at"`
 	Status    string
 	Language  int `json:"language"`
 	Level     int `json:"level"`
 	Accuracy  float64
+	// ArchivedAt is set while the session is hidden from the library
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
 }
@@ -0,0 +1,28 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// VocabularyHit is a flashcard found by the vocabulary search with the places it was learned in
+type VocabularyHit struct {
+	Flashcard FlashCard    `json:"flashcard"`
+	Exact     bool         `json:"exact"`
+	Score     float64      `json:"score"`
+	Decks     []DeckRef    `json:"decks"`
+	Sessions  []SessionRef `json:"sessions"`
+}
+
+type DeckRef struct {
+	Id   uuid.UUID `json:"id"`
+	Kind string    `json:"kind"`
+	Name string    `json:"name"`
+}
+
+type SessionRef struct {
+	Id        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	StartedAt time.Time `json:"started_at"`
+}
@@ -4,6 +4,10 @@ type Word struct {
 	Word        string `json:"word"`
 	Translation string `json:"translation"`
 	Lang        string
+	// PartOfSpeech, Level and Gender are set when the words of a session are summarized
+	PartOfSpeech string `json:"pos,omitempty"`
+	Level        string `json:"level,omitempty"`
+	Gender       string `json:"gender,omitempty"`
 }
 
 type Example struct {
@@ -0,0 +1,24 @@
+package requests
+
+import "github.com/google/uuid"
+
+type CreateDeck struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	LangId      int    `json:"lang_id"` // optional, a deck with a language only takes cards of it
+}
+
+// UpdateDeck changes the fields that are set, a zero lang_id lifts the language restriction
+type UpdateDeck struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	LangId      *int    `json:"lang_id"`
+}
+
+type ReorderDecks struct {
+	Ids []uuid.UUID `json:"deck_ids" binding:"required,min=1"`
+}
+
+type DeckCards struct {
+	Ids []uuid.UUID `json:"flashcard_ids" binding:"required,min=1"`
+}
@@ -0,0 +1,12 @@
+package requests
+
+type CreateSmartFilter struct {
+	Name  string `json:"name" binding:"required"`
+	Query string `json:"query" binding:"required"`
+}
+
+// UpdateSmartFilter changes the fields that are set
+type UpdateSmartFilter struct {
+	Name  *string `json:"name"`
+	Query *string `json:"query"`
+}
@@ -0,0 +1,83 @@
+package requests
+
+import "github.com/google/uuid"
+
+type CreateFlashcard struct {
+	Word        string     `json:"word" binding:"required"`
+	Translation string     `json:"translation" binding:"required"`
+	Description string     `json:"description"`
+	Example     string     `json:"example"`
+	LangId      int        `json:"lang_id" binding:"required"`
+	DeckId      *uuid.UUID `json:"deck_id"` // optional, the card is added to the deck
+}
+
+// UpdateFlashcard changes the fields that are set
+type UpdateFlashcard struct {
+	Word        *string `json:"word"`
+	Translation *string `json:"translation"`
+	Description *string `json:"description"`
+	Example     *string `json:"example"`
+}
+
+type MoveFlashcards struct {
+	Ids        []uuid.UUID `json:"flashcard_ids" binding:"required,min=1"`
+	FromDeckId uuid.UUID   `json:"from_deck_id" binding:"required"`
+	ToDeckId   uuid.UUID   `json:"to_deck_id" binding:"required"`
+}
+
+type CopyFlashcards struct {
+	Ids      []uuid.UUID `json:"flashcard_ids" binding:"required,min=1"`
+	ToDeckId uuid.UUID   `json:"to_deck_id" binding:"required"`
+}
+
+type VocabularySearch struct {
+	Query  string `form:"q" binding:"required"`
+	LangId int    `form:"lang_id"`
+	Limit  int    `form:"limit"`
+}
+
+type AddTags struct {
+	Tags []string `json:"tags" binding:"required,min=1"`
+}
+
+type ReviewAnswer struct {
+	CardId    uuid.UUID  `json:"card_id" binding:"required"`
+	SessionId *uuid.UUID `json:"session_id"` // optional, the session the quiz was taken in
+	Correct   bool       `json:"correct"`
+}
+
+type RecordReviews struct {
+	Answers []ReviewAnswer `json:"answers" binding:"required,min=1,dive"`
+}
+
+// ImportVocabulary are the form fields sent with an imported file
+type ImportVocabulary struct {
+	Format string `form:"format"` // apkg, csv or tsv, taken from the file name when omitted
+	LangId int    `form:"lang_id" binding:"required"`
+	Mode   string `form:"mode"` // skip, overwrite or merge, skip when omitted
+	DryRun bool   `form:"dry_run"`
+	// Header tells whether the first csv row names the columns, true when omitted
+	Header    *bool      `form:"header"`
+	Delimiter string     `form:"delimiter"`
+	DeckId    *uuid.UUID `form:"deck_id"`
+
+	WordColumn        string `form:"word_column"`
+	TranslationColumn string `form:"translation_column"`
+	ExampleColumn     string `form:"example_column"`
+	DescriptionColumn string `form:"description_column"`
+	TagsColumn        string `form:"tags_column"`
+	GenderColumn      string `form:"gender_column"`
+	LangColumn        string `form:"lang_column"`
+}
+
+// ExportVocabulary picks the cards and the format of a plain export, at most one of the scopes is given
+// and the whole vocabulary is exported without any
+type ExportVocabulary struct {
+	Format   string     `form:"format" binding:"required"` // csv, tsv, quizlet, json or markdown
+	DeckId   *uuid.UUID `form:"deck_id"`
+	FilterId *uuid.UUID `form:"filter_id"`
+	Query    string     `form:"q"`
+	// TermSep and CardSep are the Quizlet separators, a character, tab, comma, newline or semicolon
+	TermSep string `form:"term_sep"`
+	CardSep string `form:"card_sep"`
+}
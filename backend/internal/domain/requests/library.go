@@ -0,0 +1,15 @@
+package requests
+
+type LibraryQuery struct {
+	Cursor      string   `form:"cursor"`
+	Limit       int      `form:"limit"`
+	Lang        int      `form:"lang_id"`
+	Status      string   `form:"status"`
+	From        string   `form:"from"` // RFC 3339 or a date, compared with the session start
+	To          string   `form:"to"`   // a date includes the whole day
+	MinAccuracy *float64 `form:"min_accuracy"`
+	MaxAccuracy *float64 `form:"max_accuracy"`
+	Sort        string   `form:"sort"` // newest, oldest, accuracy_desc, accuracy_asc or name
+	Search      string   `form:"q"`    // part of the session name
+	Archived    bool     `form:"archived"`
+}
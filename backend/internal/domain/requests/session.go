@@ -1,11 +1,20 @@
 package requests
 
 type CreateSession struct {
-	Duration   int `json:"durating"`
+	Duration   int `json:"durating"` // seconds, the session is finished automatically when it elapses, 0 disables it
 	WordsCount int `json:"words_count"`
-	LangId     int `json:"lang_id"`
+	LangId     int `json:"lang_id"`  // optional, detected from the first upload when omitted
+	LevelId    int `json:"level_id"` // optional CEFR level, the user profile level is used when omitted
 }
 
 type SummarizeSession struct {
 	Accuracy float64 `json:"accuracy"`
 }
+
+type ReopenSession struct {
+	Duration int `json:"duration"` // seconds, optional, the reopened session never expires when omitted
+}
+
+type RenameSession struct {
+	Name string `json:"name" binding:"required"`
+}
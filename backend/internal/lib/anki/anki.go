@@ -0,0 +1,229 @@
+// Package anki writes and reads Anki deck packages (.apkg).
+//
+// A package is a zip holding an SQLite collection in the schema 11 layout and a media map.
+// Every current Anki version imports it, notes are matched by guid so exporting the same
+// cards again updates them instead of adding duplicates. Packages made by Anki itself are read
+// in both the legacy and the compressed schema 18 layout.
+package anki
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Fields of the note type in their order
+var Fields = []string{"Word", "Translation", "Example", "Gender", "Source"}
+
+var ErrEmptyDeck = errors.New("deck has no notes")
+
+type Deck struct {
+	Name        string
+	Description string
+	Notes       []Note
+}
+
+type Note struct {
+	// Guid identifies the note across exports, any stable string unique within the collection
+	Guid        string
+	Word        string
+	Translation string
+	Example     string
+	Gender      string
+	Source      string
+	Tags        []string
+	// Reviews are the past answers in any order, a note without them is exported as a new card
+	Reviews []Review
+}
+
+type Review struct {
+	At      time.Time
+	Correct bool
+}
+
+// Write builds the package of the deck and copies it to w.
+// The collection is built in a temporary file first, nothing is written to w if that fails.
+func Write(ctx context.Context, w io.Writer, deck Deck, now time.Time) error {
+	const op = "anki.Write"
+
+	if len(deck.Notes) == 0 {
+		return fmt.Errorf("%s:%w", op, ErrEmptyDeck)
+	}
+
+	dir, err := os.MkdirTemp("", "apkg")
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "collection.anki2")
+	if err := writeCollection(ctx, path, deck, now); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	if err := writeZip(w, path, now); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	return nil
+}
+
+func writeZip(w io.Writer, path string, now time.Time) error {
+	col, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer col.Close()
+
+	zw := zip.NewWriter(w)
+
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: "collection.anki2", Method: zip.Deflate, Modified: now})
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, col); err != nil {
+		return err
+	}
+
+	// no media is exported, the map is still required
+	f, err = zw.CreateHeader(&zip.FileHeader{Name: "media", Method: zip.Deflate, Modified: now})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, "{}"); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+func writeCollection(ctx context.Context, path string, deck Deck, now time.Time) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, schema); err != nil {
+		return err
+	}
+
+	// the collection day 0 is the day of the first review, due dates are counted from it
+	crt := day(now)
+	for _, n := range deck.Notes {
+		for _, r := range n.Reviews {
+			if d := day(r.At); d.Before(crt) {
+				crt = d
+			}
+		}
+	}
+
+	nowMs := now.UnixMilli()
+	deckId := nowMs
+
+	col, err := collectionRow(deck, deckId, now, len(deck.Notes))
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO col (id, crt, mod, scm, ver, dty, usn, ls, conf, models, decks, dconf, tags)
+		VALUES (1, ?, ?, ?, 11, 0, 0, 0, ?, ?, ?, ?, '{}')
+	`, crt.Unix(), nowMs, nowMs, col.conf, col.models, col.decks, col.dconf); err != nil {
+		return err
+	}
+
+	// note, card and revlog ids are millisecond timestamps in Anki, they only have to be unique
+	revId := int64(0)
+	for i, n := range deck.Notes {
+		id := nowMs + int64(i)
+
+		flds := noteFields(n)
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO notes (id, guid, mid, mod, usn, tags, flds, sfld, csum, flags, data)
+			VALUES (?, ?, ?, ?, -1, ?, ?, ?, ?, 0, '')
+		`, id, n.Guid, modelId, now.Unix(), noteTags(n.Tags), strings.Join(flds, "\x1f"), stripHTML(flds[0]), checksum(flds[0])); err != nil {
+			return err
+		}
+
+		s := schedule(n.Reviews, crt, i+1)
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO cards (id, nid, did, ord, mod, usn, type, queue, due, ivl, factor, reps, lapses, left, odue, odid, flags, data)
+			VALUES (?, ?, ?, 0, ?, -1, ?, ?, ?, ?, ?, ?, ?, 0, 0, 0, 0, '')
+		`, id, id, deckId, now.Unix(), s.cardType, s.queue, s.due, s.ivl, s.factor, s.reps, s.lapses); err != nil {
+			return err
+		}
+
+		for _, r := range s.log {
+			// two answers in the same millisecond would collide
+			revId = max(revId+1, r.at.UnixMilli())
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO revlog (id, cid, usn, ease, ivl, lastIvl, factor, time, type)
+				VALUES (?, ?, -1, ?, ?, ?, ?, 0, ?)
+			`, revId, id, r.ease, r.ivl, r.lastIvl, r.factor, r.kind); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+func noteFields(n Note) []string {
+	out := make([]string, 0, len(Fields))
+	for _, v := range []string{n.Word, n.Translation, n.Example, n.Gender, n.Source} {
+		out = append(out, strings.ReplaceAll(html.EscapeString(v), "\n", "<br>"))
+	}
+
+	return out
+}
+
+// noteTags joins the tags the way Anki stores them, spaces separate tags so they can't be inside one
+func noteTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+
+	out := make([]string, 0, len(tags))
+	for _, t := range tags {
+		out = append(out, strings.Join(strings.Fields(t), "_"))
+	}
+
+	return " " + strings.Join(out, " ") + " "
+}
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+func stripHTML(s string) string {
+	return html.UnescapeString(htmlTag.ReplaceAllString(s, ""))
+}
+
+// checksum is the duplicate check of Anki: the first 8 hex digits of the sha1 of the stripped first field
+func checksum(field string) int64 {
+	sum := sha1.Sum([]byte(stripHTML(field)))
+	n, _ := strconv.ParseInt(hex.EncodeToString(sum[:4]), 16, 64)
+	return n
+}
+
+func day(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
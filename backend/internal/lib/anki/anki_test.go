@@ -0,0 +1,285 @@
+package anki
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"maps"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(context.Background(), &buf, Deck{Name: "empty"}, time.Now()); !errors.Is(err, ErrEmptyDeck) {
+		t.Fatalf("got %v, want %v", err, ErrEmptyDeck)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("wrote %d bytes for an empty deck", buf.Len())
+	}
+}
+
+// writeTestCollection writes the collection of the deck into a temporary file and opens it
+func writeTestCollection(t *testing.T, deck Deck, now time.Time) *sql.DB {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "collection.anki2")
+	if err := writeCollection(context.Background(), path, deck, now); err != nil {
+		t.Fatalf("write collection: %v", err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("open collection: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func TestWriteNotes(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		note     Note
+		wantFlds []string
+		wantSfld string
+		wantCsum int64
+		wantTags string
+	}{
+		{
+			name:     "plain note",
+			note:     Note{Guid: "g1", Word: "Haus", Translation: "house", Gender: "das"},
+			wantFlds: []string{"Haus", "house", "", "das", ""},
+			wantSfld: "Haus",
+			wantCsum: 582454829,
+		},
+		{
+			name:     "markup is escaped",
+			note:     Note{Guid: "g2", Word: "<b>Tisch</b> & Stuhl", Translation: `"table" < chair`},
+			wantFlds: []string{"&lt;b&gt;Tisch&lt;/b&gt; &amp; Stuhl", "&#34;table&#34; &lt; chair", "", "", ""},
+			wantSfld: "<b>Tisch</b> & Stuhl",
+		},
+		{
+			name:     "line breaks become br",
+			note:     Note{Guid: "g3", Word: "laufen", Translation: "to run", Example: "Ich laufe.\nDu läufst."},
+			wantFlds: []string{"laufen", "to run", "Ich laufe.<br>Du läufst.", "", ""},
+			wantSfld: "laufen",
+			wantCsum: 3573397094,
+		},
+		{
+			name:     "tags with spaces",
+			note:     Note{Guid: "g4", Word: "Küche", Translation: "kitchen", Source: "Session 1", Tags: []string{"pos:noun", "at home"}},
+			wantFlds: []string{"Küche", "kitchen", "", "", "Session 1"},
+			wantSfld: "Küche",
+			wantTags: " pos:noun at_home ",
+		},
+	}
+
+	deck := Deck{Name: "Deutsch"}
+	for _, tt := range tests {
+		deck.Notes = append(deck.Notes, tt.note)
+	}
+	db := writeTestCollection(t, deck, now)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mid, csum  int64
+				flds, sfld string
+				tags       string
+			)
+			err := db.QueryRow(`SELECT mid, flds, sfld, csum, tags FROM notes WHERE guid = ?`, tt.note.Guid).
+				Scan(&mid, &flds, &sfld, &csum, &tags)
+			if err != nil {
+				t.Fatalf("read note: %v", err)
+			}
+
+			if mid != modelId {
+				t.Errorf("model %d, want %d", mid, modelId)
+			}
+			if got := strings.Split(flds, "\x1f"); !reflect.DeepEqual(got, tt.wantFlds) {
+				t.Errorf("fields %q, want %q", got, tt.wantFlds)
+			}
+			if sfld != tt.wantSfld {
+				t.Errorf("sort field %q, want %q", sfld, tt.wantSfld)
+			}
+			if tt.wantCsum != 0 && csum != tt.wantCsum {
+				t.Errorf("checksum %d, want %d", csum, tt.wantCsum)
+			}
+			if tags != tt.wantTags {
+				t.Errorf("tags %q, want %q", tags, tt.wantTags)
+			}
+		})
+	}
+}
+
+func TestWriteSchedule(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	at := func(month time.Month, d int) time.Time { return time.Date(2026, month, d, 10, 0, 0, 0, time.UTC) }
+
+	type card struct{ typ, queue, due, ivl, factor, reps, lapses int }
+	type rev struct{ ease, ivl, lastIvl, factor, kind int }
+
+	tests := []struct {
+		name     string
+		reviews  []Review
+		wantCard card
+		wantLog  []rev
+	}{
+		{
+			// the first note sets the collection day 0 to april 1st
+			name: "answers in any order",
+			reviews: []Review{
+				{At: at(4, 12), Correct: false},
+				{At: at(4, 1), Correct: true},
+				{At: at(4, 5), Correct: true},
+				{At: at(4, 13), Correct: true},
+				{At: at(4, 2), Correct: true},
+			},
+			wantCard: card{typ: typeReview, queue: queueReview, due: 12 + 2, ivl: 2, factor: 2300, reps: 5, lapses: 1},
+			wantLog: []rev{
+				{ease: 3, ivl: 1, lastIvl: 0, factor: 2500, kind: revLearn},
+				{ease: 3, ivl: 2, lastIvl: 1, factor: 2500, kind: revReview},
+				{ease: 3, ivl: 5, lastIvl: 2, factor: 2500, kind: revReview},
+				{ease: 1, ivl: 1, lastIvl: 5, factor: 2300, kind: revReview},
+				{ease: 3, ivl: 2, lastIvl: 1, factor: 2300, kind: revReview},
+			},
+		},
+		{
+			name:     "new card",
+			wantCard: card{typ: typeNew, queue: queueNew, due: 2},
+		},
+		{
+			name: "lapse after learning",
+			reviews: []Review{
+				{At: at(4, 29), Correct: true},
+				{At: at(4, 30), Correct: false},
+			},
+			wantCard: card{typ: typeReview, queue: queueReview, due: 29 + 1, ivl: 1, factor: 2300, reps: 2, lapses: 1},
+			wantLog: []rev{
+				{ease: 3, ivl: 1, lastIvl: 0, factor: 2500, kind: revLearn},
+				{ease: 1, ivl: 1, lastIvl: 1, factor: 2300, kind: revReview},
+			},
+		},
+		{
+			name:     "first answer wrong is no lapse",
+			reviews:  []Review{{At: at(4, 20), Correct: false}},
+			wantCard: card{typ: typeReview, queue: queueReview, due: 19 + 1, ivl: 1, factor: 2500, reps: 1},
+			wantLog: []rev{
+				{ease: 1, ivl: 1, lastIvl: 0, factor: 2500, kind: revLearn},
+			},
+		},
+		{
+			name: "answers in the same millisecond",
+			reviews: []Review{
+				{At: at(4, 10), Correct: true},
+				{At: at(4, 10), Correct: true},
+			},
+			wantCard: card{typ: typeReview, queue: queueReview, due: 9 + 2, ivl: 2, factor: 2500, reps: 2},
+			wantLog: []rev{
+				{ease: 3, ivl: 1, lastIvl: 0, factor: 2500, kind: revLearn},
+				{ease: 3, ivl: 2, lastIvl: 1, factor: 2500, kind: revReview},
+			},
+		},
+	}
+
+	deck := Deck{Name: "Deutsch"}
+	for i, tt := range tests {
+		deck.Notes = append(deck.Notes, Note{Guid: strconv.Itoa(i), Word: tt.name, Translation: tt.name, Reviews: tt.reviews})
+	}
+	db := writeTestCollection(t, deck, now)
+
+	var crt int64
+	if err := db.QueryRow(`SELECT crt FROM col`).Scan(&crt); err != nil {
+		t.Fatalf("read collection: %v", err)
+	}
+	if want := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC).Unix(); crt != want {
+		t.Errorf("collection created at %d, want %d", crt, want)
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				cid int64
+				got card
+			)
+			err := db.QueryRow(`
+				SELECT c.id, c.type, c.queue, c.due, c.ivl, c.factor, c.reps, c.lapses
+				FROM cards c JOIN notes n ON n.id = c.nid
+				WHERE n.guid = ?`, strconv.Itoa(i),
+			).Scan(&cid, &got.typ, &got.queue, &got.due, &got.ivl, &got.factor, &got.reps, &got.lapses)
+			if err != nil {
+				t.Fatalf("read card: %v", err)
+			}
+			if got != tt.wantCard {
+				t.Errorf("card %+v, want %+v", got, tt.wantCard)
+			}
+
+			rows, err := db.Query(`SELECT ease, ivl, lastIvl, factor, type FROM revlog WHERE cid = ? ORDER BY id`, cid)
+			if err != nil {
+				t.Fatalf("read revlog: %v", err)
+			}
+			defer rows.Close()
+
+			var log []rev
+			for rows.Next() {
+				var r rev
+				if err := rows.Scan(&r.ease, &r.ivl, &r.lastIvl, &r.factor, &r.kind); err != nil {
+					t.Fatalf("read revlog: %v", err)
+				}
+				log = append(log, r)
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatalf("read revlog: %v", err)
+			}
+			if !reflect.DeepEqual(log, tt.wantLog) {
+				t.Errorf("revlog\n got: %+v\nwant: %+v", log, tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestWritePackage(t *testing.T) {
+	deck := Deck{Name: "Deutsch", Notes: []Note{{Guid: "g1", Word: "Haus", Translation: "house"}}}
+
+	var buf bytes.Buffer
+	if err := Write(context.Background(), &buf, deck, time.Now()); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("open package: %v", err)
+	}
+
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	if len(files) != 2 || files["collection.anki2"] == nil || files["media"] == nil {
+		t.Fatalf("package holds %v, want the collection and the media map", slices.Collect(maps.Keys(files)))
+	}
+
+	rc, err := files["media"].Open()
+	if err != nil {
+		t.Fatalf("open media map: %v", err)
+	}
+	defer rc.Close()
+	media, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("read media map: %v", err)
+	}
+	if string(media) != "{}" {
+		t.Errorf("media map %q, want an empty object", media)
+	}
+}
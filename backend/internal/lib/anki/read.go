@@ -0,0 +1,285 @@
+package anki
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// MaxCollectionSize bounds the unpacked collection, larger packages are refused
+const MaxCollectionSize = 512 << 20
+
+var (
+	ErrInvalidPackage = errors.New("invalid anki package")
+	ErrTooLarge       = errors.New("anki collection is too large")
+)
+
+// ReadNote is a note of an imported package, Fields and Names are in the order of its note type
+type ReadNote struct {
+	Guid   string
+	Model  string
+	Names  []string
+	Fields []string
+	Tags   []string
+}
+
+// collections in the order they are preferred. Packages made by Anki 2.1.50+ keep the real
+// collection in the zstd compressed anki21b and a stub asking to upgrade in anki2.
+var collections = []string{"collection.anki21b", "collection.anki21", "collection.anki2"}
+
+// Read returns the notes of the package in r with their fields converted to plain text, at most limit
+// of them when limit is positive. Both the legacy schema 11 and the schema 18 collections are read,
+// media is ignored.
+func Read(ctx context.Context, r io.ReaderAt, size int64, limit int) ([]ReadNote, error) {
+	const op = "anki.Read"
+
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w: %w", op, ErrInvalidPackage, err)
+	}
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var col *zip.File
+	for _, name := range collections {
+		if col = files[name]; col != nil {
+			break
+		}
+	}
+	if col == nil {
+		return nil, fmt.Errorf("%s:%w: no collection in the package", op, ErrInvalidPackage)
+	}
+
+	dir, err := os.MkdirTemp("", "apkg")
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "collection.db")
+	if err := unpack(col, path); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	notes, err := readCollection(ctx, path, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return notes, nil
+}
+
+func unpack(f *zip.File, path string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidPackage, err)
+	}
+	defer rc.Close()
+
+	var src io.Reader = rc
+	if strings.HasSuffix(f.Name, "b") {
+		dec, err := zstd.NewReader(rc)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidPackage, err)
+		}
+		defer dec.Close()
+		src = dec
+	}
+
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	n, err := io.Copy(out, io.LimitReader(src, MaxCollectionSize+1))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidPackage, err)
+	}
+	if n > MaxCollectionSize {
+		return ErrTooLarge
+	}
+
+	return out.Close()
+}
+
+type noteType struct {
+	name   string
+	fields []string
+}
+
+func readCollection(ctx context.Context, path string, limit int) ([]ReadNote, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var ver int
+	if err := db.QueryRowContext(ctx, `SELECT ver FROM col`).Scan(&ver); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPackage, err)
+	}
+
+	// schema 11 keeps the note types as json in col, later schemas in their own tables
+	var types map[int64]noteType
+	if ver < 15 {
+		types, err = legacyNoteTypes(ctx, db)
+	} else {
+		types, err = noteTypes(ctx, db)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPackage, err)
+	}
+
+	// sqlite reads no limit as a negative one
+	if limit <= 0 {
+		limit = -1
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT guid, mid, tags, flds FROM notes ORDER BY id LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPackage, err)
+	}
+	defer rows.Close()
+
+	out := make([]ReadNote, 0, 256)
+	for rows.Next() {
+		var (
+			n          ReadNote
+			mid        int64
+			tags, flds string
+		)
+		if err := rows.Scan(&n.Guid, &mid, &tags, &flds); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidPackage, err)
+		}
+
+		t := types[mid]
+		n.Model, n.Names = t.name, t.fields
+		n.Tags = strings.Fields(tags)
+		for _, f := range strings.Split(flds, "\x1f") {
+			n.Fields = append(n.Fields, plainText(f))
+		}
+
+		out = append(out, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPackage, err)
+	}
+
+	return out, nil
+}
+
+func legacyNoteTypes(ctx context.Context, db *sql.DB) (map[int64]noteType, error) {
+	var raw string
+	if err := db.QueryRowContext(ctx, `SELECT models FROM col`).Scan(&raw); err != nil {
+		return nil, err
+	}
+
+	var models map[string]struct {
+		Id   int64  `json:"id"`
+		Name string `json:"name"`
+		Flds []struct {
+			Name string `json:"name"`
+			Ord  int    `json:"ord"`
+		} `json:"flds"`
+	}
+	if err := json.Unmarshal([]byte(raw), &models); err != nil {
+		return nil, err
+	}
+
+	out := make(map[int64]noteType, len(models))
+	for _, m := range models {
+		t := noteType{name: m.Name, fields: make([]string, len(m.Flds))}
+		for i, f := range m.Flds {
+			if f.Ord >= 0 && f.Ord < len(t.fields) {
+				t.fields[f.Ord] = f.Name
+			} else {
+				t.fields[i] = f.Name
+			}
+		}
+		out[m.Id] = t
+	}
+
+	return out, nil
+}
+
+func noteTypes(ctx context.Context, db *sql.DB) (map[int64]noteType, error) {
+	out := make(map[int64]noteType)
+
+	rows, err := db.QueryContext(ctx, `SELECT id, name FROM notetypes`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id   int64
+			name string
+		)
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		out[id] = noteType{name: name}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	fields, err := db.QueryContext(ctx, `SELECT ntid, name FROM fields ORDER BY ntid, ord`)
+	if err != nil {
+		return nil, err
+	}
+	defer fields.Close()
+
+	for fields.Next() {
+		var (
+			ntid int64
+			name string
+		)
+		if err := fields.Scan(&ntid, &name); err != nil {
+			return nil, err
+		}
+		t := out[ntid]
+		t.fields = append(t.fields, name)
+		out[ntid] = t
+	}
+
+	return out, fields.Err()
+}
+
+var (
+	lineBreak = regexp.MustCompile(`(?i)<br\s*/?>|</div>|</p>`)
+	soundTag  = regexp.MustCompile(`\[sound:[^\]]*\]`)
+)
+
+// plainText turns a field into text: line breaks are kept, markup and sound references are dropped
+func plainText(s string) string {
+	s = lineBreak.ReplaceAllString(s, "\n")
+	s = soundTag.ReplaceAllString(s, "")
+	s = strings.ReplaceAll(stripHTML(s), "\u00a0", " ")
+
+	lines := strings.Split(s, "\n")
+	out := lines[:0]
+	for _, l := range lines {
+		if l = strings.TrimSpace(l); l != "" {
+			out = append(out, l)
+		}
+	}
+
+	return strings.Join(out, "\n")
+}
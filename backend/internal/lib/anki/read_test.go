@@ -0,0 +1,146 @@
+package anki
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteRead(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		note     Note
+		want     []string
+		wantTags []string
+	}{
+		{
+			name:     "plain note",
+			note:     Note{Guid: "g1", Word: "Haus", Translation: "house", Gender: "das"},
+			want:     []string{"Haus", "house", "", "das", ""},
+			wantTags: []string{},
+		},
+		{
+			name:     "markup is kept as text",
+			note:     Note{Guid: "g2", Word: "<b>Tisch</b> & Stuhl", Translation: `"table" < chair`},
+			want:     []string{"<b>Tisch</b> & Stuhl", `"table" < chair`, "", "", ""},
+			wantTags: []string{},
+		},
+		{
+			name:     "line breaks survive",
+			note:     Note{Guid: "g3", Word: "laufen", Translation: "to run", Example: "Ich laufe.\nDu läufst."},
+			want:     []string{"laufen", "to run", "Ich laufe.\nDu läufst.", "", ""},
+			wantTags: []string{},
+		},
+		{
+			name:     "tags with spaces",
+			note:     Note{Guid: "g4", Word: "Küche", Translation: "kitchen", Source: "Session 1", Tags: []string{"pos:noun", "at home"}},
+			want:     []string{"Küche", "kitchen", "", "", "Session 1"},
+			wantTags: []string{"pos:noun", "at_home"},
+		},
+		{
+			name: "reviewed note",
+			note: Note{Guid: "g5", Word: "gehen", Translation: "to go", Reviews: []Review{
+				{At: now.Add(-48 * time.Hour), Correct: true},
+				{At: now.Add(-24 * time.Hour), Correct: false},
+			}},
+			want:     []string{"gehen", "to go", "", "", ""},
+			wantTags: []string{},
+		},
+	}
+
+	deck := Deck{Name: "Deutsch", Description: "test deck"}
+	for _, tt := range tests {
+		deck.Notes = append(deck.Notes, tt.note)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(context.Background(), &buf, deck, now); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	notes, err := Read(context.Background(), bytes.NewReader(buf.Bytes()), int64(buf.Len()), 0)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if len(notes) != len(tests) {
+		t.Fatalf("read %d notes, want %d", len(notes), len(tests))
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := notes[i]
+
+			if n.Guid != tt.note.Guid {
+				t.Errorf("guid %q, want %q", n.Guid, tt.note.Guid)
+			}
+			if n.Model != modelName {
+				t.Errorf("model %q, want %q", n.Model, modelName)
+			}
+			if !reflect.DeepEqual(n.Names, Fields) {
+				t.Errorf("field names %q, want %q", n.Names, Fields)
+			}
+			if !reflect.DeepEqual(n.Fields, tt.want) {
+				t.Errorf("fields %q, want %q", n.Fields, tt.want)
+			}
+			if tags := append([]string{}, n.Tags...); !reflect.DeepEqual(tags, tt.wantTags) {
+				t.Errorf("tags %q, want %q", tags, tt.wantTags)
+			}
+		})
+	}
+}
+
+func TestReadLimit(t *testing.T) {
+	deck := Deck{Name: "Deutsch"}
+	for _, w := range []string{"eins", "zwei", "drei"} {
+		deck.Notes = append(deck.Notes, Note{Guid: w, Word: w, Translation: w})
+	}
+
+	var buf bytes.Buffer
+	if err := Write(context.Background(), &buf, deck, time.Now()); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	tests := []struct {
+		limit int
+		want  int
+	}{
+		{0, 3},
+		{-1, 3},
+		{2, 2},
+		{4, 3},
+	}
+
+	for _, tt := range tests {
+		notes, err := Read(context.Background(), bytes.NewReader(buf.Bytes()), int64(buf.Len()), tt.limit)
+		if err != nil {
+			t.Fatalf("limit %d: %v", tt.limit, err)
+		}
+		if len(notes) != tt.want {
+			t.Errorf("limit %d read %d notes, want %d", tt.limit, len(notes), tt.want)
+		}
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not a zip", []byte("collection")},
+		{"empty", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(context.Background(), bytes.NewReader(tt.data), int64(len(tt.data)), 0)
+			if !errors.Is(err, ErrInvalidPackage) {
+				t.Fatalf("got %v, want %v", err, ErrInvalidPackage)
+			}
+		})
+	}
+}
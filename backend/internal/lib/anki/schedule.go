@@ -0,0 +1,84 @@
+package anki
+
+import (
+	"slices"
+	"time"
+)
+
+// card types and queues of Anki
+const (
+	typeNew    = 0
+	typeReview = 2
+
+	queueNew    = 0
+	queueReview = 2
+)
+
+// revlog types
+const (
+	revLearn  = 0
+	revReview = 1
+)
+
+const (
+	startFactor = 2500
+	minFactor   = 1300
+)
+
+type cardSchedule struct {
+	cardType, queue int
+	due, ivl        int
+	factor          int
+	reps, lapses    int
+	log             []revEntry
+}
+
+type revEntry struct {
+	at           time.Time
+	ease         int
+	ivl, lastIvl int
+	factor, kind int
+}
+
+// schedule replays the answers with the SM-2 rules Anki uses, so the card continues where it was.
+// A note without answers is a new card at position pos. Due days are counted from crt.
+func schedule(reviews []Review, crt time.Time, pos int) cardSchedule {
+	if len(reviews) == 0 {
+		return cardSchedule{cardType: typeNew, queue: queueNew, due: pos}
+	}
+
+	reviews = slices.Clone(reviews)
+	slices.SortFunc(reviews, func(a, b Review) int { return a.At.Compare(b.At) })
+
+	s := cardSchedule{cardType: typeReview, queue: queueReview, factor: startFactor}
+	for _, r := range reviews {
+		last := s.ivl
+		kind := revReview
+		if last == 0 {
+			kind = revLearn
+		}
+
+		ease := 3
+		switch {
+		case !r.Correct:
+			ease = 1
+			if last > 0 {
+				s.lapses++
+				s.factor = max(minFactor, s.factor-200)
+			}
+			s.ivl = 1
+		case last == 0:
+			s.ivl = 1
+		default:
+			s.ivl = max(last+1, last*s.factor/1000)
+		}
+
+		s.reps++
+		s.log = append(s.log, revEntry{at: r.At, ease: ease, ivl: s.ivl, lastIvl: last, factor: s.factor, kind: kind})
+	}
+
+	lastDay := day(reviews[len(reviews)-1].At)
+	s.due = int(lastDay.Sub(crt).Hours()/24) + s.ivl
+
+	return s
+}
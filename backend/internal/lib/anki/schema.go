@@ -0,0 +1,205 @@
+package anki
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// modelId is fixed so that every export uses the same note type once it has been imported
+const modelId int64 = 1718035200000
+
+const modelName = "Pythia Vocabulary"
+
+const schema = `
+CREATE TABLE col (
+    id     integer PRIMARY KEY,
+    crt    integer NOT NULL,
+    mod    integer NOT NULL,
+    scm    integer NOT NULL,
+    ver    integer NOT NULL,
+    dty    integer NOT NULL,
+    usn    integer NOT NULL,
+    ls     integer NOT NULL,
+    conf   text NOT NULL,
+    models text NOT NULL,
+    decks  text NOT NULL,
+    dconf  text NOT NULL,
+    tags   text NOT NULL
+);
+CREATE TABLE notes (
+    id    integer PRIMARY KEY,
+    guid  text NOT NULL,
+    mid   integer NOT NULL,
+    mod   integer NOT NULL,
+    usn   integer NOT NULL,
+    tags  text NOT NULL,
+    flds  text NOT NULL,
+    sfld  integer NOT NULL,
+    csum  integer NOT NULL,
+    flags integer NOT NULL,
+    data  text NOT NULL
+);
+CREATE TABLE cards (
+    id     integer PRIMARY KEY,
+    nid    integer NOT NULL,
+    did    integer NOT NULL,
+    ord    integer NOT NULL,
+    mod    integer NOT NULL,
+    usn    integer NOT NULL,
+    type   integer NOT NULL,
+    queue  integer NOT NULL,
+    due    integer NOT NULL,
+    ivl    integer NOT NULL,
+    factor integer NOT NULL,
+    reps   integer NOT NULL,
+    lapses integer NOT NULL,
+    left   integer NOT NULL,
+    odue   integer NOT NULL,
+    odid   integer NOT NULL,
+    flags  integer NOT NULL,
+    data   text NOT NULL
+);
+CREATE TABLE revlog (
+    id      integer PRIMARY KEY,
+    cid     integer NOT NULL,
+    usn     integer NOT NULL,
+    ease    integer NOT NULL,
+    ivl     integer NOT NULL,
+    lastIvl integer NOT NULL,
+    factor  integer NOT NULL,
+    time    integer NOT NULL,
+    type    integer NOT NULL
+);
+CREATE TABLE graves (
+    usn  integer NOT NULL,
+    oid  integer NOT NULL,
+    type integer NOT NULL
+);
+CREATE INDEX ix_notes_usn ON notes (usn);
+CREATE INDEX ix_cards_usn ON cards (usn);
+CREATE INDEX ix_revlog_usn ON revlog (usn);
+CREATE INDEX ix_cards_nid ON cards (nid);
+CREATE INDEX ix_cards_sched ON cards (did, queue, due);
+CREATE INDEX ix_revlog_cid ON revlog (cid);
+CREATE INDEX ix_notes_csum ON notes (csum);
+`
+
+const css = `.card {
+  font-family: arial;
+  font-size: 22px;
+  text-align: center;
+  color: black;
+  background-color: white;
+}
+.gender, .source { font-size: 14px; color: #888; }
+.example { font-style: italic; margin-top: 12px; }
+`
+
+const (
+	front = `<div class="word">{{Word}}</div>{{#Gender}}<div class="gender">{{Gender}}</div>{{/Gender}}`
+	back  = `{{FrontSide}}<hr id=answer><div class="translation">{{Translation}}</div>` +
+		`{{#Example}}<div class="example">{{Example}}</div>{{/Example}}` +
+		`{{#Source}}<div class="source">{{Source}}</div>{{/Source}}`
+)
+
+type collection struct {
+	conf, models, decks, dconf string
+}
+
+// collectionRow returns the json columns of the col table: the note type, the deck and the default options
+func collectionRow(deck Deck, deckId int64, now time.Time, notes int) (collection, error) {
+	var (
+		c   collection
+		err error
+	)
+
+	mod := now.Unix()
+
+	flds := make([]map[string]any, len(Fields))
+	for i, name := range Fields {
+		flds[i] = map[string]any{
+			"name": name, "ord": i, "sticky": false, "rtl": false,
+			"font": "Arial", "size": 20, "media": []string{},
+		}
+	}
+
+	model := map[string]any{
+		"id":    modelId,
+		"name":  modelName,
+		"type":  0,
+		"mod":   mod,
+		"usn":   -1,
+		"sortf": 0,
+		"did":   deckId,
+		"tmpls": []map[string]any{{
+			"name": "Card 1", "ord": 0, "qfmt": front, "afmt": back,
+			"bqfmt": "", "bafmt": "", "did": nil, "bfont": "", "bsize": 0,
+		}},
+		"flds":      flds,
+		"css":       css,
+		"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage[utf8]{inputenc}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+		"latexPost": "\\end{document}",
+		"latexsvg":  false,
+		"req":       [][]any{{0, "any", []int{0}}},
+		"tags":      []string{},
+		"vers":      []any{},
+	}
+
+	newDeck := func(id int64, name, desc string) map[string]any {
+		return map[string]any{
+			"id": id, "name": name, "desc": desc, "mod": mod, "usn": -1,
+			"collapsed": false, "browserCollapsed": false, "dyn": 0, "conf": 1,
+			"extendNew": 0, "extendRev": 0,
+			"newToday": []int{0, 0}, "revToday": []int{0, 0}, "lrnToday": []int{0, 0}, "timeToday": []int{0, 0},
+		}
+	}
+
+	decks := map[string]any{
+		"1":                           newDeck(1, "Default", ""),
+		strconv.FormatInt(deckId, 10): newDeck(deckId, deck.Name, deck.Description),
+	}
+
+	dconf := map[string]any{
+		"1": map[string]any{
+			"id": 1, "name": "Default", "mod": 0, "usn": 0, "maxTaken": 60,
+			"autoplay": true, "timer": 0, "replayq": true, "dyn": false,
+			"new": map[string]any{
+				"bury": false, "delays": []float64{1, 10}, "initialFactor": 2500,
+				"ints": []int{1, 4, 0}, "order": 1, "perDay": 20,
+			},
+			"lapse": map[string]any{
+				"delays": []float64{10}, "leechAction": 1, "leechFails": 8, "minInt": 1, "mult": 0,
+			},
+			"rev": map[string]any{
+				"bury": false, "ease4": 1.3, "ivlFct": 1, "maxIvl": 36500, "perDay": 200, "hardFactor": 1.2,
+			},
+		},
+	}
+
+	conf := map[string]any{
+		"activeDecks": []int64{deckId}, "curDeck": deckId, "newSpread": 0, "collapseTime": 1200,
+		"timeLim": 0, "estTimes": true, "dueCounts": true, "curModel": modelId,
+		"nextPos": notes + 1, "sortType": "noteFld", "sortBackwards": false, "addToCur": true,
+	}
+
+	if c.models, err = jsonString(map[string]any{strconv.FormatInt(modelId, 10): model}); err != nil {
+		return c, err
+	}
+	if c.decks, err = jsonString(decks); err != nil {
+		return c, err
+	}
+	if c.dconf, err = jsonString(dconf); err != nil {
+		return c, err
+	}
+	if c.conf, err = jsonString(conf); err != nil {
+		return c, err
+	}
+
+	return c, nil
+}
+
+func jsonString(v any) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
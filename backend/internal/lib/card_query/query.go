@@ -0,0 +1,453 @@
+// Package card_query parses the filter language of smart filters.
+//
+// A query is a list of conditions joined with and, or, not and parentheses, "and" binds tighter than "or":
+//
+//	lang = de and pos = noun and misses[7d] >= 2
+//	(tag = kitchen or tag = food) and not level < b1
+//
+// A condition is a field, an operator and a value. Values are bare words or double quoted strings.
+// The fields and what they accept are listed in Fields, anything else is rejected so a query
+// never reaches the database unchecked.
+package card_query
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	MaxLen        = 500
+	MaxConditions = 20
+	MaxDepth      = 10
+)
+
+var ErrInvalidQuery = errors.New("invalid query")
+
+// Kind is the type of the value a field compares with
+type Kind int
+
+const (
+	KindText Kind = iota
+	KindInt
+	KindDuration
+	KindLevel
+	KindUUID
+)
+
+type Field struct {
+	Ops  []string
+	Kind Kind
+	// Window allows a time window in brackets, e.g. misses[7d]
+	Window bool
+}
+
+var (
+	eqOps  = []string{"=", "!="}
+	cmpOps = []string{"=", "!=", "<", "<=", ">", ">="}
+)
+
+var Fields = map[string]Field{
+	"lang":    {Ops: eqOps, Kind: KindText},
+	"tag":     {Ops: eqOps, Kind: KindText},
+	"pos":     {Ops: eqOps, Kind: KindText},
+	"level":   {Ops: cmpOps, Kind: KindLevel},
+	"session": {Ops: eqOps, Kind: KindUUID},
+	"deck":    {Ops: eqOps, Kind: KindUUID},
+	"word":    {Ops: []string{"=", "!=", "~"}, Kind: KindText},
+	"misses":  {Ops: cmpOps, Kind: KindInt, Window: true},
+	"reviews": {Ops: cmpOps, Kind: KindInt, Window: true},
+	"age":     {Ops: []string{"<", "<=", ">", ">="}, Kind: KindDuration},
+}
+
+// Levels are the CEFR levels in ascending order
+var Levels = []string{"A1", "A2", "B1", "B2", "C1", "C2"}
+
+type Node interface {
+	String() string
+}
+
+type And struct{ Left, Right Node }
+type Or struct{ Left, Right Node }
+type Not struct{ X Node }
+
+type Cond struct {
+	Field string
+	// Window is zero when the condition spans the whole history
+	Window time.Duration
+	Op     string
+	// Value is the value as written, the typed fields are set by the field kind
+	Value string
+	Int   int
+	Dur   time.Duration
+	Id    uuid.UUID
+}
+
+func (n *And) String() string { return "(" + n.Left.String() + " and " + n.Right.String() + ")" }
+func (n *Or) String() string  { return "(" + n.Left.String() + " or " + n.Right.String() + ")" }
+func (n *Not) String() string { return "not " + n.X.String() }
+func (c *Cond) String() string {
+	f := c.Field
+	if c.Window > 0 {
+		f += "[" + FormatDuration(c.Window) + "]"
+	}
+	return fmt.Sprintf("%s %s %s", f, c.Op, strconv.Quote(c.Value))
+}
+
+// Walk calls fn for every condition of the query, it stops at the first error
+func Walk(n Node, fn func(c *Cond) error) error {
+	switch n := n.(type) {
+	case *And:
+		if err := Walk(n.Left, fn); err != nil {
+			return err
+		}
+		return Walk(n.Right, fn)
+	case *Or:
+		if err := Walk(n.Left, fn); err != nil {
+			return err
+		}
+		return Walk(n.Right, fn)
+	case *Not:
+		return Walk(n.X, fn)
+	case *Cond:
+		return fn(n)
+	}
+
+	return nil
+}
+
+// Parse validates the query and returns its tree
+func Parse(q string) (Node, error) {
+	if len(q) > MaxLen {
+		return nil, fmt.Errorf("%w: longer than %d characters", ErrInvalidQuery, MaxLen)
+	}
+
+	toks, err := lex(q)
+	if err != nil {
+		return nil, err
+	}
+	if len(toks) == 0 {
+		return nil, fmt.Errorf("%w: empty query", ErrInvalidQuery)
+	}
+
+	p := &parser{toks: toks, end: utf8.RuneCountInString(q)}
+	n, err := p.or(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, p.errorf("unexpected %q", p.toks[p.pos].text)
+	}
+
+	return n, nil
+}
+
+type tokKind int
+
+const (
+	tokWord tokKind = iota
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokLBracket
+	tokRBracket
+)
+
+type token struct {
+	kind tokKind
+	text string
+	at   int
+}
+
+func lex(q string) ([]token, error) {
+	var toks []token
+	rs := []rune(q)
+
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			toks = append(toks, token{tokLParen, "(", i})
+			i++
+		case r == ')':
+			toks = append(toks, token{tokRParen, ")", i})
+			i++
+		case r == '[':
+			toks = append(toks, token{tokLBracket, "[", i})
+			i++
+		case r == ']':
+			toks = append(toks, token{tokRBracket, "]", i})
+			i++
+		case strings.ContainsRune("=!<>~", r):
+			start := i
+			i++
+			if i < len(rs) && rs[i] == '=' && r != '=' && r != '~' {
+				i++
+			}
+			op := string(rs[start:i])
+			if op == "!" {
+				return nil, fmt.Errorf("%w: unknown operator at %d", ErrInvalidQuery, start)
+			}
+			toks = append(toks, token{tokOp, op, start})
+		case r == '"':
+			start := i
+			var sb strings.Builder
+			i++
+			for ; i < len(rs) && rs[i] != '"'; i++ {
+				if rs[i] == '\\' && i+1 < len(rs) {
+					i++
+				}
+				sb.WriteRune(rs[i])
+			}
+			if i == len(rs) {
+				return nil, fmt.Errorf("%w: unterminated string at %d", ErrInvalidQuery, start)
+			}
+			i++
+			toks = append(toks, token{tokString, sb.String(), start})
+		default:
+			start := i
+			for i < len(rs) && !unicode.IsSpace(rs[i]) && !strings.ContainsRune(`()[]"=!<>~`, rs[i]) {
+				i++
+			}
+			toks = append(toks, token{tokWord, string(rs[start:i]), start})
+		}
+	}
+
+	return toks, nil
+}
+
+type parser struct {
+	toks  []token
+	pos   int
+	conds int
+	// end is the position reported for errors at the end of the query
+	end int
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	at := p.end
+	if p.pos < len(p.toks) {
+		at = p.toks[p.pos].at
+	}
+	return fmt.Errorf("%w: %s at %d", ErrInvalidQuery, fmt.Sprintf(format, args...), at)
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.toks) {
+		return token{}, false
+	}
+	return p.toks[p.pos], true
+}
+
+func (p *parser) keyword(kw string) bool {
+	t, ok := p.peek()
+	if ok && t.kind == tokWord && strings.EqualFold(t.text, kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) or(depth int) (Node, error) {
+	left, err := p.and(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.and(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &Or{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) and(depth int) (Node, error) {
+	left, err := p.unary(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.unary(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &And{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) unary(depth int) (Node, error) {
+	if depth > MaxDepth {
+		return nil, p.errorf("nested deeper than %d", MaxDepth)
+	}
+
+	if p.keyword("not") {
+		x, err := p.unary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return &Not{x}, nil
+	}
+
+	t, ok := p.peek()
+	if !ok {
+		return nil, p.errorf("condition expected")
+	}
+	if t.kind == tokLParen {
+		p.pos++
+		n, err := p.or(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if t, ok := p.peek(); !ok || t.kind != tokRParen {
+			return nil, p.errorf("missing )")
+		}
+		p.pos++
+		return n, nil
+	}
+
+	return p.cond()
+}
+
+func (p *parser) cond() (Node, error) {
+	t, _ := p.peek()
+	if t.kind != tokWord {
+		return nil, p.errorf("field expected")
+	}
+
+	name := strings.ToLower(t.text)
+	field, ok := Fields[name]
+	if !ok {
+		return nil, p.errorf("unknown field %q", t.text)
+	}
+	p.pos++
+
+	p.conds++
+	if p.conds > MaxConditions {
+		return nil, p.errorf("more than %d conditions", MaxConditions)
+	}
+
+	c := &Cond{Field: name}
+
+	if t, ok := p.peek(); ok && t.kind == tokLBracket {
+		if !field.Window {
+			return nil, p.errorf("%s takes no time window", name)
+		}
+		p.pos++
+		w, ok := p.peek()
+		if !ok || w.kind != tokWord {
+			return nil, p.errorf("time window expected")
+		}
+		d, err := ParseDuration(w.text)
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		c.Window = d
+		p.pos++
+		if t, ok := p.peek(); !ok || t.kind != tokRBracket {
+			return nil, p.errorf("missing ]")
+		}
+		p.pos++
+	}
+
+	op, ok := p.peek()
+	if !ok || op.kind != tokOp {
+		return nil, p.errorf("operator expected after %s", name)
+	}
+	if !slices.Contains(field.Ops, op.text) {
+		return nil, p.errorf("%s doesn't support %s", name, op.text)
+	}
+	c.Op = op.text
+	p.pos++
+
+	v, ok := p.peek()
+	if !ok || (v.kind != tokWord && v.kind != tokString) {
+		return nil, p.errorf("value expected after %s %s", name, c.Op)
+	}
+	c.Value = v.text
+	if err := typeValue(c, field.Kind); err != nil {
+		return nil, p.errorf("%s: %v", name, err)
+	}
+	p.pos++
+
+	return c, nil
+}
+
+func typeValue(c *Cond, kind Kind) error {
+	switch kind {
+	case KindText:
+		if strings.TrimSpace(c.Value) == "" {
+			return errors.New("empty value")
+		}
+	case KindInt:
+		n, err := strconv.Atoi(c.Value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("%q is not a count", c.Value)
+		}
+		c.Int = n
+	case KindDuration:
+		d, err := ParseDuration(c.Value)
+		if err != nil {
+			return err
+		}
+		c.Dur = d
+	case KindLevel:
+		c.Value = strings.ToUpper(c.Value)
+		if !slices.Contains(Levels, c.Value) {
+			return fmt.Errorf("unknown level %q", c.Value)
+		}
+	case KindUUID:
+		id, err := uuid.Parse(c.Value)
+		if err != nil {
+			return fmt.Errorf("%q is not an id", c.Value)
+		}
+		c.Id = id
+	}
+
+	return nil
+}
+
+// ParseDuration reads a count of hours, days or weeks such as 12h, 7d or 2w
+func ParseDuration(s string) (time.Duration, error) {
+	if len(s) < 2 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n <= 0 || n > 3650 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	switch s[len(s)-1] {
+	case 'h':
+		return time.Duration(n) * time.Hour, nil
+	case 'd':
+		return time.Duration(n) * 24 * time.Hour, nil
+	case 'w':
+		return time.Duration(n) * 7 * 24 * time.Hour, nil
+	}
+
+	return 0, fmt.Errorf("invalid duration %q, use h, d or w", s)
+}
+
+func FormatDuration(d time.Duration) string {
+	switch {
+	case d%(7*24*time.Hour) == 0:
+		return fmt.Sprintf("%dw", d/(7*24*time.Hour))
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	return fmt.Sprintf("%dh", d/time.Hour)
+}
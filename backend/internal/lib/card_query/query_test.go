@@ -0,0 +1,222 @@
+package card_query
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"single condition", `word = Haus`, `word = "Haus"`},
+		{"and binds tighter than or", `tag = a or tag = b and pos = noun`, `(tag = "a" or (tag = "b" and pos = "noun"))`},
+		{"parentheses", `(tag = a or tag = b) and pos = noun`, `((tag = "a" or tag = "b") and pos = "noun")`},
+		{"keywords in any case", `tag = a AND NOT tag = b`, `(tag = "a" and not tag = "b")`},
+		{"not of a group", `not (lang = de or lang = fr)`, `not (lang = "de" or lang = "fr")`},
+		{"no spaces around operators", `misses[7d]>=2 and word~hau`, `(misses[1w] >= "2" and word ~ "hau")`},
+		{"field names in any case", `Word != Haus`, `word != "Haus"`},
+		{"quoted value with spaces", `tag = "at home"`, `tag = "at home"`},
+		{"escaped quote", `word = "say \"hi\""`, `word = "say \"hi\""`},
+		{"escaped backslash", `word = "a\\b"`, `word = "a\\b"`},
+		{"operators inside quotes", `word = "a = b or (c)"`, `word = "a = b or (c)"`},
+		{"unicode word", `word = Küche`, `word = "Küche"`},
+		{"level is upper cased", `level >= b1`, `level >= "B1"`},
+		{"or is left associative", `age < 30d or age > 2w or age <= 12h`, `((age < "30d" or age > "2w") or age <= "12h")`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := Parse(tt.query)
+			if err != nil {
+				t.Fatalf("parse %q: %v", tt.query, err)
+			}
+			if got := n.String(); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	id := uuid.New()
+
+	tests := []struct {
+		name  string
+		query string
+		want  *Cond
+	}{
+		{
+			name:  "text",
+			query: `tag = kitchen`,
+			want:  &Cond{Field: "tag", Op: "=", Value: "kitchen"},
+		},
+		{
+			name:  "count in a window",
+			query: `misses[2w] >= 3`,
+			want:  &Cond{Field: "misses", Window: 14 * 24 * time.Hour, Op: ">=", Value: "3", Int: 3},
+		},
+		{
+			name:  "count over the whole history",
+			query: `reviews = 0`,
+			want:  &Cond{Field: "reviews", Op: "=", Value: "0"},
+		},
+		{
+			name:  "duration",
+			query: `age > 12h`,
+			want:  &Cond{Field: "age", Op: ">", Value: "12h", Dur: 12 * time.Hour},
+		},
+		{
+			name:  "level",
+			query: `level < c1`,
+			want:  &Cond{Field: "level", Op: "<", Value: "C1"},
+		},
+		{
+			name:  "uuid",
+			query: `deck = ` + id.String(),
+			want:  &Cond{Field: "deck", Op: "=", Value: id.String(), Id: id},
+		},
+		{
+			name:  "quoted uuid",
+			query: `session != "` + id.String() + `"`,
+			want:  &Cond{Field: "session", Op: "!=", Value: id.String(), Id: id},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := Parse(tt.query)
+			if err != nil {
+				t.Fatalf("parse %q: %v", tt.query, err)
+			}
+			if !reflect.DeepEqual(n, tt.want) {
+				t.Errorf("got %+v, want %+v", n, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	nested := func(depth int) string {
+		return strings.Repeat("(", depth) + "word = a" + strings.Repeat(")", depth)
+	}
+	conds := func(n int) string {
+		return strings.TrimSuffix(strings.Repeat("tag = a and ", n), " and ")
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		wantMsg string
+	}{
+		{"too long", `word = ` + strings.Repeat("a", MaxLen), "longer than"},
+		{"empty", "", "empty query"},
+		{"only spaces", "  \t ", "empty query"},
+
+		{"lone bang", `word ! a`, "unknown operator"},
+		{"unknown field", `color = red`, "unknown field"},
+		{"field expected", `= a`, "field expected"},
+		{"operator expected", `word Haus`, "operator expected"},
+		{"operator at the end", `word`, "operator expected"},
+		{"unsupported operator", `tag < a`, "tag doesn't support <"},
+		{"like on a tag", `tag ~ a`, "tag doesn't support ~"},
+		{"equality on age", `age = 3d`, "age doesn't support ="},
+		{"doubled operator", `word == a`, "value expected"},
+		{"value expected", `word =`, "value expected"},
+		{"parenthesis as value", `word = (a)`, "value expected"},
+		{"empty text", `word = "  "`, "empty value"},
+
+		{"unterminated string", `word = "Haus`, "unterminated string"},
+		{"escaped closing quote", `word = "Haus\"`, "unterminated string"},
+
+		{"missing )", `(word = a`, "missing )"},
+		{"unopened )", `word = a)`, `unexpected ")"`},
+		{"two values", `word = a b`, `unexpected "b"`},
+		{"dangling and", `word = a and`, "condition expected"},
+		{"dangling not", `not`, "condition expected"},
+		{"empty group", `()`, "field expected"},
+
+		{"window on a text field", `tag[7d] = a`, "tag takes no time window"},
+		{"empty window", `misses[] > 1`, "time window expected"},
+		{"unknown window unit", `misses[7m] > 1`, "use h, d or w"},
+		{"zero window", `misses[0d] > 1`, "invalid duration"},
+		{"window too long", `misses[3651d] > 1`, "invalid duration"},
+		{"missing ]", `misses[7d > 1`, "missing ]"},
+
+		{"negative count", `misses > -1`, "is not a count"},
+		{"count as text", `reviews > many`, "is not a count"},
+		{"duration without unit", `age < 30`, "invalid duration"},
+		{"unknown level", `level = d1`, "unknown level"},
+		{"invalid uuid", `deck = kitchen`, "is not an id"},
+
+		{"too many conditions", conds(MaxConditions + 1), "more than 20 conditions"},
+		{"nested too deep", nested(MaxDepth + 1), "nested deeper than 10"},
+		{"not nested too deep", strings.Repeat("not ", MaxDepth+1) + "word = a", "nested deeper than 10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.query)
+			if !errors.Is(err, ErrInvalidQuery) {
+				t.Fatalf("parse %q: got %v, want %v", tt.query, err, ErrInvalidQuery)
+			}
+			if !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("parse %q: error %q doesn't mention %q", tt.query, err, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"longest query", `word = ` + strings.Repeat("a", MaxLen-len(`word = `))},
+		{"most conditions", strings.TrimSuffix(strings.Repeat("tag = a or ", MaxConditions), " or ")},
+		{"deepest nesting", strings.Repeat("(", MaxDepth) + "word = a" + strings.Repeat(")", MaxDepth)},
+		{"deepest not", strings.Repeat("not ", MaxDepth) + "word = a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.query); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"12h", 12 * time.Hour},
+		{"7d", 7 * 24 * time.Hour},
+		{"2w", 14 * 24 * time.Hour},
+		{"36h", 36 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			d, err := ParseDuration(tt.in)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if d != tt.want {
+				t.Errorf("got %v, want %v", d, tt.want)
+			}
+			if back, _ := ParseDuration(FormatDuration(d)); back != d {
+				t.Errorf("%q formats to %q", tt.in, FormatDuration(d))
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package img_preprocessor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+)
+
+const (
+	markerSOS        = 0xDA
+	markerAPP1       = 0xE1
+	tagOrientation   = 0x0112
+	orientationUpper = 8
+)
+
+// exifOrientation returns the EXIF orientation tag of a JPEG, 1 (normal) if it is missing or broken
+func exifOrientation(data []byte) int {
+	i := 2 // skip SOI
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return 1
+		}
+
+		marker := data[i+1]
+		if marker == markerSOS {
+			return 1
+		}
+
+		size := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		if size < 2 || i+2+size > len(data) {
+			return 1
+		}
+
+		segment := data[i+4 : i+2+size]
+		if marker == markerAPP1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return parseTIFFOrientation(segment[6:])
+		}
+
+		i += 2 + size
+	}
+
+	return 1
+}
+
+func parseTIFFOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+
+	entries := int(order.Uint16(tiff[ifd : ifd+2]))
+	for n := 0; n < entries; n++ {
+		off := ifd + 2 + n*12
+		if off+12 > len(tiff) {
+			return 1
+		}
+
+		if order.Uint16(tiff[off:off+2]) != tagOrientation {
+			continue
+		}
+
+		v := int(order.Uint16(tiff[off+8 : off+10]))
+		if v < 1 || v > orientationUpper {
+			return 1
+		}
+		return v
+	}
+
+	return 1
+}
+
+// applyOrientation rotates and flips img so it is displayed upright.
+// The pixels are copied between the Pix slices, other image types are converted to RGBA first.
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > orientationUpper {
+		return img
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	dst := image.Rect(0, 0, w, h)
+	if orientation >= 5 {
+		dst = image.Rect(0, 0, h, w)
+	}
+
+	switch src := img.(type) {
+	case *image.RGBA:
+		out := image.NewRGBA(dst)
+		orientPix(out.Pix, out.Stride, src.Pix, src.Stride, src.PixOffset(b.Min.X, b.Min.Y), w, h, 4, orientation)
+		return out
+	case *image.NRGBA:
+		out := image.NewNRGBA(dst)
+		orientPix(out.Pix, out.Stride, src.Pix, src.Stride, src.PixOffset(b.Min.X, b.Min.Y), w, h, 4, orientation)
+		return out
+	case *image.Gray:
+		out := image.NewGray(dst)
+		orientPix(out.Pix, out.Stride, src.Pix, src.Stride, src.PixOffset(b.Min.X, b.Min.Y), w, h, 1, orientation)
+		return out
+	}
+
+	rgba := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+
+	return applyOrientation(rgba, orientation)
+}
+
+// orientPix copies the w x h pixels starting at src[off] into dst turned by orientation, a pixel is bpp bytes.
+// Every orientation maps the destination pixel (x, y) linearly onto the source, so a row is a walk with a fixed step.
+func orientPix(dst []byte, dstStride int, src []byte, srcStride, off, w, h, bpp, orientation int) {
+	// the source pixel of the destination (0, 0) and the source steps for x+1 and y+1
+	var x0, y0, xx, xy, yx, yy int
+	switch orientation {
+	case 2:
+		x0, xx, yy = w-1, -1, 1
+	case 3:
+		x0, y0, xx, yy = w-1, h-1, -1, -1
+	case 4:
+		y0, xx, yy = h-1, 1, -1
+	case 5:
+		xy, yx = 1, 1
+	case 6:
+		y0, xy, yx = h-1, 1, -1
+	case 7:
+		x0, y0, xy, yx = w-1, h-1, -1, -1
+	case 8:
+		x0, xy, yx = w-1, -1, 1
+	}
+
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	start := off + y0*srcStride + x0*bpp
+	stepX := yx*srcStride + xx*bpp
+	stepY := yy*srcStride + xy*bpp
+
+	for y := 0; y < dh; y++ {
+		i := start + y*stepY
+		row := dst[y*dstStride : y*dstStride+dw*bpp]
+		for d := 0; d < len(row); d += bpp {
+			copy(row[d:d+bpp], src[i:i+bpp])
+			i += stepX
+		}
+	}
+}
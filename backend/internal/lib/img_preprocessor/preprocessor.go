@@ -0,0 +1,178 @@
+package img_preprocessor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrTooLarge          = errors.New("image is too large")
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrCorrupted         = errors.New("image is corrupted")
+)
+
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatGIF  = "gif"
+	FormatWEBP = "webp"
+)
+
+type Config struct {
+	MaxBytes    int64   // upload limit, checked before decoding
+	MaxPixels   int     // decompression-bomb guard, checked on the header only
+	MaxSide     int     // longest side after downscaling, 0 keeps the original size
+	Grayscale   bool    // drop colour information before OCR
+	Contrast    float64 // contrast factor, values <= 1 leave the image as is
+	JPEGQuality int
+}
+
+type Preprocessor struct {
+	cfg Config
+}
+
+func New(cfg Config) *Preprocessor {
+	if cfg.JPEGQuality <= 0 || cfg.JPEGQuality > 100 {
+		cfg.JPEGQuality = jpeg.DefaultQuality
+	}
+
+	return &Preprocessor{cfg: cfg}
+}
+
+func (p *Preprocessor) MaxBytes() int64 {
+	return p.cfg.MaxBytes
+}
+
+// Process validates the upload and returns a normalized JPEG ready for OCR.
+// The JPEG is upright and at most MaxSide long, the coordinates the OCR finds are relative to it.
+func (p *Preprocessor) Process(data []byte) ([]byte, error) {
+	const op = "img_preprocessor.Preprocessor.Process"
+
+	if p.cfg.MaxBytes > 0 && int64(len(data)) > p.cfg.MaxBytes {
+		return nil, fmt.Errorf("%s:%w", op, ErrTooLarge)
+	}
+
+	format := DetectFormat(data)
+	if format == "" {
+		return nil, fmt.Errorf("%s:%w", op, ErrUnsupportedFormat)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, ErrCorrupted)
+	}
+	if p.cfg.MaxPixels > 0 && cfg.Width*cfg.Height > p.cfg.MaxPixels {
+		return nil, fmt.Errorf("%s:%w", op, ErrTooLarge)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, ErrCorrupted)
+	}
+
+	// downscale first, rotating a smaller image is cheaper and the longest side stays the same
+	img = downscale(img, p.cfg.MaxSide)
+
+	if format == FormatJPEG {
+		img = applyOrientation(img, exifOrientation(data))
+	}
+
+	if p.cfg.Grayscale {
+		img = grayscale(img)
+	}
+
+	if p.cfg.Contrast > 1 {
+		img = boostContrast(img, p.cfg.Contrast)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: p.cfg.JPEGQuality}); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// DetectFormat sniffs the magic bytes, the file name and content type sent by the client are not trusted
+func DetectFormat(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return FormatJPEG
+	case bytes.HasPrefix(data, []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n'}):
+		return FormatPNG
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return FormatGIF
+	case len(data) >= 12 && bytes.Equal(data[:4], []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WEBP")):
+		return FormatWEBP
+	default:
+		return ""
+	}
+}
+
+func downscale(img image.Image, maxSide int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if maxSide <= 0 || (w <= maxSide && h <= maxSide) {
+		return img
+	}
+
+	if w >= h {
+		h = h * maxSide / w
+		w = maxSide
+	} else {
+		w = w * maxSide / h
+		h = maxSide
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, max(w, 1), max(h, 1)))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, b, xdraw.Src, nil)
+
+	return dst
+}
+
+func grayscale(img image.Image) *image.Gray {
+	b := img.Bounds()
+	dst := image.NewGray(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+
+	return dst
+}
+
+// boostContrast stretches pixel values around the middle gray by factor
+func boostContrast(img image.Image, factor float64) image.Image {
+	var lut [256]uint8
+	for i := range lut {
+		v := (float64(i)-128)*factor + 128
+		lut[i] = uint8(min(max(v, 0), 255))
+	}
+
+	b := img.Bounds()
+
+	if g, ok := img.(*image.Gray); ok {
+		dst := image.NewGray(image.Rect(0, 0, b.Dx(), b.Dy()))
+		for i, v := range g.Pix {
+			dst.Pix[i] = lut[v]
+		}
+		return dst
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			c := color.RGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.RGBA)
+			dst.SetRGBA(x, y, color.RGBA{R: lut[c.R], G: lut[c.G], B: lut[c.B], A: c.A})
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,561 @@
+package job_queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/rwrrioe/pythia/backend/internal/lib/logger/sl"
+)
+
+// Every stage is a redis stream read by the consumer group of the workers.
+// A delivered job stays pending until it is acked, if the worker dies or doesn't finish within
+// the visibility timeout another worker claims it. Failed jobs are rescheduled through a sorted set
+// with exponential backoff and end up in the dead letter stream after the last attempt.
+// Delivery is at-least-once, handlers must be idempotent.
+
+const (
+	group    = "workers"
+	jobField = "job"
+
+	promoteInterval = time.Second
+	promoteBatch    = 100
+	deadMaxLen      = 10000
+
+	cancelChannel = "queue:cancel"
+	// a cancelled task stays marked long enough for its queued or delayed jobs to be dropped
+	cancelTTL = 24 * time.Hour
+)
+
+// ErrCancelled is the cause of the job ctx when its task is cancelled
+var ErrCancelled = errors.New("job cancelled")
+
+// ErrPermanent marks failures that retrying won't fix, such jobs are dead-lettered right away
+var ErrPermanent = errors.New("permanent job failure")
+
+// Permanent wraps err so the job is not retried
+func Permanent(err error) error {
+	return fmt.Errorf("%w: %w", ErrPermanent, err)
+}
+
+type Job struct {
+	Id        string          `json:"id"`
+	Stage     string          `json:"stage"`
+	SessionId uuid.UUID       `json:"session_id"`
+	TaskId    string          `json:"task_id"`
+	UserId    int64           `json:"user_id"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	// Next lists the stages that follow this one, the next job is enqueued together with the ack
+	Next []string `json:"next,omitempty"`
+	// Gen is the generation of the task work the job belongs to, set by Enqueue and kept by
+	// the chained stages. Cancel drops the generations enqueued before it only.
+	Gen        int64     `json:"gen"`
+	Attempt    int       `json:"attempt"`
+	LastError  string    `json:"last_error,omitempty"`
+	EnqueuedAt time.Time `json:"enqueued_at"`
+}
+
+type Handler interface {
+	Handle(ctx context.Context, job *Job) error
+	// Retry is called when a failed job is rescheduled
+	Retry(ctx context.Context, job *Job, delay time.Duration)
+	// Dead is called once the job is moved to the dead letter stream
+	Dead(ctx context.Context, job *Job)
+	// Cancelled is called when the job is dropped because its task was cancelled
+	Cancelled(ctx context.Context, job *Job)
+	// Handover is called once the next stage of the job is enqueued, never before
+	Handover(ctx context.Context, job *Job)
+}
+
+type StageConfig struct {
+	Concurrency int
+	// Visibility is the longest a job may run, afterwards it is cancelled and handed to another worker
+	Visibility  time.Duration
+	MaxAttempts int
+}
+
+type Config struct {
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// Block is how long an idle worker waits for new jobs before checking for stale ones
+	Block time.Duration
+}
+
+type Queue struct {
+	client   *redis.Client
+	log      *slog.Logger
+	cfg      Config
+	consumer string
+
+	mu      sync.Mutex
+	running map[string]runningJob
+	watch   sync.Once
+}
+
+func New(client *redis.Client, log *slog.Logger, cfg Config) *Queue {
+	host, _ := os.Hostname()
+
+	return &Queue{
+		client:   client,
+		log:      log,
+		cfg:      cfg,
+		consumer: fmt.Sprintf("%s-%d", host, os.Getpid()),
+		running:  make(map[string]runningJob),
+	}
+}
+
+func streamKey(stage string) string  { return "queue:" + stage }
+func delayedKey(stage string) string { return "queue:" + stage + ":delayed" }
+func deadKey(stage string) string    { return "queue:" + stage + ":dead" }
+func cancelKey(taskId string) string { return "queue:cancelled:" + taskId }
+func genKey(taskId string) string    { return "queue:gen:" + taskId }
+
+type runningJob struct {
+	gen    int64
+	cancel context.CancelCauseFunc
+}
+
+// Enqueue adds the job to its stage stream, the job id is generated when empty
+func (q *Queue) Enqueue(ctx context.Context, job Job) (string, error) {
+	const op = "job_queue.Queue.Enqueue"
+
+	if job.Stage == "" {
+		return "", fmt.Errorf("%s: job without stage", op)
+	}
+	if job.Id == "" {
+		job.Id = uuid.NewString()
+	}
+	if job.EnqueuedAt.IsZero() {
+		job.EnqueuedAt = time.Now()
+	}
+
+	// new work for the task starts a generation no earlier cancellation covers
+	gen, err := genScript.Run(ctx, q.client, []string{genKey(job.TaskId)}, int(cancelTTL.Seconds())).Int64()
+	if err != nil {
+		return "", fmt.Errorf("%s:%w", op, err)
+	}
+	job.Gen = gen
+
+	b, err := json.Marshal(job)
+	if err != nil {
+		return "", fmt.Errorf("%s:%w", op, err)
+	}
+
+	if err := q.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: streamKey(job.Stage),
+		Values: map[string]any{jobField: b},
+	}).Err(); err != nil {
+		return "", fmt.Errorf("%s:%w", op, err)
+	}
+
+	return job.Id, nil
+}
+
+var genScript = redis.NewScript(`
+local gen = redis.call('INCR', KEYS[1])
+redis.call('EXPIRE', KEYS[1], ARGV[1])
+return gen
+`)
+
+// cancelScript marks the generations enqueued so far as cancelled and tells the running workers
+var cancelScript = redis.NewScript(`
+local gen = redis.call('GET', KEYS[1]) or '0'
+redis.call('SET', KEYS[2], gen, 'EX', ARGV[1])
+redis.call('PUBLISH', ARGV[2], ARGV[3] .. '|' .. gen)
+return gen
+`)
+
+// Cancel stops the jobs of the task enqueued so far on every instance. A running job gets its ctx cancelled,
+// queued and delayed ones are dropped when they come up, the stages chained after them are not started.
+// Jobs enqueued after the cancellation, e.g. a retry, run as usual.
+func (q *Queue) Cancel(ctx context.Context, taskId string) error {
+	const op = "job_queue.Queue.Cancel"
+
+	err := cancelScript.Run(ctx, q.client,
+		[]string{genKey(taskId), cancelKey(taskId)},
+		int(cancelTTL.Seconds()), cancelChannel, taskId,
+	).Err()
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	return nil
+}
+
+func (q *Queue) watchCancels(ctx context.Context) {
+	sub := q.client.Subscribe(ctx, cancelChannel)
+	defer sub.Close()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			i := strings.LastIndexByte(msg.Payload, '|')
+			if i < 0 {
+				continue
+			}
+			gen, err := strconv.ParseInt(msg.Payload[i+1:], 10, 64)
+			if err != nil {
+				continue
+			}
+
+			q.mu.Lock()
+			if r, ok := q.running[msg.Payload[:i]]; ok && r.gen <= gen {
+				r.cancel(ErrCancelled)
+			}
+			q.mu.Unlock()
+		}
+	}
+}
+
+// isCancelled tells whether the generation of the job was cancelled
+func (q *Queue) isCancelled(ctx context.Context, job *Job) bool {
+	gen, err := q.client.Get(ctx, cancelKey(job.TaskId)).Int64()
+	return err == nil && job.Gen <= gen
+}
+
+// Run serves the stage with sc.Concurrency workers until ctx is done
+func (q *Queue) Run(ctx context.Context, stage string, sc StageConfig, h Handler) error {
+	const op = "job_queue.Queue.Run"
+
+	err := q.client.XGroupCreateMkStream(ctx, streamKey(stage), group, "0").Err()
+	if err != nil && !strings.Contains(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	q.watch.Do(func() {
+		go q.watchCancels(ctx)
+	})
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		q.promote(ctx, stage)
+	}()
+
+	for i := 0; i < sc.Concurrency; i++ {
+		consumer := fmt.Sprintf("%s-%d", q.consumer, i)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx, stage, consumer, sc, h)
+		}()
+	}
+
+	q.log.Info("job workers started", slog.String("stage", stage), slog.Int("concurrency", sc.Concurrency))
+	wg.Wait()
+
+	return nil
+}
+
+func (q *Queue) work(ctx context.Context, stage, consumer string, sc StageConfig, h Handler) {
+	for ctx.Err() == nil {
+		msg, ok, err := q.next(ctx, stage, consumer, sc, h)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			q.log.Error("failed to read jobs", slog.String("stage", stage), sl.Err(err))
+			time.Sleep(time.Second)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		q.process(ctx, stage, msg, sc, h)
+	}
+}
+
+// next prefers jobs abandoned by other workers over new ones
+func (q *Queue) next(ctx context.Context, stage, consumer string, sc StageConfig, h Handler) (redis.XMessage, bool, error) {
+	stream := streamKey(stage)
+
+	claimed, _, err := q.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   stream,
+		Group:    group,
+		Consumer: consumer,
+		MinIdle:  sc.Visibility,
+		Start:    "0-0",
+		Count:    1,
+	}).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return redis.XMessage{}, false, err
+	}
+	for _, msg := range claimed {
+		if msg.Values == nil {
+			continue
+		}
+
+		// a job that keeps killing its worker never fails in the handler, count the deliveries instead
+		pending, err := q.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+			Stream: stream,
+			Group:  group,
+			Start:  msg.ID,
+			End:    msg.ID,
+			Count:  1,
+		}).Result()
+		if err == nil && len(pending) == 1 && pending[0].RetryCount > int64(sc.MaxAttempts) {
+			job, _ := decode(msg)
+			job.LastError = "job exceeded its delivery limit"
+			q.dead(ctx, stage, msg.ID, job, h)
+			continue
+		}
+
+		return msg, true, nil
+	}
+
+	streams, err := q.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    group,
+		Consumer: consumer,
+		Streams:  []string{stream, ">"},
+		Count:    1,
+		Block:    q.cfg.Block,
+	}).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return redis.XMessage{}, false, nil
+		}
+		return redis.XMessage{}, false, err
+	}
+
+	for _, s := range streams {
+		for _, msg := range s.Messages {
+			return msg, true, nil
+		}
+	}
+
+	return redis.XMessage{}, false, nil
+}
+
+func (q *Queue) process(ctx context.Context, stage string, msg redis.XMessage, sc StageConfig, h Handler) {
+	job, err := decode(msg)
+	if err != nil {
+		q.log.Error("dropping malformed job", slog.String("stage", stage), slog.String("id", msg.ID), sl.Err(err))
+		job.LastError = err.Error()
+		q.dead(ctx, stage, msg.ID, job, h)
+		return
+	}
+
+	cancelCtx, cancelJob := context.WithCancelCause(ctx)
+	q.mu.Lock()
+	q.running[job.TaskId] = runningJob{gen: job.Gen, cancel: cancelJob}
+	q.mu.Unlock()
+
+	// checked after registering, a cancellation published in between is not missed
+	if q.isCancelled(ctx, job) {
+		cancelJob(ErrCancelled)
+	}
+
+	skipped := errors.Is(context.Cause(cancelCtx), ErrCancelled)
+	if !skipped {
+		jobCtx, cancel := context.WithTimeout(cancelCtx, sc.Visibility)
+		err = h.Handle(jobCtx, job)
+		cancel()
+	}
+
+	q.mu.Lock()
+	delete(q.running, job.TaskId)
+	q.mu.Unlock()
+
+	// a job finished before it saw the cancellation completes, the chained stage carries
+	// the cancelled generation and is dropped when it comes up
+	cancelled := skipped || (err != nil && errors.Is(context.Cause(cancelCtx), ErrCancelled))
+	cancelJob(nil)
+
+	if cancelled {
+		job.Next = nil
+		q.complete(ctx, stage, msg.ID, job)
+		h.Cancelled(ctx, job)
+		return
+	}
+
+	if err == nil {
+		if q.complete(ctx, stage, msg.ID, job) && len(job.Next) > 0 {
+			h.Handover(ctx, job)
+		}
+		return
+	}
+
+	// shutting down, the job stays pending and is claimed after the restart
+	if ctx.Err() != nil {
+		return
+	}
+
+	job.Attempt++
+	job.LastError = err.Error()
+
+	if errors.Is(err, ErrPermanent) || job.Attempt >= sc.MaxAttempts {
+		q.log.Warn("job failed", slog.String("stage", stage), slog.String("job_id", job.Id), slog.Int("attempt", job.Attempt), sl.Err(err))
+		q.dead(ctx, stage, msg.ID, job, h)
+		return
+	}
+
+	delay := q.backoff(job.Attempt)
+	if err := q.retry(ctx, stage, msg.ID, job, delay); err != nil {
+		q.log.Error("failed to reschedule job", slog.String("stage", stage), slog.String("job_id", job.Id), sl.Err(err))
+		return
+	}
+
+	h.Retry(ctx, job, delay)
+}
+
+func decode(msg redis.XMessage) (*Job, error) {
+	raw, ok := msg.Values[jobField].(string)
+	if !ok {
+		return &Job{}, fmt.Errorf("message %s has no job", msg.ID)
+	}
+
+	var job Job
+	if err := json.Unmarshal([]byte(raw), &job); err != nil {
+		return &Job{}, err
+	}
+
+	return &job, nil
+}
+
+// complete acks the job and hands the task over to the next stage in one transaction,
+// so a chained stage is neither lost nor started twice. It returns false when the job stays pending.
+func (q *Queue) complete(ctx context.Context, stage, msgId string, job *Job) bool {
+	var next []byte
+	if len(job.Next) > 0 {
+		var err error
+		next, err = json.Marshal(Job{
+			Id:         uuid.NewString(),
+			Stage:      job.Next[0],
+			SessionId:  job.SessionId,
+			TaskId:     job.TaskId,
+			UserId:     job.UserId,
+			Next:       job.Next[1:],
+			Gen:        job.Gen,
+			EnqueuedAt: time.Now(),
+		})
+		if err != nil {
+			q.log.Error("failed to encode next job", slog.String("stage", stage), sl.Err(err))
+			return false
+		}
+	}
+
+	_, err := q.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		if next != nil {
+			p.XAdd(ctx, &redis.XAddArgs{
+				Stream: streamKey(job.Next[0]),
+				Values: map[string]any{jobField: next},
+			})
+		}
+		p.XAck(ctx, streamKey(stage), group, msgId)
+		p.XDel(ctx, streamKey(stage), msgId)
+		return nil
+	})
+	if err != nil {
+		q.log.Error("failed to ack job", slog.String("stage", stage), slog.String("id", msgId), sl.Err(err))
+		return false
+	}
+
+	return true
+}
+
+func (q *Queue) retry(ctx context.Context, stage, msgId string, job *Job, delay time.Duration) error {
+	b, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.ZAdd(ctx, delayedKey(stage), redis.Z{
+			Score:  float64(time.Now().Add(delay).UnixMilli()),
+			Member: b,
+		})
+		p.XAck(ctx, streamKey(stage), group, msgId)
+		p.XDel(ctx, streamKey(stage), msgId)
+		return nil
+	})
+
+	return err
+}
+
+func (q *Queue) dead(ctx context.Context, stage, msgId string, job *Job, h Handler) {
+	b, err := json.Marshal(job)
+	if err != nil {
+		q.log.Error("failed to encode dead job", slog.String("stage", stage), sl.Err(err))
+		return
+	}
+
+	_, err = q.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.XAdd(ctx, &redis.XAddArgs{
+			Stream: deadKey(stage),
+			MaxLen: deadMaxLen,
+			Approx: true,
+			Values: map[string]any{jobField: b},
+		})
+		p.XAck(ctx, streamKey(stage), group, msgId)
+		p.XDel(ctx, streamKey(stage), msgId)
+		return nil
+	})
+	if err != nil {
+		q.log.Error("failed to dead-letter job", slog.String("stage", stage), slog.String("job_id", job.Id), sl.Err(err))
+		return
+	}
+
+	h.Dead(ctx, job)
+}
+
+// backoff grows exponentially with the attempt, the jitter spreads retries of jobs failed together
+func (q *Queue) backoff(attempt int) time.Duration {
+	d := q.cfg.BackoffBase << (attempt - 1)
+	if d <= 0 || d > q.cfg.BackoffMax {
+		d = q.cfg.BackoffMax
+	}
+
+	return d/2 + rand.N(d/2+1)
+}
+
+// promoteScript moves due jobs back to the stream, atomic so every instance may run it
+var promoteScript = redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, job in ipairs(due) do
+	redis.call('ZREM', KEYS[1], job)
+	redis.call('XADD', KEYS[2], '*', ARGV[3], job)
+end
+return #due
+`)
+
+func (q *Queue) promote(ctx context.Context, stage string) {
+	ticker := time.NewTicker(promoteInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := promoteScript.Run(ctx, q.client,
+			[]string{delayedKey(stage), streamKey(stage)},
+			time.Now().UnixMilli(), promoteBatch, jobField,
+		).Err()
+		if err != nil && !errors.Is(err, redis.Nil) && ctx.Err() == nil {
+			q.log.Error("failed to promote delayed jobs", slog.String("stage", stage), sl.Err(err))
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package lang_detector
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Cavnar & Trenkle n-gram ranking: every language is described by its most frequent 1-3 grams,
+// the text is assigned to the language whose ranking is the closest one.
+
+const (
+	maxGram        = 3
+	profileSize    = 400
+	minLetters     = 20
+	defaultMinConf = 0.05
+)
+
+//go:embed profiles/*.txt
+var profilesFS embed.FS
+
+type Result struct {
+	Lang       string  `json:"lang"`
+	Confidence float64 `json:"confidence"`
+}
+
+type Detector struct {
+	profiles map[string]map[string]int
+	minConf  float64
+}
+
+// New builds the profiles from the embedded training texts, one file per ISO 639-1 code
+func New() (*Detector, error) {
+	const op = "lang_detector.New"
+
+	entries, err := profilesFS.ReadDir("profiles")
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	profiles := make(map[string]map[string]int, len(entries))
+	for _, e := range entries {
+		raw, err := profilesFS.ReadFile(path.Join("profiles", e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+
+		lang := strings.TrimSuffix(e.Name(), path.Ext(e.Name()))
+		profiles[lang] = rank(string(raw), profileSize)
+	}
+
+	return &Detector{
+		profiles: profiles,
+		minConf:  defaultMinConf,
+	}, nil
+}
+
+func (d *Detector) Languages() []string {
+	langs := make([]string, 0, len(d.profiles))
+	for l := range d.profiles {
+		langs = append(langs, l)
+	}
+	sort.Strings(langs)
+
+	return langs
+}
+
+// Detect returns the closest language, ok is false when the text is too short or ambiguous
+func (d *Detector) Detect(text string) (Result, bool) {
+	letters := 0
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	if letters < minLetters {
+		return Result{}, false
+	}
+
+	doc := rank(text, profileSize)
+
+	type score struct {
+		lang string
+		dist int
+	}
+	scores := make([]score, 0, len(d.profiles))
+	for lang, profile := range d.profiles {
+		scores = append(scores, score{lang: lang, dist: distance(doc, profile)})
+	}
+	sort.Slice(scores, func(i, j int) bool { return scores[i].dist < scores[j].dist })
+
+	if len(scores) == 0 {
+		return Result{}, false
+	}
+
+	res := Result{Lang: scores[0].lang, Confidence: 1}
+	if len(scores) > 1 && scores[1].dist > 0 {
+		res.Confidence = float64(scores[1].dist-scores[0].dist) / float64(scores[1].dist)
+	}
+
+	return res, res.Confidence >= d.minConf
+}
+
+// distance is the out-of-place measure, n-grams missing from the profile get the maximum penalty
+func distance(doc, profile map[string]int) int {
+	dist := 0
+	for g, r := range doc {
+		pr, ok := profile[g]
+		if !ok {
+			dist += profileSize
+			continue
+		}
+		if pr > r {
+			dist += pr - r
+		} else {
+			dist += r - pr
+		}
+	}
+
+	return dist
+}
+
+func rank(text string, size int) map[string]int {
+	counts := make(map[string]int)
+
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		padded := []rune(" " + w + " ")
+		for n := 1; n <= maxGram; n++ {
+			for i := 0; i+n <= len(padded); i++ {
+				g := string(padded[i : i+n])
+				if g == " " {
+					continue
+				}
+				counts[g]++
+			}
+		}
+	}
+
+	grams := make([]string, 0, len(counts))
+	for g := range counts {
+		grams = append(grams, g)
+	}
+	sort.Slice(grams, func(i, j int) bool {
+		if counts[grams[i]] != counts[grams[j]] {
+			return counts[grams[i]] > counts[grams[j]]
+		}
+		return grams[i] < grams[j]
+	})
+
+	if len(grams) > size {
+		grams = grams[:size]
+	}
+
+	ranks := make(map[string]int, len(grams))
+	for i, g := range grams {
+		ranks[g] = i
+	}
+
+	return ranks
+}
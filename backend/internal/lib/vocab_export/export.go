@@ -0,0 +1,164 @@
+// Package vocab_export writes flashcards in plain text formats other apps import.
+//
+// A Formatter gets the cards one by one and writes each of them right away, so an export
+// of any size takes constant memory. Formats are looked up by name in a registry and new
+// ones are added with Register.
+package vocab_export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUnknownFormat  = errors.New("unknown export format")
+	ErrInvalidOptions = errors.New("invalid export options")
+)
+
+// maxSepLen bounds a custom separator
+const maxSepLen = 10
+
+// Card is an exported flashcard with the session it was first found in
+type Card struct {
+	Word        string
+	Translation string
+	Example     string
+	Description string
+	Lang        string
+	Gender      string
+	Tags        []string
+	// SessionId is zero for a card added by hand or imported
+	SessionId   uuid.UUID
+	Session     string
+	SessionDate time.Time
+}
+
+// Options tune the output, formats ignore the ones they don't use
+type Options struct {
+	// Title heads the document in the formats that have one
+	Title string
+	// TermSep and CardSep separate the term from the definition and the cards in the Quizlet format
+	TermSep string
+	CardSep string
+}
+
+// Formatter writes the cards it's given, Close writes whatever ends the document.
+// Cards must come grouped by session for the formats grouping them.
+type Formatter interface {
+	Write(c Card) error
+	Close() error
+}
+
+type Format struct {
+	Name        string
+	ContentType string
+	Ext         string
+	New         func(w io.Writer, opts Options) Formatter
+}
+
+var (
+	mu      sync.RWMutex
+	formats = map[string]Format{}
+)
+
+// Register makes a format available by its name, registering a name again replaces it
+func Register(f Format) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	formats[f.Name] = f
+}
+
+func Lookup(name string) (Format, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	f, ok := formats[strings.ToLower(name)]
+	if !ok {
+		return Format{}, fmt.Errorf("%w %q, use one of %s", ErrUnknownFormat, name, strings.Join(names(), ", "))
+	}
+
+	return f, nil
+}
+
+// Names lists the registered formats in alphabetical order
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return names()
+}
+
+func names() []string {
+	out := make([]string, 0, len(formats))
+	for n := range formats {
+		out = append(out, n)
+	}
+	slices.Sort(out)
+
+	return out
+}
+
+func init() {
+	Register(Format{Name: "csv", ContentType: "text/csv; charset=utf-8", Ext: "csv", New: newCSV(',')})
+	Register(Format{Name: "tsv", ContentType: "text/tab-separated-values; charset=utf-8", Ext: "tsv", New: newCSV('\t')})
+	Register(Format{Name: "quizlet", ContentType: "text/plain; charset=utf-8", Ext: "txt", New: newQuizlet})
+	Register(Format{Name: "json", ContentType: "application/json", Ext: "json", New: newJSON})
+	Register(Format{Name: "markdown", ContentType: "text/markdown; charset=utf-8", Ext: "md", New: newMarkdown})
+}
+
+// separators known by name, as Quizlet offers them
+var namedSeps = map[string]string{
+	"tab":       "\t",
+	"comma":     ",",
+	"newline":   "\n",
+	"semicolon": ";",
+}
+
+// Separator reads a separator given by name or with the \t and \n escapes
+func Separator(s string) string {
+	if sep, ok := namedSeps[strings.ToLower(s)]; ok {
+		return sep
+	}
+
+	return strings.NewReplacer(`\t`, "\t", `\n`, "\n").Replace(s)
+}
+
+// Validate checks the custom separators can be told apart
+func (o Options) Validate() error {
+	o = o.withDefaults()
+
+	for _, sep := range []string{o.TermSep, o.CardSep} {
+		if utf8.RuneCountInString(sep) > maxSepLen {
+			return fmt.Errorf("%w: separators are limited to %d characters", ErrInvalidOptions, maxSepLen)
+		}
+		// the spaces between words would be taken for separators
+		if strings.Trim(sep, " ") == "" {
+			return fmt.Errorf("%w: a separator can't be made of spaces only", ErrInvalidOptions)
+		}
+	}
+	if strings.Contains(o.TermSep, o.CardSep) || strings.Contains(o.CardSep, o.TermSep) {
+		return fmt.Errorf("%w: the term and card separators must differ", ErrInvalidOptions)
+	}
+
+	return nil
+}
+
+func (o Options) withDefaults() Options {
+	if o.TermSep == "" {
+		o.TermSep = "\t"
+	}
+	if o.CardSep == "" {
+		o.CardSep = "\n"
+	}
+
+	return o
+}
@@ -0,0 +1,272 @@
+package vocab_export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// csvHeader names the columns as the vocabulary import expects them, so the file reads back as is
+var csvHeader = []string{"word", "translation", "example", "description", "lang", "gender", "tags", "session"}
+
+type csvFormatter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func newCSV(comma rune) func(w io.Writer, opts Options) Formatter {
+	return func(w io.Writer, _ Options) Formatter {
+		cw := csv.NewWriter(w)
+		cw.Comma = comma
+		return &csvFormatter{w: cw}
+	}
+}
+
+func (f *csvFormatter) Write(c Card) error {
+	if err := f.writeHeader(); err != nil {
+		return err
+	}
+
+	return f.w.Write([]string{c.Word, c.Translation, c.Example, c.Description, c.Lang, c.Gender, joinTags(c.Tags), c.Session})
+}
+
+func (f *csvFormatter) Close() error {
+	if err := f.writeHeader(); err != nil {
+		return err
+	}
+	f.w.Flush()
+
+	return f.w.Error()
+}
+
+func (f *csvFormatter) writeHeader() error {
+	if f.header {
+		return nil
+	}
+	f.header = true
+
+	return f.w.Write(csvHeader)
+}
+
+// joinTags separates the tags by spaces, the spaces inside a tag become underscores as in Anki
+func joinTags(tags []string) string {
+	out := make([]string, len(tags))
+	for i, t := range tags {
+		out[i] = strings.ReplaceAll(t, " ", "_")
+	}
+
+	return strings.Join(out, " ")
+}
+
+// quizletFormatter writes the text pasted into the Quizlet import box: a term, the term separator,
+// the definition and the card separator. Quizlet has no escaping, separators inside a field become spaces.
+type quizletFormatter struct {
+	w    *bufio.Writer
+	opts Options
+	repl *strings.Replacer
+}
+
+func newQuizlet(w io.Writer, opts Options) Formatter {
+	opts = opts.withDefaults()
+
+	// line breaks are kept only when they can't be taken for a separator
+	olds := []string{opts.TermSep, " ", opts.CardSep, " "}
+	if strings.ContainsAny(opts.TermSep+opts.CardSep, "\r\n") {
+		olds = append(olds, "\r\n", " ", "\n", " ", "\r", " ")
+	}
+
+	return &quizletFormatter{w: bufio.NewWriter(w), opts: opts, repl: strings.NewReplacer(olds...)}
+}
+
+func (f *quizletFormatter) Write(c Card) error {
+	_, err := f.w.WriteString(f.repl.Replace(c.Word) + f.opts.TermSep + f.repl.Replace(c.Translation) + f.opts.CardSep)
+
+	return err
+}
+
+func (f *quizletFormatter) Close() error {
+	return f.w.Flush()
+}
+
+type jsonSession struct {
+	Id   uuid.UUID  `json:"id"`
+	Name string     `json:"name"`
+	Date *time.Time `json:"date,omitempty"`
+}
+
+type jsonCard struct {
+	Word        string       `json:"word"`
+	Translation string       `json:"translation"`
+	Example     string       `json:"example,omitempty"`
+	Description string       `json:"description,omitempty"`
+	Lang        string       `json:"lang"`
+	Gender      string       `json:"gender,omitempty"`
+	Tags        []string     `json:"tags"`
+	Session     *jsonSession `json:"session,omitempty"`
+}
+
+// jsonFormatter writes an array with a card per line, the array is never held in memory
+type jsonFormatter struct {
+	w *bufio.Writer
+	n int
+}
+
+func newJSON(w io.Writer, _ Options) Formatter {
+	return &jsonFormatter{w: bufio.NewWriter(w)}
+}
+
+func (f *jsonFormatter) Write(c Card) error {
+	out := jsonCard{
+		Word:        c.Word,
+		Translation: c.Translation,
+		Example:     c.Example,
+		Description: c.Description,
+		Lang:        c.Lang,
+		Gender:      c.Gender,
+		Tags:        c.Tags,
+	}
+	if out.Tags == nil {
+		out.Tags = []string{}
+	}
+	if c.SessionId != uuid.Nil {
+		out.Session = &jsonSession{Id: c.SessionId, Name: c.Session}
+		if !c.SessionDate.IsZero() {
+			out.Session.Date = &c.SessionDate
+		}
+	}
+
+	b, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+
+	sep := ",\n"
+	if f.n == 0 {
+		sep = "[\n"
+	}
+	if _, err := f.w.WriteString(sep); err != nil {
+		return err
+	}
+	f.n++
+	_, err = f.w.Write(b)
+
+	return err
+}
+
+func (f *jsonFormatter) Close() error {
+	end := "\n]\n"
+	if f.n == 0 {
+		end = "[" + end
+	}
+	if _, err := f.w.WriteString(end); err != nil {
+		return err
+	}
+
+	return f.w.Flush()
+}
+
+// markdownFormatter writes a glossary with a section per session, the cards found in no session come last
+type markdownFormatter struct {
+	w       *bufio.Writer
+	title   string
+	started bool
+	session uuid.UUID
+	n       int
+}
+
+func newMarkdown(w io.Writer, opts Options) Formatter {
+	title := opts.Title
+	if title == "" {
+		title = "Vocabulary"
+	}
+
+	return &markdownFormatter{w: bufio.NewWriter(w), title: title}
+}
+
+func (f *markdownFormatter) Write(c Card) error {
+	if err := f.start(); err != nil {
+		return err
+	}
+
+	// the entry is built first so a failed write is reported once
+	var sb strings.Builder
+	if f.n == 0 || c.SessionId != f.session {
+		f.session = c.SessionId
+		sb.WriteString("\n## " + sectionTitle(c) + "\n\n")
+	}
+	f.n++
+
+	sb.WriteString("- **" + mdEscape(c.Word) + "**")
+	if c.Gender != "" {
+		sb.WriteString(" *(" + mdEscape(c.Gender) + ")*")
+	}
+	sb.WriteString(" — " + mdEscape(c.Translation) + "\n")
+	if c.Example != "" {
+		sb.WriteString("  - *" + mdEscape(c.Example) + "*\n")
+	}
+	if c.Description != "" {
+		sb.WriteString("  - " + mdEscape(c.Description) + "\n")
+	}
+	if len(c.Tags) > 0 {
+		tags := make([]string, len(c.Tags))
+		for i, t := range c.Tags {
+			tags[i] = "`" + strings.ReplaceAll(t, "`", "'") + "`"
+		}
+		sb.WriteString("  - " + strings.Join(tags, " ") + "\n")
+	}
+
+	_, err := f.w.WriteString(sb.String())
+
+	return err
+}
+
+func (f *markdownFormatter) Close() error {
+	if err := f.start(); err != nil {
+		return err
+	}
+
+	return f.w.Flush()
+}
+
+func (f *markdownFormatter) start() error {
+	if f.started {
+		return nil
+	}
+	f.started = true
+
+	_, err := f.w.WriteString("# " + mdEscape(f.title) + "\n")
+
+	return err
+}
+
+func sectionTitle(c Card) string {
+	if c.SessionId == uuid.Nil {
+		return "Other words"
+	}
+
+	title := mdEscape(c.Session)
+	if title == "" {
+		title = "Untitled session"
+	}
+	if !c.SessionDate.IsZero() {
+		title += " (" + c.SessionDate.Format(time.DateOnly) + ")"
+	}
+
+	return title
+}
+
+var mdEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`,
+	`<`, `\<`, `>`, `\>`, `#`, `\#`, `|`, `\|`,
+	"\r\n", " ", "\n", " ", "\r", " ",
+)
+
+// mdEscape keeps a field on one line and its markup characters literal
+func mdEscape(s string) string {
+	return mdEscaper.Replace(s)
+}
@@ -0,0 +1,94 @@
+package vocab_export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCSVEscaping(t *testing.T) {
+	tests := []struct {
+		name string
+		card Card
+		want []string
+	}{
+		{
+			name: "plain card",
+			card: Card{Word: "Haus", Translation: "house", Lang: "de"},
+			want: []string{"Haus", "house", "", "", "de", "", "", ""},
+		},
+		{
+			name: "separators inside fields",
+			card: Card{Word: "Haus, Hof", Translation: "house\tand yard", Example: "a; b", Lang: "de"},
+			want: []string{"Haus, Hof", "house\tand yard", "a; b", "", "de", "", "", ""},
+		},
+		{
+			name: "quotes",
+			card: Card{Word: `"Haus"`, Translation: `the "house"`, Lang: "de"},
+			want: []string{`"Haus"`, `the "house"`, "", "", "de", "", "", ""},
+		},
+		{
+			name: "line breaks",
+			card: Card{Word: "laufen", Translation: "to run", Example: "Ich laufe.\nDu läufst.", Lang: "de"},
+			want: []string{"laufen", "to run", "Ich laufe.\nDu läufst.", "", "de", "", "", ""},
+		},
+		{
+			name: "tags with spaces",
+			card: Card{Word: "Küche", Translation: "kitchen", Lang: "de", Gender: "die", Tags: []string{"pos:noun", "at home"}, Session: "Lesson 1"},
+			want: []string{"Küche", "kitchen", "", "", "de", "die", "pos:noun at_home", "Lesson 1"},
+		},
+	}
+
+	for _, format := range []struct {
+		name  string
+		comma rune
+	}{{"csv", ','}, {"tsv", '\t'}} {
+		for _, tt := range tests {
+			t.Run(format.name+"/"+tt.name, func(t *testing.T) {
+				f, err := Lookup(format.name)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var buf bytes.Buffer
+				w := f.New(&buf, Options{})
+				if err := w.Write(tt.card); err != nil {
+					t.Fatalf("write: %v", err)
+				}
+				if err := w.Close(); err != nil {
+					t.Fatalf("close: %v", err)
+				}
+
+				r := csv.NewReader(&buf)
+				r.Comma = format.comma
+				records, err := r.ReadAll()
+				if err != nil {
+					t.Fatalf("read back: %v", err)
+				}
+				if len(records) != 2 {
+					t.Fatalf("got %d records, want the header and a card", len(records))
+				}
+				if !reflect.DeepEqual(records[0], csvHeader) {
+					t.Errorf("header %q, want %q", records[0], csvHeader)
+				}
+				if !reflect.DeepEqual(records[1], tt.want) {
+					t.Errorf("card %q, want %q", records[1], tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestCSVEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	w := newCSV(',')(&buf, Options{})
+	if err := w.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	if got, want := buf.String(), strings.Join(csvHeader, ",")+"\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -5,11 +5,16 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rwrrioe/pythia/backend/internal/auth/authn"
 	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
+	"github.com/rwrrioe/pythia/backend/internal/domain/requests"
+	"github.com/rwrrioe/pythia/backend/internal/lib/card_query"
 	"github.com/rwrrioe/pythia/backend/internal/storage/postgresql"
 )
 
@@ -17,6 +22,22 @@ type FlashCardProvider interface {
 	List(ctx context.Context, q postgresql.Querier, uid int64) ([]entities.FlashCard, error)
 	ListByDeck(ctx context.Context, q postgresql.Querier, deckId uuid.UUID, uid int64) ([]entities.FlashCard, error)
 	GetOrCreate(ctx context.Context, q postgresql.Querier, flCard entities.FlashCard, uid int64) (uuid.UUID, error)
+	Get(ctx context.Context, q postgresql.Querier, id uuid.UUID, uid int64) (*entities.FlashCard, error)
+	Create(ctx context.Context, q postgresql.Querier, flCard entities.FlashCard, uid int64) (uuid.UUID, error)
+	Update(ctx context.Context, q postgresql.Querier, flCard entities.FlashCard, uid int64) error
+	Delete(ctx context.Context, q postgresql.Querier, id uuid.UUID, uid int64) error
+	AddTags(ctx context.Context, q postgresql.Querier, flashcardId uuid.UUID, tags []string, auto bool, uid int64) (int64, error)
+	TagFlashcards(ctx context.Context, q postgresql.Querier, flashcardIds []uuid.UUID, tag string, auto bool, uid int64) (int64, error)
+	RemoveTag(ctx context.Context, q postgresql.Querier, flashcardId uuid.UUID, tag string, uid int64) error
+	ListTags(ctx context.Context, q postgresql.Querier, uid int64) ([]entities.Tag, error)
+	RecordReviews(ctx context.Context, q postgresql.Querier, reviews []entities.Review, uid int64) (int64, error)
+	SearchVocabulary(ctx context.Context, q postgresql.Querier, uid int64, term string, lang, limit int) ([]entities.VocabularyHit, error)
+	Memberships(ctx context.Context, q postgresql.Querier, hits []entities.VocabularyHit, uid int64) error
+	QueryCards(ctx context.Context, q postgresql.Querier, uid int64, query card_query.Node, order string, limit int, now time.Time) ([]entities.FlashCard, error)
+	ListReviews(ctx context.Context, q postgresql.Querier, flashcardIds []uuid.UUID, uid int64) (map[uuid.UUID][]entities.Review, error)
+	Sources(ctx context.Context, q postgresql.Querier, flashcardIds []uuid.UUID, uid int64) (map[uuid.UUID]string, error)
+	FindWords(ctx context.Context, q postgresql.Querier, words []string, langs []int, uid int64) ([]entities.FlashCard, error)
+	StreamCards(ctx context.Context, q postgresql.Querier, uid int64, query card_query.Node, now time.Time, fn func(fl entities.FlashCard, src entities.CardSource) error) error
 	FlashcardsPool() *pgxpool.Pool
 }
 
@@ -24,6 +45,17 @@ type DeckProvider interface {
 	ListBySession(ctx context.Context, q postgresql.Querier, sessionId uuid.UUID, uid int64) (*entities.Deck, error)
 	AttachFlashcard(ctx context.Context, q postgresql.Querier, deckId uuid.UUID, flashcardId uuid.UUID) error
 	GetOrCreate(ctx context.Context, q postgresql.Querier, sessionId uuid.UUID, uid int64) (uuid.UUID, error)
+	DeckExists(ctx context.Context, q postgresql.Querier, deckId uuid.UUID, uid int64) (bool, error)
+	CopyFlashcards(ctx context.Context, q postgresql.Querier, deckId uuid.UUID, flashcardIds []uuid.UUID, uid int64) (int64, error)
+	DetachFlashcards(ctx context.Context, q postgresql.Querier, deckId uuid.UUID, flashcardIds []uuid.UUID) (int64, error)
+	InDeck(ctx context.Context, q postgresql.Querier, deckId uuid.UUID, flashcardIds []uuid.UUID) ([]uuid.UUID, error)
+	ListDecks(ctx context.Context, q postgresql.Querier, uid int64, kind string) ([]entities.Deck, error)
+	GetDeck(ctx context.Context, q postgresql.Querier, deckId uuid.UUID, uid int64) (*entities.Deck, error)
+	CreateDeck(ctx context.Context, q postgresql.Querier, deck entities.Deck, uid int64) (uuid.UUID, error)
+	UpdateDeck(ctx context.Context, q postgresql.Querier, deck entities.Deck, uid int64) error
+	DeleteDeck(ctx context.Context, q postgresql.Querier, deckId uuid.UUID, uid int64) error
+	ReorderDecks(ctx context.Context, q postgresql.Querier, deckIds []uuid.UUID, uid int64) (int64, error)
+	ReorderFlashcards(ctx context.Context, q postgresql.Querier, deckId uuid.UUID, flashcardIds []uuid.UUID) (int64, error)
 	DeckPool() *pgxpool.Pool
 }
 
@@ -32,17 +64,20 @@ type FlashCardsService struct {
 	decks      DeckProvider
 
 	pool postgresql.Querier
+	txm  *postgresql.TxManager
 }
 
 func NewCardsService(
 	flashcards FlashCardProvider,
 	decks DeckProvider,
 	pool postgresql.Querier,
+	txm *postgresql.TxManager,
 ) *FlashCardsService {
 	return &FlashCardsService{
 		flashcards: flashcards,
 		decks:      decks,
 		pool:       pool,
+		txm:        txm,
 	}
 }
 
@@ -84,3 +119,290 @@ func (s *FlashCardsService) GetBySession(ctx context.Context, sessionId uuid.UUI
 
 	return flCards, nil
 }
+
+const (
+	maxCardTextLen = 100
+	maxCardDescLen = 1000
+	maxCardBatch   = 500
+)
+
+func (s *FlashCardsService) List(ctx context.Context, langId int) ([]entities.FlashCard, error) {
+	const op = "service.FlashcardService.List"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+
+	cards, err := s.flashcards.List(ctx, s.pool, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	if langId == 0 {
+		return cards, nil
+	}
+
+	out := cards[:0]
+	for _, fl := range cards {
+		if fl.Lang == langId {
+			out = append(out, fl)
+		}
+	}
+
+	return out, nil
+}
+
+func (s *FlashCardsService) Get(ctx context.Context, id uuid.UUID) (*entities.FlashCard, error) {
+	const op = "service.FlashcardService.Get"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+
+	fl, err := s.flashcards.Get(ctx, s.pool, id, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, cardErr(err))
+	}
+
+	return fl, nil
+}
+
+// Create adds a manual flashcard, optionally straight into a deck
+func (s *FlashCardsService) Create(ctx context.Context, req requests.CreateFlashcard) (*entities.FlashCard, error) {
+	const op = "service.FlashcardService.Create"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+
+	fl := postgresql.NormalizeCard(entities.FlashCard{
+		Word:    req.Word,
+		Transl:  req.Translation,
+		Desc:    req.Description,
+		Example: req.Example,
+		Lang:    req.LangId,
+	})
+	if err := validateCard(fl); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	err := s.txm.WithTx(ctx, func(tx pgx.Tx) error {
+		id, err := s.flashcards.Create(ctx, tx, fl, uid)
+		if err != nil {
+			return err
+		}
+		fl.Id = id
+
+		if req.DeckId == nil {
+			return nil
+		}
+		if err := s.ownDeck(ctx, tx, *req.DeckId, uid); err != nil {
+			return err
+		}
+
+		// the card goes last and only into a deck of its language, as when it's copied
+		n, err := s.decks.CopyFlashcards(ctx, tx, *req.DeckId, []uuid.UUID{id}, uid)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return fmt.Errorf("%w: the deck holds cards in another language", ErrInvalidFlashcard)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, cardErr(err))
+	}
+
+	return &fl, nil
+}
+
+// Update edits the flashcard, the result must stay unique per user and language
+func (s *FlashCardsService) Update(ctx context.Context, id uuid.UUID, req requests.UpdateFlashcard) (*entities.FlashCard, error) {
+	const op = "service.FlashcardService.Update"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+
+	fl, err := s.flashcards.Get(ctx, s.pool, id, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, cardErr(err))
+	}
+
+	if req.Word != nil {
+		fl.Word = *req.Word
+	}
+	if req.Translation != nil {
+		fl.Transl = *req.Translation
+	}
+	if req.Description != nil {
+		fl.Desc = *req.Description
+	}
+	if req.Example != nil {
+		fl.Example = *req.Example
+	}
+
+	*fl = postgresql.NormalizeCard(*fl)
+	if err := validateCard(*fl); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	if err := s.flashcards.Update(ctx, s.pool, *fl, uid); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, cardErr(err))
+	}
+
+	return fl, nil
+}
+
+// Delete removes the flashcard from the user's vocabulary and from every deck
+func (s *FlashCardsService) Delete(ctx context.Context, id uuid.UUID) error {
+	const op = "service.FlashcardService.Delete"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+
+	if err := s.flashcards.Delete(ctx, s.pool, id, uid); err != nil {
+		return fmt.Errorf("%s:%w", op, cardErr(err))
+	}
+
+	return nil
+}
+
+// Copy adds the flashcards to the deck, it returns how many were not in it yet
+func (s *FlashCardsService) Copy(ctx context.Context, req requests.CopyFlashcards) (int64, error) {
+	const op = "service.FlashcardService.Copy"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return 0, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+	if len(req.Ids) > maxCardBatch {
+		return 0, fmt.Errorf("%s:%w: at most %d flashcards at once", op, ErrInvalidFlashcard, maxCardBatch)
+	}
+
+	if err := s.ownDeck(ctx, s.pool, req.ToDeckId, uid); err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+
+	n, err := s.decks.CopyFlashcards(ctx, s.pool, req.ToDeckId, req.Ids, uid)
+	if err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return n, nil
+}
+
+// Move takes the flashcards out of one deck and puts them into another, cards missing from the
+// source deck or in another language than the target deck are left where they are. It returns how many were moved.
+func (s *FlashCardsService) Move(ctx context.Context, req requests.MoveFlashcards) (int, error) {
+	const op = "service.FlashcardService.Move"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return 0, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+	if len(req.Ids) > maxCardBatch {
+		return 0, fmt.Errorf("%s:%w: at most %d flashcards at once", op, ErrInvalidFlashcard, maxCardBatch)
+	}
+	if req.FromDeckId == req.ToDeckId {
+		return 0, fmt.Errorf("%s:%w: source and target deck are the same", op, ErrInvalidFlashcard)
+	}
+
+	var moved int
+	err := s.txm.WithTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		moved, err = s.move(ctx, tx, req, uid)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return moved, nil
+}
+
+func (s *FlashCardsService) move(ctx context.Context, q postgresql.Querier, req requests.MoveFlashcards, uid int64) (int, error) {
+	for _, deckId := range []uuid.UUID{req.FromDeckId, req.ToDeckId} {
+		if err := s.ownDeck(ctx, q, deckId, uid); err != nil {
+			return 0, err
+		}
+	}
+
+	ids, err := s.decks.InDeck(ctx, q, req.FromDeckId, req.Ids)
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	if _, err := s.decks.CopyFlashcards(ctx, q, req.ToDeckId, ids, uid); err != nil {
+		return 0, err
+	}
+
+	// the copy skips the cards the target deck doesn't take, only the ones that made it leave the source
+	moved, err := s.decks.InDeck(ctx, q, req.ToDeckId, ids)
+	if err != nil {
+		return 0, err
+	}
+	if len(moved) == 0 {
+		return 0, nil
+	}
+	if _, err := s.decks.DetachFlashcards(ctx, q, req.FromDeckId, moved); err != nil {
+		return 0, err
+	}
+
+	return len(moved), nil
+}
+
+func (s *FlashCardsService) ownDeck(ctx context.Context, q postgresql.Querier, deckId uuid.UUID, uid int64) error {
+	ok, err := s.decks.DeckExists(ctx, q, deckId, uid)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrDeckNotFound
+	}
+
+	return nil
+}
+
+// validateCard checks a normalized flashcard against the column limits
+func validateCard(fl entities.FlashCard) error {
+	switch {
+	case fl.Word == "":
+		return fmt.Errorf("%w: word is empty", ErrInvalidFlashcard)
+	case fl.Transl == "":
+		return fmt.Errorf("%w: translation is empty", ErrInvalidFlashcard)
+	case utf8.RuneCountInString(fl.Word) > maxCardTextLen, utf8.RuneCountInString(fl.Transl) > maxCardTextLen:
+		return fmt.Errorf("%w: word and translation are limited to %d characters", ErrInvalidFlashcard, maxCardTextLen)
+	case utf8.RuneCountInString(fl.Desc) > maxCardDescLen:
+		return fmt.Errorf("%w: description is limited to %d characters", ErrInvalidFlashcard, maxCardDescLen)
+	case utf8.RuneCountInString(fl.Example) > maxCardDescLen:
+		return fmt.Errorf("%w: example is limited to %d characters", ErrInvalidFlashcard, maxCardDescLen)
+	}
+
+	if _, ok := LangsMap[fl.Lang]; !ok {
+		return fmt.Errorf("%w: unknown language", ErrInvalidFlashcard)
+	}
+
+	return nil
+}
+
+func cardErr(err error) error {
+	switch {
+	case errors.Is(err, postgresql.ErrFlashcardNotFound):
+		return ErrFlashcardNotFound
+	case errors.Is(err, postgresql.ErrFlashcardAlreadyExists):
+		return ErrFlashcardExists
+	}
+
+	return err
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rwrrioe/pythia/backend/internal/domain/requests"
+	"github.com/rwrrioe/pythia/backend/internal/storage/postgresql"
+)
+
+// memDecks keeps decks in memory, the methods Move doesn't use are left to the nil interface
+type memDecks struct {
+	DeckProvider
+
+	// lang 0 takes cards of any language
+	langs     map[uuid.UUID]int
+	cards     map[uuid.UUID][]uuid.UUID
+	cardLangs map[uuid.UUID]int
+}
+
+func (d *memDecks) DeckExists(ctx context.Context, q postgresql.Querier, deckId uuid.UUID, uid int64) (bool, error) {
+	_, ok := d.langs[deckId]
+	return ok, nil
+}
+
+func (d *memDecks) InDeck(ctx context.Context, q postgresql.Querier, deckId uuid.UUID, flashcardIds []uuid.UUID) ([]uuid.UUID, error) {
+	var out []uuid.UUID
+	for _, id := range flashcardIds {
+		if slices.Contains(d.cards[deckId], id) {
+			out = append(out, id)
+		}
+	}
+	return out, nil
+}
+
+func (d *memDecks) CopyFlashcards(ctx context.Context, q postgresql.Querier, deckId uuid.UUID, flashcardIds []uuid.UUID, uid int64) (int64, error) {
+	var n int64
+	for _, id := range flashcardIds {
+		if lang := d.langs[deckId]; lang != 0 && lang != d.cardLangs[id] {
+			continue
+		}
+		if !slices.Contains(d.cards[deckId], id) {
+			d.cards[deckId] = append(d.cards[deckId], id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (d *memDecks) DetachFlashcards(ctx context.Context, q postgresql.Querier, deckId uuid.UUID, flashcardIds []uuid.UUID) (int64, error) {
+	before := len(d.cards[deckId])
+	d.cards[deckId] = slices.DeleteFunc(d.cards[deckId], func(id uuid.UUID) bool {
+		return slices.Contains(flashcardIds, id)
+	})
+	return int64(before - len(d.cards[deckId])), nil
+}
+
+func TestMove(t *testing.T) {
+	const (
+		de = 1
+		fr = 2
+	)
+	var (
+		mixed, german, french = uuid.New(), uuid.New(), uuid.New()
+		haus, tisch, maison   = uuid.New(), uuid.New(), uuid.New()
+		stranger              = uuid.New()
+	)
+
+	tests := []struct {
+		name      string
+		to        uuid.UUID
+		ids       []uuid.UUID
+		want      int
+		wantFrom  []uuid.UUID
+		wantTo    []uuid.UUID
+		wantError error
+	}{
+		{
+			name:     "same language",
+			to:       german,
+			ids:      []uuid.UUID{haus, tisch},
+			want:     2,
+			wantFrom: []uuid.UUID{maison},
+			wantTo:   []uuid.UUID{haus, tisch},
+		},
+		{
+			name:     "other language stays in the source",
+			to:       french,
+			ids:      []uuid.UUID{haus, maison},
+			want:     1,
+			wantFrom: []uuid.UUID{haus, tisch},
+			wantTo:   []uuid.UUID{maison},
+		},
+		{
+			name:     "only other languages",
+			to:       french,
+			ids:      []uuid.UUID{haus, tisch},
+			want:     0,
+			wantFrom: []uuid.UUID{haus, tisch, maison},
+			wantTo:   nil,
+		},
+		{
+			name:     "cards missing from the source",
+			to:       german,
+			ids:      []uuid.UUID{stranger},
+			want:     0,
+			wantFrom: []uuid.UUID{haus, tisch, maison},
+			wantTo:   nil,
+		},
+		{
+			name:      "unknown deck",
+			to:        uuid.New(),
+			ids:       []uuid.UUID{haus},
+			wantFrom:  []uuid.UUID{haus, tisch, maison},
+			wantError: ErrDeckNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decks := &memDecks{
+				langs: map[uuid.UUID]int{mixed: 0, german: de, french: fr},
+				cards: map[uuid.UUID][]uuid.UUID{
+					mixed: {haus, tisch, maison},
+				},
+				cardLangs: map[uuid.UUID]int{haus: de, tisch: de, maison: fr},
+			}
+			s := &FlashCardsService{decks: decks}
+
+			n, err := s.move(context.Background(), nil, requests.MoveFlashcards{
+				FromDeckId: mixed,
+				ToDeckId:   tt.to,
+				Ids:        tt.ids,
+			}, 1)
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("got error %v, want %v", err, tt.wantError)
+			}
+
+			if n != tt.want {
+				t.Errorf("moved %d, want %d", n, tt.want)
+			}
+			if got := decks.cards[mixed]; !slices.Equal(got, tt.wantFrom) {
+				t.Errorf("source deck %v, want %v", got, tt.wantFrom)
+			}
+			if got := decks.cards[tt.to]; !slices.Equal(got, tt.wantTo) {
+				t.Errorf("target deck %v, want %v", got, tt.wantTo)
+			}
+		})
+	}
+}
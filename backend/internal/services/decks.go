@@ -0,0 +1,331 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/rwrrioe/pythia/backend/internal/auth/authn"
+	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
+	"github.com/rwrrioe/pythia/backend/internal/domain/requests"
+	"github.com/rwrrioe/pythia/backend/internal/storage/postgresql"
+)
+
+const (
+	maxDeckNameLen = 100
+	maxDeckDescLen = 1000
+)
+
+// DecksService curates the decks of a user. Session decks are filled when a session ends and
+// only their cards may be changed, user decks are built by hand from any cards.
+type DecksService struct {
+	decks      DeckProvider
+	flashcards FlashCardProvider
+
+	pool postgresql.Querier
+	txm  *postgresql.TxManager
+}
+
+func NewDecksService(
+	decks DeckProvider,
+	flashcards FlashCardProvider,
+	pool postgresql.Querier,
+	txm *postgresql.TxManager,
+) *DecksService {
+	return &DecksService{
+		decks:      decks,
+		flashcards: flashcards,
+		pool:       pool,
+		txm:        txm,
+	}
+}
+
+func (s *DecksService) List(ctx context.Context, kind string) ([]entities.Deck, error) {
+	const op = "service.DecksService.List"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+	if kind != "" && kind != entities.DeckUser && kind != entities.DeckSession {
+		return nil, fmt.Errorf("%s:%w: unknown kind %q", op, ErrInvalidDeck, kind)
+	}
+
+	decks, err := s.decks.ListDecks(ctx, s.pool, uid, kind)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return decks, nil
+}
+
+// Get returns the deck with its cards in order
+func (s *DecksService) Get(ctx context.Context, deckId uuid.UUID) (*entities.Deck, error) {
+	const op = "service.DecksService.Get"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+
+	deck, err := s.getDeck(ctx, deckId, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	deck.Flashcards, err = s.flashcards.ListByDeck(ctx, s.pool, deckId, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return deck, nil
+}
+
+func (s *DecksService) Create(ctx context.Context, req requests.CreateDeck) (*entities.Deck, error) {
+	const op = "service.DecksService.Create"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+
+	deck := entities.Deck{
+		Kind:        entities.DeckUser,
+		Name:        strings.TrimSpace(req.Name),
+		Description: strings.TrimSpace(req.Description),
+		Language:    req.LangId,
+	}
+	if err := validateDeck(deck); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	id, err := s.decks.CreateDeck(ctx, s.pool, deck, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	created, err := s.getDeck(ctx, id, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return created, nil
+}
+
+func (s *DecksService) Update(ctx context.Context, deckId uuid.UUID, req requests.UpdateDeck) (*entities.Deck, error) {
+	const op = "service.DecksService.Update"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+
+	deck, err := s.userDeck(ctx, deckId, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	if req.Name != nil {
+		deck.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Description != nil {
+		deck.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.LangId != nil {
+		deck.Language = *req.LangId
+	}
+	if err := validateDeck(*deck); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	if err := s.decks.UpdateDeck(ctx, s.pool, *deck, uid); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, deckErr(err))
+	}
+
+	return deck, nil
+}
+
+// Delete removes a user deck, its cards stay in the vocabulary and in the other decks
+func (s *DecksService) Delete(ctx context.Context, deckId uuid.UUID) error {
+	const op = "service.DecksService.Delete"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+
+	if _, err := s.userDeck(ctx, deckId, uid); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	if err := s.decks.DeleteDeck(ctx, s.pool, deckId, uid); err != nil {
+		return fmt.Errorf("%s:%w", op, deckErr(err))
+	}
+
+	return nil
+}
+
+// Reorder places the user decks in the given order, decks left out keep their position
+func (s *DecksService) Reorder(ctx context.Context, deckIds []uuid.UUID) error {
+	const op = "service.DecksService.Reorder"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+	if err := checkIds(deckIds); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	n, err := s.decks.ReorderDecks(ctx, s.pool, deckIds, uid)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if int(n) != len(deckIds) {
+		return fmt.Errorf("%s:%w: some decks are not user decks", op, ErrDeckNotFound)
+	}
+
+	return nil
+}
+
+// AddCards appends the cards to the deck, it returns how many were added
+func (s *DecksService) AddCards(ctx context.Context, deckId uuid.UUID, cardIds []uuid.UUID) (int64, error) {
+	const op = "service.DecksService.AddCards"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return 0, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+	if err := checkIds(cardIds); err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+
+	if _, err := s.getDeck(ctx, deckId, uid); err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+
+	n, err := s.decks.CopyFlashcards(ctx, s.pool, deckId, cardIds, uid)
+	if err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return n, nil
+}
+
+// RemoveCards takes the cards out of the deck, it returns how many were removed
+func (s *DecksService) RemoveCards(ctx context.Context, deckId uuid.UUID, cardIds []uuid.UUID) (int64, error) {
+	const op = "service.DecksService.RemoveCards"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return 0, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+	if err := checkIds(cardIds); err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+
+	if _, err := s.getDeck(ctx, deckId, uid); err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+
+	n, err := s.decks.DetachFlashcards(ctx, s.pool, deckId, cardIds)
+	if err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return n, nil
+}
+
+// ReorderCards places the cards of the deck in the given order, cards left out keep their position
+func (s *DecksService) ReorderCards(ctx context.Context, deckId uuid.UUID, cardIds []uuid.UUID) error {
+	const op = "service.DecksService.ReorderCards"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+	if err := checkIds(cardIds); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	if _, err := s.getDeck(ctx, deckId, uid); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	n, err := s.decks.ReorderFlashcards(ctx, s.pool, deckId, cardIds)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if int(n) != len(cardIds) {
+		return fmt.Errorf("%s:%w: some cards are not in the deck", op, ErrFlashcardNotFound)
+	}
+
+	return nil
+}
+
+func (s *DecksService) getDeck(ctx context.Context, deckId uuid.UUID, uid int64) (*entities.Deck, error) {
+	deck, err := s.decks.GetDeck(ctx, s.pool, deckId, uid)
+	if err != nil {
+		return nil, deckErr(err)
+	}
+
+	return deck, nil
+}
+
+// userDeck returns the deck if it may be edited
+func (s *DecksService) userDeck(ctx context.Context, deckId uuid.UUID, uid int64) (*entities.Deck, error) {
+	deck, err := s.getDeck(ctx, deckId, uid)
+	if err != nil {
+		return nil, err
+	}
+	if deck.Kind != entities.DeckUser {
+		return nil, ErrSessionDeck
+	}
+
+	return deck, nil
+}
+
+func validateDeck(d entities.Deck) error {
+	switch {
+	case d.Name == "":
+		return fmt.Errorf("%w: name is empty", ErrInvalidDeck)
+	case utf8.RuneCountInString(d.Name) > maxDeckNameLen:
+		return fmt.Errorf("%w: name is limited to %d characters", ErrInvalidDeck, maxDeckNameLen)
+	case utf8.RuneCountInString(d.Description) > maxDeckDescLen:
+		return fmt.Errorf("%w: description is limited to %d characters", ErrInvalidDeck, maxDeckDescLen)
+	}
+
+	if _, ok := LangsMap[d.Language]; d.Language != 0 && !ok {
+		return fmt.Errorf("%w: unknown language", ErrInvalidDeck)
+	}
+
+	return nil
+}
+
+// checkIds rejects duplicates and oversized batches
+func checkIds(ids []uuid.UUID) error {
+	if len(ids) > maxCardBatch {
+		return fmt.Errorf("%w: at most %d ids at once", ErrInvalidDeck, maxCardBatch)
+	}
+
+	seen := make(map[uuid.UUID]struct{}, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			return fmt.Errorf("%w: %s is listed twice", ErrInvalidDeck, id)
+		}
+		seen[id] = struct{}{}
+	}
+
+	return nil
+}
+
+func deckErr(err error) error {
+	if errors.Is(err, postgresql.ErrDeckNotFound) {
+		return ErrDeckNotFound
+	}
+
+	return err
+}
@@ -1,13 +1,53 @@
 package service
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/rwrrioe/pythia/backend/internal/lib/card_query"
+)
 
 var (
 	ErrDeckNotFound           = errors.New("deck not found")
 	ErrNoWords                = errors.New("no words")
 	ErrSessionNotFound        = errors.New("session not found")
 	ErrSessionAlreadyFinished = errors.New("session already finished")
+	ErrSessionNotFinished     = errors.New("session is not finished")
 	ErrTaskNotFound           = errors.New("task not found")
+	ErrTaskExists             = errors.New("task id is taken by another session")
 	ErrUnauthorized           = errors.New("user is unauthorized")
 	ErrForbidden              = errors.New("access forbidden")
+	ErrImageTooLarge          = errors.New("image is too large")
+	ErrUnsupportedImage       = errors.New("unsupported image")
+	ErrInvalidDuration        = errors.New("invalid session duration")
+	ErrInvalidLevel           = errors.New("invalid level")
+	ErrDocumentNotFound       = errors.New("document not found")
+	ErrInvalidPipeline        = errors.New("invalid pipeline")
+	ErrInvalidStage           = errors.New("stage can't be started on its own")
+	ErrTaskNotRunning         = errors.New("task is not running")
+	ErrTaskNotFailed          = errors.New("task has not failed")
+	ErrUploadExpired          = errors.New("upload has expired")
+	ErrInvalidName            = errors.New("invalid session name")
+	ErrSessionActive          = errors.New("session is still active")
+	ErrNoText                 = errors.New("session has no text")
+	ErrInvalidFilter          = errors.New("invalid filter")
+	ErrInvalidCursor          = errors.New("invalid cursor")
+	ErrFlashcardNotFound      = errors.New("flashcard not found")
+	ErrFlashcardExists        = errors.New("flashcard already exists")
+	ErrInvalidFlashcard       = errors.New("invalid flashcard")
+	ErrInvalidDeck            = errors.New("invalid deck")
+	ErrSessionDeck            = errors.New("session decks follow their session")
+	ErrInvalidTag             = errors.New("invalid tag")
+	ErrTagNotFound            = errors.New("tag not found")
+	ErrInvalidQuery           = card_query.ErrInvalidQuery
+	ErrInvalidSmartFilter     = errors.New("invalid smart filter")
+	ErrSmartFilterNotFound    = errors.New("smart filter not found")
+	ErrSmartFilterExists      = errors.New("smart filter already exists")
+	ErrNotEnoughCards         = errors.New("not enough cards")
+	ErrInvalidSearch          = errors.New("invalid search")
+	ErrExportTooLarge         = errors.New("too many cards to export")
+	ErrInvalidExport          = errors.New("invalid export")
+	ErrInvalidImport          = errors.New("invalid import")
+	ErrImportNotFound         = errors.New("import not found")
+	ErrImportNotPreviewed     = errors.New("only a finished dry run can be confirmed")
+	ErrImportConfirmed        = errors.New("import already confirmed")
 )
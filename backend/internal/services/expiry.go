@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rwrrioe/pythia/backend/internal/lib/logger/sl"
+)
+
+type SessionEventPublisher interface {
+	PublishSessionEvent(ctx context.Context, sessionId uuid.UUID, payload any) error
+}
+
+// ExpiryScheduler finishes sessions whose duration has elapsed.
+// Every instance may run it, expired sessions are leased in postgres so each one is finalized once.
+type ExpiryScheduler struct {
+	log      *slog.Logger
+	session  *SessionService
+	events   SessionEventPublisher
+	interval time.Duration
+	lease    time.Duration
+	batch    int
+}
+
+func NewExpiryScheduler(
+	log *slog.Logger,
+	session *SessionService,
+	events SessionEventPublisher,
+	interval, lease time.Duration,
+	batch int,
+) *ExpiryScheduler {
+	return &ExpiryScheduler{
+		log:      log,
+		session:  session,
+		events:   events,
+		interval: interval,
+		lease:    lease,
+		batch:    batch,
+	}
+}
+
+// Run checks for expired sessions every interval until ctx is done
+func (e *ExpiryScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		e.expire(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *ExpiryScheduler) expire(ctx context.Context) {
+	const op = "service.ExpiryScheduler.expire"
+
+	log := e.log.With(slog.String("op", op))
+
+	claimed, err := e.session.SessionProvider.ClaimExpired(ctx, e.session.txm.Pool, time.Now(), e.lease, e.batch)
+	if err != nil {
+		log.Error("failed to claim expired sessions", sl.Err(err))
+		return
+	}
+
+	for _, ss := range claimed {
+		// the lease must outlive the finalization, otherwise another instance may pick the session up
+		finishCtx, cancel := context.WithTimeout(ctx, e.lease)
+		finished, err := e.session.ExpireSession(finishCtx, ss.Id, ss.UserId)
+		cancel()
+
+		if err != nil {
+			log.Error("failed to finish expired session", slog.String("session_id", ss.Id.String()), sl.Err(err))
+			continue
+		}
+		if !finished {
+			continue
+		}
+
+		log.Info("session expired", slog.String("session_id", ss.Id.String()))
+
+		if err := e.events.PublishSessionEvent(ctx, ss.Id, map[string]any{
+			"session_id": ss.Id,
+			"status":     Finished,
+			"stage":      "session",
+			"reason":     "expired",
+		}); err != nil {
+			log.Warn("failed to publish session event", slog.String("session_id", ss.Id.String()), sl.Err(err))
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rwrrioe/pythia/backend/internal/auth/authn"
+	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
+	"github.com/rwrrioe/pythia/backend/internal/domain/requests"
+	"github.com/rwrrioe/pythia/backend/internal/lib/anki"
+	"github.com/rwrrioe/pythia/backend/internal/lib/card_query"
+	"github.com/rwrrioe/pythia/backend/internal/lib/vocab_export"
+	"github.com/rwrrioe/pythia/backend/internal/storage/postgresql"
+)
+
+// maxExportCards bounds a single export, the package is built in memory and on disk at once
+const maxExportCards = 5000
+
+// ExportService turns decks, smart filters and the vocabulary into files for other flashcard apps
+type ExportService struct {
+	flashcards FlashCardProvider
+	decks      DeckProvider
+	filters    FilterProvider
+
+	pool postgresql.Querier
+}
+
+func NewExportService(
+	flashcards FlashCardProvider,
+	decks DeckProvider,
+	filters FilterProvider,
+	pool postgresql.Querier,
+) *ExportService {
+	return &ExportService{
+		flashcards: flashcards,
+		decks:      decks,
+		filters:    filters,
+		pool:       pool,
+	}
+}
+
+// AnkiDeck prepares the Anki deck of one of the user's decks
+func (s *ExportService) AnkiDeck(ctx context.Context, deckId uuid.UUID) (*anki.Deck, error) {
+	const op = "service.ExportService.AnkiDeck"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+
+	deck, err := s.decks.GetDeck(ctx, s.pool, deckId, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, deckErr(err))
+	}
+
+	cards, err := s.flashcards.ListByDeck(ctx, s.pool, deckId, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	if len(cards) > maxExportCards {
+		return nil, fmt.Errorf("%s:%w: at most %d cards at once", op, ErrExportTooLarge, maxExportCards)
+	}
+
+	out, err := s.ankiDeck(ctx, deck.Name, deck.Description, cards, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return out, nil
+}
+
+// AnkiFilter prepares the Anki deck of the cards currently matching a smart filter
+func (s *ExportService) AnkiFilter(ctx context.Context, filterId uuid.UUID) (*anki.Deck, error) {
+	const op = "service.ExportService.AnkiFilter"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+
+	f, err := s.filters.GetFilter(ctx, s.pool, filterId, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, filterErr(err))
+	}
+
+	node, err := ParseCardQuery(f.Query)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	// one card over the limit tells a full export from a truncated one
+	cards, err := s.flashcards.QueryCards(ctx, s.pool, uid, node, postgresql.CardsNewest, maxExportCards+1, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	if len(cards) > maxExportCards {
+		return nil, fmt.Errorf("%s:%w: at most %d cards at once", op, ErrExportTooLarge, maxExportCards)
+	}
+
+	out, err := s.ankiDeck(ctx, f.Name, f.Query, cards, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return out, nil
+}
+
+func (s *ExportService) ankiDeck(ctx context.Context, name, desc string, cards []entities.FlashCard, uid int64) (*anki.Deck, error) {
+	if len(cards) == 0 {
+		return nil, fmt.Errorf("%w: nothing to export", ErrNotEnoughCards)
+	}
+
+	ids := make([]uuid.UUID, len(cards))
+	for i, fl := range cards {
+		ids[i] = fl.Id
+	}
+
+	reviews, err := s.flashcards.ListReviews(ctx, s.pool, ids, uid)
+	if err != nil {
+		return nil, err
+	}
+	sources, err := s.flashcards.Sources(ctx, s.pool, ids, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		name = "Pythia"
+	}
+	deck := &anki.Deck{
+		Name:        name,
+		Description: desc,
+		Notes:       make([]anki.Note, len(cards)),
+	}
+
+	for i, fl := range cards {
+		n := anki.Note{
+			// the card id keeps the guid stable, exporting again updates the notes in Anki
+			Guid:        base64.RawURLEncoding.EncodeToString(fl.Id[:]),
+			Word:        fl.Word,
+			Translation: fl.Transl,
+			Example:     fl.Example,
+			Source:      sources[fl.Id],
+			Reviews:     make([]anki.Review, 0, len(reviews[fl.Id])),
+		}
+
+		for _, t := range fl.Tags {
+			switch {
+			case strings.HasPrefix(t, entities.TagGenderPrefix):
+				n.Gender = strings.TrimPrefix(t, entities.TagGenderPrefix)
+			case strings.HasPrefix(t, entities.TagSessPrefix):
+				// the session is carried by the source field
+				continue
+			}
+			// Anki nests tags on a double colon
+			n.Tags = append(n.Tags, strings.Replace(t, ":", "::", 1))
+		}
+
+		for _, r := range reviews[fl.Id] {
+			n.Reviews = append(n.Reviews, anki.Review{At: r.ReviewedAt, Correct: r.Correct})
+		}
+
+		deck.Notes[i] = n
+	}
+
+	return deck, nil
+}
+
+// VocabExport is a plain export whose scope and options were checked, Write streams its cards
+type VocabExport struct {
+	Name   string
+	Format vocab_export.Format
+
+	opts   vocab_export.Options
+	stream func(ctx context.Context, fn func(fl entities.FlashCard, src entities.CardSource) error) error
+}
+
+// Vocabulary prepares a plain export of a deck, a smart filter, a card query or the whole vocabulary
+func (s *ExportService) Vocabulary(ctx context.Context, req requests.ExportVocabulary) (*VocabExport, error) {
+	const op = "service.ExportService.Vocabulary"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+
+	format, err := vocab_export.Lookup(req.Format)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w: %w", op, ErrInvalidExport, err)
+	}
+
+	opts := vocab_export.Options{
+		TermSep: vocab_export.Separator(req.TermSep),
+		CardSep: vocab_export.Separator(req.CardSep),
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("%s:%w: %w", op, ErrInvalidExport, err)
+	}
+
+	query := strings.TrimSpace(req.Query)
+	scopes := 0
+	for _, set := range []bool{req.DeckId != nil, req.FilterId != nil, query != ""} {
+		if set {
+			scopes++
+		}
+	}
+	if scopes > 1 {
+		return nil, fmt.Errorf("%s:%w: give one of deck_id, filter_id and q", op, ErrInvalidExport)
+	}
+
+	var (
+		name = "vocabulary"
+		node card_query.Node
+	)
+	switch {
+	case req.DeckId != nil:
+		deck, err := s.decks.GetDeck(ctx, s.pool, *req.DeckId, uid)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%w", op, deckErr(err))
+		}
+		name = deck.Name
+		node = &card_query.Cond{Field: "deck", Op: "=", Id: deck.Id}
+	case req.FilterId != nil:
+		f, err := s.filters.GetFilter(ctx, s.pool, *req.FilterId, uid)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%w", op, filterErr(err))
+		}
+		name = f.Name
+		query = f.Query
+	}
+	if query != "" {
+		if node, err = ParseCardQuery(query); err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+	}
+
+	opts.Title = name
+	now := time.Now()
+
+	return &VocabExport{
+		Name:   name,
+		Format: format,
+		opts:   opts,
+		stream: func(ctx context.Context, fn func(fl entities.FlashCard, src entities.CardSource) error) error {
+			return s.flashcards.StreamCards(ctx, s.pool, uid, node, now, fn)
+		},
+	}, nil
+}
+
+// Write writes the export to w as the cards are read and returns how many were written
+func (e *VocabExport) Write(ctx context.Context, w io.Writer) (int, error) {
+	const op = "service.VocabExport.Write"
+
+	f := e.Format.New(w, e.opts)
+
+	n := 0
+	err := e.stream(ctx, func(fl entities.FlashCard, src entities.CardSource) error {
+		n++
+		return f.Write(exportCard(fl, src))
+	})
+	if err != nil {
+		return n, fmt.Errorf("%s:%w", op, err)
+	}
+
+	if err := f.Close(); err != nil {
+		return n, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return n, nil
+}
+
+func exportCard(fl entities.FlashCard, src entities.CardSource) vocab_export.Card {
+	c := vocab_export.Card{
+		Word:        fl.Word,
+		Translation: fl.Transl,
+		Example:     fl.Example,
+		Description: fl.Desc,
+		Lang:        LangsMap[fl.Lang],
+		Tags:        make([]string, 0, len(fl.Tags)),
+		SessionId:   src.SessionId,
+		Session:     src.Name,
+		SessionDate: src.StartedAt,
+	}
+
+	for _, t := range fl.Tags {
+		switch {
+		case strings.HasPrefix(t, entities.TagGenderPrefix):
+			c.Gender = strings.TrimPrefix(t, entities.TagGenderPrefix)
+		case strings.HasPrefix(t, entities.TagSessPrefix):
+			// the session has its own field
+		default:
+			c.Tags = append(c.Tags, t)
+		}
+	}
+
+	return c
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/rwrrioe/pythia/backend/internal/auth/authn"
+	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
+	"github.com/rwrrioe/pythia/backend/internal/domain/requests"
+	"github.com/rwrrioe/pythia/backend/internal/storage/postgresql"
+)
+
+const maxFilterNameLen = 100
+
+type FilterProvider interface {
+	ListFilters(ctx context.Context, q postgresql.Querier, uid int64) ([]entities.SmartFilter, error)
+	GetFilter(ctx context.Context, q postgresql.Querier, id uuid.UUID, uid int64) (*entities.SmartFilter, error)
+	CreateFilter(ctx context.Context, q postgresql.Querier, name, query string, uid int64) (*entities.SmartFilter, error)
+	UpdateFilter(ctx context.Context, q postgresql.Querier, f entities.SmartFilter, uid int64) (*entities.SmartFilter, error)
+	DeleteFilter(ctx context.Context, q postgresql.Querier, id uuid.UUID, uid int64) error
+}
+
+// FiltersService keeps the smart filters of a user. A filter is a saved card query
+// that works as a dynamic deck: its cards are looked up every time it is used.
+type FiltersService struct {
+	filters    FilterProvider
+	flashcards FlashCardProvider
+	learn      *LearnService
+
+	pool postgresql.Querier
+	txm  *postgresql.TxManager
+}
+
+func NewFiltersService(
+	filters FilterProvider,
+	flashcards FlashCardProvider,
+	learn *LearnService,
+	pool postgresql.Querier,
+	txm *postgresql.TxManager,
+) *FiltersService {
+	return &FiltersService{
+		filters:    filters,
+		flashcards: flashcards,
+		learn:      learn,
+		pool:       pool,
+		txm:        txm,
+	}
+}
+
+func (s *FiltersService) List(ctx context.Context) ([]entities.SmartFilter, error) {
+	const op = "service.FiltersService.List"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+
+	filters, err := s.filters.ListFilters(ctx, s.pool, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return filters, nil
+}
+
+func (s *FiltersService) Get(ctx context.Context, id uuid.UUID) (*entities.SmartFilter, error) {
+	const op = "service.FiltersService.Get"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+
+	f, err := s.filters.GetFilter(ctx, s.pool, id, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, filterErr(err))
+	}
+
+	return f, nil
+}
+
+// Create saves a filter, the query is checked before it is stored
+func (s *FiltersService) Create(ctx context.Context, req requests.CreateSmartFilter) (*entities.SmartFilter, error) {
+	const op = "service.FiltersService.Create"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+
+	name, query := strings.TrimSpace(req.Name), strings.TrimSpace(req.Query)
+	if err := validateFilter(name, query); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	f, err := s.filters.CreateFilter(ctx, s.pool, name, query, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, filterErr(err))
+	}
+
+	return f, nil
+}
+
+func (s *FiltersService) Update(ctx context.Context, id uuid.UUID, req requests.UpdateSmartFilter) (*entities.SmartFilter, error) {
+	const op = "service.FiltersService.Update"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+
+	f, err := s.filters.GetFilter(ctx, s.pool, id, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, filterErr(err))
+	}
+
+	if req.Name != nil {
+		f.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Query != nil {
+		f.Query = strings.TrimSpace(*req.Query)
+	}
+	if err := validateFilter(f.Name, f.Query); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	f, err = s.filters.UpdateFilter(ctx, s.pool, *f, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, filterErr(err))
+	}
+
+	return f, nil
+}
+
+func (s *FiltersService) Delete(ctx context.Context, id uuid.UUID) error {
+	const op = "service.FiltersService.Delete"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+
+	if err := s.filters.DeleteFilter(ctx, s.pool, id, uid); err != nil {
+		return fmt.Errorf("%s:%w", op, filterErr(err))
+	}
+
+	return nil
+}
+
+// Cards returns the flashcards currently matching the filter, newest first
+func (s *FiltersService) Cards(ctx context.Context, id uuid.UUID, limit int) ([]entities.FlashCard, error) {
+	const op = "service.FiltersService.Cards"
+
+	cards, err := s.filterCards(ctx, id, postgresql.CardsNewest, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return cards, nil
+}
+
+// Review returns the review queue of the filter: the most missed cards first, then the least recently seen
+func (s *FiltersService) Review(ctx context.Context, id uuid.UUID, limit int) ([]entities.FlashCard, error) {
+	const op = "service.FiltersService.Review"
+
+	cards, err := s.filterCards(ctx, id, postgresql.CardsReview, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return cards, nil
+}
+
+// Quiz builds a quiz from a random pick of the filter cards, answers are reported with RecordReviews
+func (s *FiltersService) Quiz(ctx context.Context, id uuid.UUID, limit int) ([]entities.QuizQuestion, error) {
+	const op = "service.FiltersService.Quiz"
+
+	cards, err := s.filterCards(ctx, id, postgresql.CardsRandom, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	// the same word in two languages would be its own wrong option, the first card is kept
+	seen := make(map[string]struct{}, len(cards))
+	cards = slices.DeleteFunc(cards, func(fl entities.FlashCard) bool {
+		key := strings.ToLower(fl.Word)
+		if _, ok := seen[key]; ok {
+			return true
+		}
+		seen[key] = struct{}{}
+		return false
+	})
+
+	// every question needs Optcount-1 wrong options taken from the other words
+	if len(cards) < s.learn.Optcount {
+		return nil, fmt.Errorf("%s:%w: a quiz needs at least %d different words", op, ErrNotEnoughCards, s.learn.Optcount)
+	}
+
+	words := make([]entities.Word, len(cards))
+	for i, fl := range cards {
+		words[i] = entities.Word{Word: fl.Word, Translation: fl.Transl, Lang: LangsMap[fl.Lang]}
+	}
+
+	quiz := s.learn.QuizTest(ctx, words)
+	for i := range quiz {
+		quiz[i].CardId = cards[i].Id
+	}
+
+	return quiz, nil
+}
+
+func (s *FiltersService) filterCards(ctx context.Context, id uuid.UUID, order string, limit int) ([]entities.FlashCard, error) {
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthorized
+	}
+
+	f, err := s.filters.GetFilter(ctx, s.pool, id, uid)
+	if err != nil {
+		return nil, filterErr(err)
+	}
+
+	return queryCards(ctx, s.flashcards, s.pool, uid, f.Query, order, limit)
+}
+
+func validateFilter(name, query string) error {
+	if name == "" || utf8.RuneCountInString(name) > maxFilterNameLen {
+		return fmt.Errorf("%w: name must be 1 to %d characters", ErrInvalidSmartFilter, maxFilterNameLen)
+	}
+
+	_, err := ParseCardQuery(query)
+	return err
+}
+
+func filterErr(err error) error {
+	switch {
+	case errors.Is(err, postgresql.ErrFilterNotFound):
+		return ErrSmartFilterNotFound
+	case errors.Is(err, postgresql.ErrFilterAlreadyExists):
+		return ErrSmartFilterExists
+	}
+
+	return err
+}
@@ -0,0 +1,726 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rwrrioe/pythia/backend/internal/auth/authn"
+	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
+	"github.com/rwrrioe/pythia/backend/internal/domain/requests"
+	"github.com/rwrrioe/pythia/backend/internal/lib/job_queue"
+	"github.com/rwrrioe/pythia/backend/internal/storage/postgresql"
+	taskstorage "github.com/rwrrioe/pythia/backend/internal/storage/redis/task_storage"
+)
+
+// StageImport runs vocabulary imports, the job task id is the import id
+const StageImport = "import"
+
+const (
+	MaxImportBytes   = 20 << 20
+	maxImportRows    = 50000
+	importBatch      = 200
+	maxImportErrors  = 50
+	maxImportPreview = 50
+)
+
+type ImportStorage interface {
+	SaveImport(ctx context.Context, imp taskstorage.ImportDTO) error
+	GetImport(ctx context.Context, importId string) (*taskstorage.ImportDTO, bool, error)
+	UpdateImport(ctx context.Context, importId string, update func(imp *taskstorage.ImportDTO) bool) (bool, error)
+}
+
+// ImportService brings vocabulary from Anki packages and csv files. The file is kept in the upload
+// storage and imported by a worker in batches, each batch is saved in its own transaction.
+type ImportService struct {
+	flashcards FlashCardProvider
+	decks      DeckProvider
+	imports    ImportStorage
+	uploads    UploadStorage
+	queue      JobQueue
+
+	pool postgresql.Querier
+	txm  *postgresql.TxManager
+}
+
+func NewImportService(
+	flashcards FlashCardProvider,
+	decks DeckProvider,
+	imports ImportStorage,
+	uploads UploadStorage,
+	queue JobQueue,
+	pool postgresql.Querier,
+	txm *postgresql.TxManager,
+) *ImportService {
+	return &ImportService{
+		flashcards: flashcards,
+		decks:      decks,
+		imports:    imports,
+		uploads:    uploads,
+		queue:      queue,
+		pool:       pool,
+		txm:        txm,
+	}
+}
+
+// Start queues the import of the uploaded file
+func (s *ImportService) Start(ctx context.Context, req requests.ImportVocabulary, fileName string, data []byte) (*entities.Import, error) {
+	const op = "service.ImportService.Start"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+
+	opts, err := importOptions(req, fileName)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	if err := s.checkDeck(ctx, opts.DeckId, uid); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	imp, err := s.enqueue(ctx, uuid.NewString(), uid, opts, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return imp, nil
+}
+
+// checkDeck makes sure the cards may be added to the deck, it may have been deleted since the dry run
+func (s *ImportService) checkDeck(ctx context.Context, deckId *uuid.UUID, uid int64) error {
+	if deckId == nil {
+		return nil
+	}
+
+	deck, err := s.decks.GetDeck(ctx, s.pool, *deckId, uid)
+	if err != nil {
+		return deckErr(err)
+	}
+	if deck.Kind != entities.DeckUser {
+		return ErrSessionDeck
+	}
+
+	return nil
+}
+
+// Confirm imports the file of a finished dry run with the same options. A dry run is confirmed once,
+// its file moves to the started import.
+func (s *ImportService) Confirm(ctx context.Context, importId string) (*entities.Import, error) {
+	const op = "service.ImportService.Confirm"
+
+	imp, err := s.own(ctx, importId)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	if imp.ConfirmedBy != "" {
+		return nil, fmt.Errorf("%s:%w", op, ErrImportConfirmed)
+	}
+	if !imp.Options.DryRun || imp.Status != TaskDone {
+		return nil, fmt.Errorf("%s:%w", op, ErrImportNotPreviewed)
+	}
+	if err := s.checkDeck(ctx, imp.Options.DeckId, imp.UserId); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	// claimed before the file is copied, a concurrent confirmation finds the dry run taken
+	newId := uuid.NewString()
+	claimed, err := s.imports.UpdateImport(ctx, importId, func(cur *taskstorage.ImportDTO) bool {
+		if cur.ConfirmedBy != "" {
+			return false
+		}
+		cur.ConfirmedBy = newId
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	if !claimed {
+		return nil, fmt.Errorf("%s:%w", op, ErrImportConfirmed)
+	}
+
+	out, err := s.confirm(ctx, imp, newId)
+	if err != nil {
+		// the dry run may be confirmed again
+		_, _ = s.imports.UpdateImport(context.WithoutCancel(ctx), importId, func(cur *taskstorage.ImportDTO) bool {
+			if cur.ConfirmedBy != newId {
+				return false
+			}
+			cur.ConfirmedBy = ""
+			return true
+		})
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	_ = s.uploads.DeleteUpload(context.WithoutCancel(ctx), importId)
+
+	return out, nil
+}
+
+func (s *ImportService) confirm(ctx context.Context, imp *taskstorage.ImportDTO, newId string) (*entities.Import, error) {
+	data, ok, err := s.uploads.GetUpload(ctx, imp.Id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrUploadExpired
+	}
+
+	opts := imp.Options
+	opts.DryRun = false
+
+	return s.enqueue(ctx, newId, imp.UserId, opts, data[0])
+}
+
+func (s *ImportService) Get(ctx context.Context, importId string) (*entities.Import, error) {
+	const op = "service.ImportService.Get"
+
+	imp, err := s.own(ctx, importId)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return &imp.Import, nil
+}
+
+// Cancel stops a queued or running import, the batches saved before are kept
+func (s *ImportService) Cancel(ctx context.Context, importId string) error {
+	const op = "service.ImportService.Cancel"
+
+	imp, err := s.own(ctx, importId)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if imp.Status != TaskQueued && imp.Status != TaskProcessing {
+		return fmt.Errorf("%s:%w", op, ErrTaskNotRunning)
+	}
+
+	// a run finishing meanwhile keeps its status, the cancellation is then too late
+	cancelled, err := s.imports.UpdateImport(ctx, importId, func(imp *taskstorage.ImportDTO) bool {
+		if imp.Status != TaskQueued && imp.Status != TaskProcessing {
+			return false
+		}
+		imp.Status, imp.Error = TaskCancelled, ""
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if !cancelled {
+		return fmt.Errorf("%s:%w", op, ErrTaskNotRunning)
+	}
+
+	if err := s.queue.Cancel(ctx, importId); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	return nil
+}
+
+// SetStatus records the state of the import job, a cancelled import keeps its status
+func (s *ImportService) SetStatus(ctx context.Context, importId, status string, taskErr error) error {
+	const op = "service.ImportService.SetStatus"
+
+	_, err := s.imports.UpdateImport(ctx, importId, func(imp *taskstorage.ImportDTO) bool {
+		if imp.Status == TaskCancelled {
+			return false
+		}
+		imp.Status = status
+		imp.Error = ""
+		if taskErr != nil {
+			imp.Error = taskErr.Error()
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	return nil
+}
+
+// saveProgress stores the state of a run unless the import was cancelled meanwhile, reported by false
+func (s *ImportService) saveProgress(ctx context.Context, imp *taskstorage.ImportDTO) (bool, error) {
+	return s.imports.UpdateImport(ctx, imp.Id, func(cur *taskstorage.ImportDTO) bool {
+		if cur.Status == TaskCancelled {
+			return false
+		}
+		confirmedBy := cur.ConfirmedBy
+		*cur = *imp
+		cur.ConfirmedBy = confirmedBy
+		return true
+	})
+}
+
+// Run imports the file, it is called by the import worker. Every run starts over,
+// rows saved by an interrupted run are found again as existing cards.
+func (s *ImportService) Run(ctx context.Context, importId string) error {
+	const op = "service.ImportService.Run"
+
+	imp, ok, err := s.imports.GetImport(ctx, importId)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrImportNotFound)
+	}
+	// cancelled before the job came up
+	if imp.Status == TaskCancelled {
+		return nil
+	}
+
+	data, ok, err := s.uploads.GetUpload(ctx, importId)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUploadExpired)
+	}
+
+	columns, rows, err := parseImport(ctx, data[0], imp.Options)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if len(rows) > maxImportRows {
+		return fmt.Errorf("%s:%w: at most %d rows at once", op, ErrInvalidImport, maxImportRows)
+	}
+
+	cols, err := resolveColumns(imp.Options.Columns, columns)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	// cards in another language than the deck's stay out of it
+	deckLang := 0
+	if imp.Options.DeckId != nil {
+		deck, err := s.decks.GetDeck(ctx, s.pool, *imp.Options.DeckId, imp.UserId)
+		if err != nil {
+			return fmt.Errorf("%s:%w", op, deckErr(err))
+		}
+		deckLang = deck.Language
+	}
+
+	imp.Status = TaskProcessing
+	imp.Error = ""
+	imp.Options.Columns = cols
+	imp.Columns = columns
+	imp.Total, imp.Processed = len(rows), 0
+	imp.Counts = entities.ImportCounts{}
+	imp.Errors, imp.Preview = []entities.ImportRowError{}, []entities.ImportPreviewRow{}
+	// a cancelled import stops here, the batches saved before stay
+	if ok, err = s.saveProgress(ctx, imp); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if !ok {
+		return nil
+	}
+
+	// the cards as left by the rows before, a word repeated in the file applies to its earlier row
+	seen := make(map[importKey]*entities.FlashCard, len(rows))
+
+	for start := 0; start < len(rows); start += importBatch {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%s:%w", op, context.Cause(ctx))
+		}
+
+		batch := rows[start:min(start+importBatch, len(rows))]
+		if err := s.importBatch(ctx, imp, batch, seen, deckLang); err != nil {
+			return fmt.Errorf("%s:%w", op, err)
+		}
+
+		imp.Processed += len(batch)
+		if ok, err = s.saveProgress(ctx, imp); err != nil {
+			return fmt.Errorf("%s:%w", op, err)
+		}
+		if !ok {
+			return nil
+		}
+	}
+
+	// a dry run keeps the file for the confirmation
+	if !imp.Options.DryRun {
+		_ = s.uploads.DeleteUpload(context.WithoutCancel(ctx), importId)
+	}
+
+	imp.Status = TaskDone
+	if _, err := s.saveProgress(context.WithoutCancel(ctx), imp); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	return nil
+}
+
+type importKey struct {
+	word string
+	lang int
+}
+
+type importedCard struct {
+	row  importRow
+	card entities.FlashCard
+	tags []string
+	auto []string
+	err  error
+	// deckErr tells why a valid card wasn't added to the deck
+	deckErr  error
+	action   string
+	existing *entities.FlashCard
+	result   *entities.FlashCard
+}
+
+func (s *ImportService) importBatch(
+	ctx context.Context,
+	imp *taskstorage.ImportDTO,
+	rows []importRow,
+	seen map[importKey]*entities.FlashCard,
+	deckLang int,
+) error {
+	opts := imp.Options
+	uid := imp.UserId
+
+	cards := make([]importedCard, len(rows))
+	var (
+		words []string
+		langs []int
+	)
+	for i, r := range rows {
+		fl, tags, auto := rowCard(r, opts.Columns, opts.LangId)
+		fl = postgresql.NormalizeCard(fl)
+		cards[i] = importedCard{row: r, card: fl, tags: tags, auto: auto, err: validateCard(fl)}
+
+		key := importKey{fl.Word, fl.Lang}
+		if _, ok := seen[key]; !ok && cards[i].err == nil {
+			words, langs = append(words, fl.Word), append(langs, fl.Lang)
+		}
+	}
+
+	if len(words) > 0 {
+		existing, err := s.flashcards.FindWords(ctx, s.pool, words, langs, uid)
+		if err != nil {
+			return err
+		}
+		for _, fl := range existing {
+			seen[importKey{fl.Word, fl.Lang}] = &fl
+		}
+	}
+
+	apply := func(q postgresql.Querier) error {
+		deckCards := make([]uuid.UUID, 0, len(cards))
+
+		for i := range cards {
+			c := &cards[i]
+			if c.err != nil {
+				c.action = entities.ImportInvalid
+				continue
+			}
+
+			key := importKey{c.card.Word, c.card.Lang}
+			next, action := planRow(seen[key], c.card, c.tags, c.auto, opts.Mode)
+			c.existing, c.action = seen[key], action
+
+			if opts.DeckId != nil && deckLang != 0 && next.Lang != deckLang {
+				c.deckErr = fmt.Errorf("not added to the deck, its language is %s", LangsMap[deckLang])
+			}
+
+			if !opts.DryRun {
+				if err := s.saveRow(ctx, q, &next, c, uid); err != nil {
+					return err
+				}
+				if next.Id != uuid.Nil && c.deckErr == nil {
+					deckCards = append(deckCards, next.Id)
+				}
+			}
+
+			c.result = &next
+			seen[key] = &next
+		}
+
+		if opts.DeckId != nil && len(deckCards) > 0 {
+			if _, err := s.decks.CopyFlashcards(ctx, q, *opts.DeckId, deckCards, uid); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	var err error
+	if opts.DryRun {
+		err = apply(s.pool)
+	} else {
+		err = s.txm.WithTx(ctx, func(tx pgx.Tx) error { return apply(tx) })
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, c := range cards {
+		countRow(&imp.Counts, c.action)
+
+		if err := cmp.Or(c.err, c.deckErr); err != nil && len(imp.Errors) < maxImportErrors {
+			imp.Errors = append(imp.Errors, entities.ImportRowError{Row: c.row.line, Error: err.Error()})
+		}
+		if c.err == nil && len(imp.Preview) < maxImportPreview {
+			imp.Preview = append(imp.Preview, entities.ImportPreviewRow{
+				Row:      c.row.line,
+				Action:   c.action,
+				Card:     *c.result,
+				Existing: c.existing,
+			})
+		}
+	}
+
+	return nil
+}
+
+// saveRow writes the planned card and its new tags, next gets the id of a created card
+func (s *ImportService) saveRow(ctx context.Context, q postgresql.Querier, next *entities.FlashCard, c *importedCard, uid int64) error {
+	switch c.action {
+	case entities.ImportCreated:
+		id, err := s.flashcards.Create(ctx, q, *next, uid)
+		if err != nil {
+			return err
+		}
+		next.Id = id
+	case entities.ImportUpdated, entities.ImportMerged:
+		if err := s.flashcards.Update(ctx, q, *next, uid); err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+
+	if len(c.tags) > 0 {
+		if _, err := s.flashcards.AddTags(ctx, q, next.Id, c.tags, false, uid); err != nil {
+			return err
+		}
+	}
+	if len(c.auto) > 0 {
+		if _, err := s.flashcards.AddTags(ctx, q, next.Id, c.auto, true, uid); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// planRow returns the card the row leaves behind and what is done to get it.
+// cur is the card with the same word before the row, nil when there is none.
+func planRow(cur *entities.FlashCard, fl entities.FlashCard, tags, auto []string, mode string) (entities.FlashCard, string) {
+	if cur == nil {
+		fl.Tags = append(slices.Clone(tags), auto...)
+		slices.Sort(fl.Tags)
+		return fl, entities.ImportCreated
+	}
+	if mode == entities.ImportSkip {
+		return *cur, entities.ImportSkipped
+	}
+
+	next := *cur
+	next.Tags = slices.Clone(cur.Tags)
+	for _, t := range append(slices.Clone(tags), auto...) {
+		if !slices.Contains(next.Tags, t) {
+			next.Tags = append(next.Tags, t)
+		}
+	}
+	slices.Sort(next.Tags)
+
+	action := entities.ImportMerged
+	if mode == entities.ImportOverwrite {
+		action = entities.ImportUpdated
+		next.Transl = fl.Transl
+		next.Example = firstOf(fl.Example, next.Example)
+		next.Desc = firstOf(fl.Desc, next.Desc)
+	} else {
+		next.Transl = mergeTranslation(next.Transl, fl.Transl)
+		next.Example = firstOf(next.Example, fl.Example)
+		next.Desc = firstOf(next.Desc, fl.Desc)
+	}
+
+	if next.Transl == cur.Transl && next.Example == cur.Example && next.Desc == cur.Desc && slices.Equal(next.Tags, cur.Tags) {
+		return *cur, entities.ImportUnchanged
+	}
+
+	return next, action
+}
+
+// firstOf returns the first non-empty string
+func firstOf(a, b string) string {
+	if a != "" {
+		return a
+	}
+	return b
+}
+
+// mergeTranslation adds a translation the card doesn't have yet, as long as it fits
+func mergeTranslation(cur, add string) string {
+	for _, t := range strings.Split(cur, ";") {
+		if strings.EqualFold(strings.TrimSpace(t), add) {
+			return cur
+		}
+	}
+
+	merged := cur + "; " + add
+	if utf8.RuneCountInString(merged) > maxCardTextLen {
+		return cur
+	}
+
+	return merged
+}
+
+func countRow(c *entities.ImportCounts, action string) {
+	switch action {
+	case entities.ImportCreated:
+		c.Created++
+	case entities.ImportUpdated:
+		c.Updated++
+	case entities.ImportMerged:
+		c.Merged++
+	case entities.ImportSkipped:
+		c.Skipped++
+	case entities.ImportUnchanged:
+		c.Unchanged++
+	case entities.ImportInvalid:
+		c.Invalid++
+	}
+}
+
+func (s *ImportService) enqueue(ctx context.Context, importId string, uid int64, opts entities.ImportOptions, data []byte) (*entities.Import, error) {
+	now := time.Now()
+	imp := taskstorage.ImportDTO{
+		UserId: uid,
+		Import: entities.Import{
+			Id:        importId,
+			Status:    TaskQueued,
+			Options:   opts,
+			Columns:   []string{},
+			Errors:    []entities.ImportRowError{},
+			Preview:   []entities.ImportPreviewRow{},
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+	}
+
+	if err := s.uploads.SaveUpload(ctx, imp.Id, [][]byte{data}); err != nil {
+		return nil, err
+	}
+	if err := s.imports.SaveImport(ctx, imp); err != nil {
+		_ = s.uploads.DeleteUpload(ctx, imp.Id)
+		return nil, err
+	}
+
+	if _, err := s.queue.Enqueue(ctx, job_queue.Job{
+		Stage:  StageImport,
+		TaskId: imp.Id,
+		UserId: uid,
+	}); err != nil {
+		_ = s.uploads.DeleteUpload(ctx, imp.Id)
+		return nil, err
+	}
+
+	return &imp.Import, nil
+}
+
+// own returns the import if it belongs to the user
+func (s *ImportService) own(ctx context.Context, importId string) (*taskstorage.ImportDTO, error) {
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthorized
+	}
+
+	imp, ok, err := s.imports.GetImport(ctx, importId)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || imp.UserId != uid {
+		return nil, ErrImportNotFound
+	}
+
+	return imp, nil
+}
+
+// importOptions checks the request, the format comes from the file name when it isn't given
+func importOptions(req requests.ImportVocabulary, fileName string) (entities.ImportOptions, error) {
+	opts := entities.ImportOptions{
+		FileName: filepath.Base(fileName),
+		Format:   strings.ToLower(strings.TrimSpace(req.Format)),
+		LangId:   req.LangId,
+		Mode:     strings.ToLower(strings.TrimSpace(req.Mode)),
+		DryRun:   req.DryRun,
+		Header:   req.Header == nil || *req.Header,
+		DeckId:   req.DeckId,
+		Columns: entities.ImportColumns{
+			Word:        req.WordColumn,
+			Translation: req.TranslationColumn,
+			Example:     req.ExampleColumn,
+			Description: req.DescriptionColumn,
+			Tags:        req.TagsColumn,
+			Gender:      req.GenderColumn,
+			Lang:        req.LangColumn,
+		},
+	}
+
+	if opts.Format == "" {
+		switch strings.ToLower(filepath.Ext(fileName)) {
+		case ".apkg", ".colpkg":
+			opts.Format = entities.ImportApkg
+		case ".csv":
+			opts.Format = entities.ImportCSV
+		case ".tsv", ".txt":
+			opts.Format = entities.ImportTSV
+		}
+	}
+
+	switch opts.Format {
+	case entities.ImportApkg:
+	case entities.ImportCSV, entities.ImportTSV:
+		delim, err := importDelimiter(req.Delimiter, opts.Format)
+		if err != nil {
+			return opts, err
+		}
+		opts.Delimiter = delim
+	default:
+		return opts, fmt.Errorf("%w: format must be apkg, csv or tsv", ErrInvalidImport)
+	}
+
+	if _, ok := LangsMap[opts.LangId]; !ok {
+		return opts, fmt.Errorf("%w: unknown language", ErrInvalidImport)
+	}
+
+	switch opts.Mode {
+	case "":
+		opts.Mode = entities.ImportSkip
+	case entities.ImportSkip, entities.ImportOverwrite, entities.ImportMerge:
+	default:
+		return opts, fmt.Errorf("%w: mode must be skip, overwrite or merge", ErrInvalidImport)
+	}
+
+	return opts, nil
+}
+
+func importDelimiter(d, format string) (string, error) {
+	switch strings.ToLower(d) {
+	case "":
+		if format == entities.ImportTSV {
+			return "\t", nil
+		}
+		return ",", nil
+	case `\t`, "tab":
+		return "\t", nil
+	}
+
+	r, n := utf8.DecodeRuneInString(d)
+	if n != len(d) || r == utf8.RuneError || r == '"' || r == '#' || r == '\r' || r == '\n' {
+		return "", fmt.Errorf("%w: invalid delimiter %q", ErrInvalidImport, d)
+	}
+
+	return d, nil
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
+	"github.com/rwrrioe/pythia/backend/internal/lib/anki"
+)
+
+// importRow is a row of a csv file or an Anki note, names are empty for a csv file without header
+type importRow struct {
+	line   int
+	names  []string
+	values []string
+	tags   []string
+}
+
+// value returns the column picked by the spec, a name or a 1-based position
+func (r importRow) value(spec string) string {
+	if spec == "" {
+		return ""
+	}
+	for i, n := range r.names {
+		if strings.EqualFold(n, spec) && i < len(r.values) {
+			return r.values[i]
+		}
+	}
+	if i, err := strconv.Atoi(spec); err == nil && i >= 1 && i <= len(r.values) {
+		return r.values[i-1]
+	}
+
+	return ""
+}
+
+// parseImport reads the rows of the file and the names of its columns in order of appearance
+func parseImport(ctx context.Context, data []byte, opts entities.ImportOptions) ([]string, []importRow, error) {
+	if opts.Format == entities.ImportApkg {
+		return parseApkg(ctx, data)
+	}
+
+	return parseCSV(data, opts)
+}
+
+func parseApkg(ctx context.Context, data []byte) ([]string, []importRow, error) {
+	// one note over the limit tells a full package from a truncated one
+	notes, err := anki.Read(ctx, bytes.NewReader(data), int64(len(data)), maxImportRows+1)
+	if err != nil {
+		if errors.Is(err, anki.ErrInvalidPackage) || errors.Is(err, anki.ErrTooLarge) {
+			return nil, nil, fmt.Errorf("%w: %w", ErrInvalidImport, err)
+		}
+		return nil, nil, err
+	}
+	if len(notes) > maxImportRows {
+		return nil, nil, fmt.Errorf("%w: at most %d rows at once", ErrInvalidImport, maxImportRows)
+	}
+
+	var columns []string
+	rows := make([]importRow, len(notes))
+	for i, n := range notes {
+		for _, name := range n.Names {
+			if !slices.ContainsFunc(columns, func(c string) bool { return strings.EqualFold(c, name) }) {
+				columns = append(columns, name)
+			}
+		}
+		rows[i] = importRow{line: i + 1, names: n.Names, values: n.Fields, tags: n.Tags}
+	}
+
+	return columns, rows, nil
+}
+
+func parseCSV(data []byte, opts entities.ImportOptions) ([]string, []importRow, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		return nil, nil, fmt.Errorf("%w: the file is not utf-8 text", ErrInvalidImport)
+	}
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma, _ = utf8.DecodeRuneInString(opts.Delimiter)
+	// Anki text exports start with #separator: and similar lines
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	var (
+		names []string
+		rows  []importRow
+	)
+	for {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %w", ErrInvalidImport, err)
+		}
+
+		line, _ := r.FieldPos(0)
+		if opts.Header && names == nil {
+			names = make([]string, len(rec))
+			for i, n := range rec {
+				names[i] = strings.TrimSpace(n)
+			}
+			continue
+		}
+		if len(rows) >= maxImportRows {
+			return nil, nil, fmt.Errorf("%w: at most %d rows at once", ErrInvalidImport, maxImportRows)
+		}
+
+		rows = append(rows, importRow{line: line, names: names, values: rec})
+	}
+
+	columns := names
+	if columns == nil {
+		n := 0
+		for _, row := range rows {
+			n = max(n, len(row.values))
+		}
+		for i := range n {
+			columns = append(columns, strconv.Itoa(i+1))
+		}
+	}
+
+	return columns, rows, nil
+}
+
+// default column names, the first one present is used when the user doesn't map the field
+var (
+	wordColumns        = []string{"word", "front", "term", "1"}
+	translationColumns = []string{"translation", "back", "definition", "meaning", "2"}
+	exampleColumns     = []string{"example", "examples", "sentence"}
+	descColumns        = []string{"description", "notes", "note"}
+	tagColumns         = []string{"tags"}
+	genderColumns      = []string{"gender"}
+	langColumns        = []string{"lang", "language"}
+)
+
+// resolveColumns fills in the fields the user left unmapped and checks the mapped ones exist
+func resolveColumns(c entities.ImportColumns, columns []string) (entities.ImportColumns, error) {
+	has := func(spec string) bool {
+		if i, err := strconv.Atoi(spec); err == nil {
+			return i >= 1 && (i <= len(columns))
+		}
+		return slices.ContainsFunc(columns, func(c string) bool { return strings.EqualFold(c, spec) })
+	}
+	pick := func(spec string, defaults []string) (string, error) {
+		if spec = strings.TrimSpace(spec); spec != "" {
+			if !has(spec) {
+				return "", fmt.Errorf("%w: the file has no column %q", ErrInvalidImport, spec)
+			}
+			return spec, nil
+		}
+		for _, d := range defaults {
+			if has(d) {
+				return d, nil
+			}
+		}
+		return "", nil
+	}
+
+	var (
+		out entities.ImportColumns
+		err error
+	)
+	if out.Word, err = pick(c.Word, wordColumns); err != nil {
+		return out, err
+	}
+	if out.Translation, err = pick(c.Translation, translationColumns); err != nil {
+		return out, err
+	}
+	if out.Example, err = pick(c.Example, exampleColumns); err != nil {
+		return out, err
+	}
+	if out.Description, err = pick(c.Description, descColumns); err != nil {
+		return out, err
+	}
+	if out.Tags, err = pick(c.Tags, tagColumns); err != nil {
+		return out, err
+	}
+	if out.Gender, err = pick(c.Gender, genderColumns); err != nil {
+		return out, err
+	}
+	if out.Lang, err = pick(c.Lang, langColumns); err != nil {
+		return out, err
+	}
+
+	if out.Word == "" || out.Translation == "" {
+		return out, fmt.Errorf("%w: map the word and translation columns", ErrInvalidImport)
+	}
+	if out.Word == out.Translation {
+		return out, fmt.Errorf("%w: word and translation are the same column", ErrInvalidImport)
+	}
+
+	return out, nil
+}
+
+// importedAuto are the automatic tags taken back from a package, Anki writes their colon as ::
+var importedAuto = []string{entities.TagPosPrefix, entities.TagLvlPrefix, entities.TagGenderPrefix}
+
+// rowCard builds the flashcard of a row with its user and automatic tags, an unknown language gives lang 0
+func rowCard(r importRow, c entities.ImportColumns, lang int) (entities.FlashCard, []string, []string) {
+	if code := strings.ToLower(strings.TrimSpace(r.value(c.Lang))); code != "" {
+		lang = ExtractLang(code)
+	}
+
+	fl := entities.FlashCard{
+		Word:    r.value(c.Word),
+		Transl:  r.value(c.Translation),
+		Example: r.value(c.Example),
+		Desc:    r.value(c.Description),
+		Lang:    lang,
+	}
+
+	raw := slices.Clone(r.tags)
+	raw = append(raw, strings.FieldsFunc(r.value(c.Tags), func(r rune) bool { return r == ',' || r == ' ' || r == ';' })...)
+
+	var tags, auto []string
+	if g := strings.ToLower(strings.TrimSpace(r.value(c.Gender))); slices.Contains(genders, g) {
+		auto = append(auto, entities.GenderTag(g))
+	}
+	for _, t := range raw {
+		t = strings.ToLower(t)
+		if a := strings.Replace(t, "::", ":", 1); a == entities.TagMistake ||
+			slices.ContainsFunc(importedAuto, func(p string) bool { return strings.HasPrefix(a, p) && len(a) > len(p) }) {
+			if utf8.RuneCountInString(a) <= maxTagLen && !slices.Contains(auto, a) {
+				auto = append(auto, a)
+			}
+			continue
+		}
+
+		// other nested tags keep their path, spaces were replaced by Anki
+		t = strings.ReplaceAll(strings.ReplaceAll(t, "::", "/"), "_", " ")
+		if tag, err := userTag(t); err == nil && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+
+	return fl, tags, auto
+}
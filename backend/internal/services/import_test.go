@@ -0,0 +1,295 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
+)
+
+func TestPlanRow(t *testing.T) {
+	existing := &entities.FlashCard{
+		Word:    "Haus",
+		Transl:  "house",
+		Example: "Das Haus ist groß.",
+		Tags:    []string{"home"},
+	}
+	row := entities.FlashCard{
+		Word:    "Haus",
+		Transl:  "building",
+		Example: "Ein altes Haus.",
+		Desc:    "neuter noun",
+	}
+
+	tests := []struct {
+		name       string
+		cur        *entities.FlashCard
+		fl         entities.FlashCard
+		tags, auto []string
+		mode       string
+		want       entities.FlashCard
+		wantAction string
+	}{
+		{
+			name:       "new word",
+			fl:         row,
+			tags:       []string{"vocab"},
+			auto:       []string{"pos:noun"},
+			mode:       entities.ImportSkip,
+			want:       entities.FlashCard{Word: "Haus", Transl: "building", Example: "Ein altes Haus.", Desc: "neuter noun", Tags: []string{"pos:noun", "vocab"}},
+			wantAction: entities.ImportCreated,
+		},
+		{
+			name:       "skip keeps the card",
+			cur:        existing,
+			fl:         row,
+			tags:       []string{"vocab"},
+			mode:       entities.ImportSkip,
+			want:       *existing,
+			wantAction: entities.ImportSkipped,
+		},
+		{
+			name:       "overwrite takes the imported fields",
+			cur:        existing,
+			fl:         row,
+			tags:       []string{"vocab"},
+			mode:       entities.ImportOverwrite,
+			want:       entities.FlashCard{Word: "Haus", Transl: "building", Example: "Ein altes Haus.", Desc: "neuter noun", Tags: []string{"home", "vocab"}},
+			wantAction: entities.ImportUpdated,
+		},
+		{
+			name:       "overwrite keeps fields the row leaves empty",
+			cur:        existing,
+			fl:         entities.FlashCard{Word: "Haus", Transl: "home"},
+			mode:       entities.ImportOverwrite,
+			want:       entities.FlashCard{Word: "Haus", Transl: "home", Example: "Das Haus ist groß.", Tags: []string{"home"}},
+			wantAction: entities.ImportUpdated,
+		},
+		{
+			name:       "merge fills in and adds the translation",
+			cur:        existing,
+			fl:         row,
+			mode:       entities.ImportMerge,
+			want:       entities.FlashCard{Word: "Haus", Transl: "house; building", Example: "Das Haus ist groß.", Desc: "neuter noun", Tags: []string{"home"}},
+			wantAction: entities.ImportMerged,
+		},
+		{
+			name:       "merge of a known row",
+			cur:        existing,
+			fl:         entities.FlashCard{Word: "Haus", Transl: "House"},
+			tags:       []string{"home"},
+			mode:       entities.ImportMerge,
+			want:       *existing,
+			wantAction: entities.ImportUnchanged,
+		},
+		{
+			name:       "overwrite with the same fields",
+			cur:        existing,
+			fl:         entities.FlashCard{Word: "Haus", Transl: "house"},
+			mode:       entities.ImportOverwrite,
+			want:       *existing,
+			wantAction: entities.ImportUnchanged,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, action := planRow(tt.cur, tt.fl, tt.tags, tt.auto, tt.mode)
+
+			if action != tt.wantAction {
+				t.Errorf("action %q, want %q", action, tt.wantAction)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("card\n got: %+v\nwant: %+v", got, tt.want)
+			}
+		})
+	}
+
+	if !reflect.DeepEqual(existing.Tags, []string{"home"}) {
+		t.Fatalf("planRow changed the tags of the existing card: %q", existing.Tags)
+	}
+}
+
+func TestMergeTranslation(t *testing.T) {
+	long := strings.Repeat("a", maxCardTextLen-3)
+
+	tests := []struct {
+		name     string
+		cur, add string
+		want     string
+	}{
+		{"new translation", "house", "building", "house; building"},
+		{"same translation", "house", "house", "house"},
+		{"case and spaces are ignored", "house; building", "Building", "house; building"},
+		{"too long", long, "home", long},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeTranslation(tt.cur, tt.add); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveColumns(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapped  entities.ImportColumns
+		columns []string
+		want    entities.ImportColumns
+		wantErr bool
+	}{
+		{
+			name:    "default names",
+			columns: []string{"Word", "Translation", "Example", "Tags"},
+			want:    entities.ImportColumns{Word: "word", Translation: "translation", Example: "example", Tags: "tags"},
+		},
+		{
+			name:    "anki field names",
+			columns: []string{"Front", "Back"},
+			want:    entities.ImportColumns{Word: "front", Translation: "back"},
+		},
+		{
+			name:    "unknown names fall back to positions",
+			columns: []string{"Begriff", "Bedeutung"},
+			want:    entities.ImportColumns{Word: "1", Translation: "2"},
+		},
+		{
+			name:    "positions without a header",
+			columns: []string{"1", "2", "3"},
+			want:    entities.ImportColumns{Word: "1", Translation: "2"},
+		},
+		{
+			name:    "mapped columns",
+			mapped:  entities.ImportColumns{Word: " Begriff ", Translation: "3"},
+			columns: []string{"Begriff", "Notiz", "Bedeutung"},
+			want:    entities.ImportColumns{Word: "Begriff", Translation: "3"},
+		},
+		{
+			name:    "mapped column missing",
+			mapped:  entities.ImportColumns{Word: "word", Translation: "meaning"},
+			columns: []string{"word", "translation"},
+			wantErr: true,
+		},
+		{
+			name:    "position out of range",
+			mapped:  entities.ImportColumns{Word: "1", Translation: "3"},
+			columns: []string{"1", "2"},
+			wantErr: true,
+		},
+		{
+			name:    "no translation",
+			columns: []string{"word"},
+			wantErr: true,
+		},
+		{
+			name:    "same column twice",
+			mapped:  entities.ImportColumns{Word: "word", Translation: "word"},
+			columns: []string{"word", "translation"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveColumns(tt.mapped, tt.columns)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidImport) {
+					t.Fatalf("got %v, want %v", err, ErrInvalidImport)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		opts        entities.ImportOptions
+		wantColumns []string
+		wantValues  [][]string
+		wantLines   []int
+		wantErr     bool
+	}{
+		{
+			name:        "header",
+			data:        "word,translation\nHaus,house\nTisch,table\n",
+			opts:        entities.ImportOptions{Header: true, Delimiter: ","},
+			wantColumns: []string{"word", "translation"},
+			wantValues:  [][]string{{"Haus", "house"}, {"Tisch", "table"}},
+			wantLines:   []int{2, 3},
+		},
+		{
+			name:        "no header numbers the columns",
+			data:        "Haus\thouse\nTisch\ttable\tfurniture\n",
+			opts:        entities.ImportOptions{Delimiter: "\t"},
+			wantColumns: []string{"1", "2", "3"},
+			wantValues:  [][]string{{"Haus", "house"}, {"Tisch", "table", "furniture"}},
+			wantLines:   []int{1, 2},
+		},
+		{
+			name:        "anki export comments and a byte order mark",
+			data:        "\xef\xbb\xbf#separator:tab\n#html:false\nHaus\thouse\n",
+			opts:        entities.ImportOptions{Delimiter: "\t"},
+			wantColumns: []string{"1", "2"},
+			wantValues:  [][]string{{"Haus", "house"}},
+			wantLines:   []int{3},
+		},
+		{
+			name:        "quoted fields",
+			data:        "word;translation\n\"Haus; Hof\";\"house \"\"and\"\" yard\"\n",
+			opts:        entities.ImportOptions{Header: true, Delimiter: ";"},
+			wantColumns: []string{"word", "translation"},
+			wantValues:  [][]string{{"Haus; Hof", `house "and" yard`}},
+			wantLines:   []int{2},
+		},
+		{
+			name:    "not utf-8",
+			data:    "Stra\xdfe,street\n",
+			opts:    entities.ImportOptions{Delimiter: ","},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, rows, err := parseCSV([]byte(tt.data), tt.opts)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidImport) {
+					t.Fatalf("got %v, want %v", err, ErrInvalidImport)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(columns, tt.wantColumns) {
+				t.Errorf("columns %q, want %q", columns, tt.wantColumns)
+			}
+			if len(rows) != len(tt.wantValues) {
+				t.Fatalf("got %d rows, want %d", len(rows), len(tt.wantValues))
+			}
+			for i, r := range rows {
+				if !reflect.DeepEqual(r.values, tt.wantValues[i]) {
+					t.Errorf("row %d values %q, want %q", i, r.values, tt.wantValues[i])
+				}
+				if r.line != tt.wantLines[i] {
+					t.Errorf("row %d line %d, want %d", i, r.line, tt.wantLines[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/rwrrioe/pythia/backend/internal/auth/authn"
+	"github.com/rwrrioe/pythia/backend/internal/auth/authz"
+	"github.com/rwrrioe/pythia/backend/internal/lib/job_queue"
+	taskstorage "github.com/rwrrioe/pythia/backend/internal/storage/redis/task_storage"
+)
+
+// TaskQueued is the status of a task waiting for a worker, also set between retries
+const TaskQueued = "queued"
+
+type JobQueue interface {
+	Enqueue(ctx context.Context, job job_queue.Job) (string, error)
+	Cancel(ctx context.Context, taskId string) error
+}
+
+// UploadStorage keeps the files of a job until it's done, the ocr uploads are stored under UploadKey
+type UploadStorage interface {
+	SaveUpload(ctx context.Context, key string, pages [][]byte) error
+	GetUpload(ctx context.Context, key string) ([][]byte, bool, error)
+	DeleteUpload(ctx context.Context, key string) error
+}
+
+// UploadKey names the upload of an ocr task, task ids come from the client so the session is part of it
+func UploadKey(sessionId uuid.UUID, taskId string) string {
+	return sessionId.String() + "/" + taskId
+}
+
+// OCRPayload is the ocr job payload, the images themselves are kept in the upload storage
+type OCRPayload struct {
+	Lang string `json:"lang"`
+	// MultiPage streams the pages and reports each one as soon as it is recognized
+	MultiPage bool `json:"multi_page"`
+	Total     int  `json:"total_pages"`
+}
+
+// JobService puts the long running work of a session on the job queue
+type JobService struct {
+	queue   JobQueue
+	uploads UploadStorage
+	session *SessionService
+}
+
+func NewJobService(queue JobQueue, uploads UploadStorage, session *SessionService) *JobService {
+	return &JobService{
+		queue:   queue,
+		uploads: uploads,
+		session: session,
+	}
+}
+
+// EnqueueOCR starts the named pipeline for the uploaded pages, the later stages are chained by the queue
+func (s *JobService) EnqueueOCR(ctx context.Context, sessionId uuid.UUID, taskId string, pages [][]byte, lang string, multiPage bool, pipeline string) error {
+	const op = "service.JobService.EnqueueOCR"
+
+	stages, err := PipelineStages(pipeline)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	uid, err := s.authorize(ctx, sessionId)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	// the task id is picked by the client, a task of another session is never taken over
+	t, ok, err := s.session.RedisProvider.Get(ctx, taskId)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if ok && t.SessionId != sessionId {
+		return fmt.Errorf("%s:%w", op, ErrTaskExists)
+	}
+
+	if err := s.uploads.SaveUpload(ctx, UploadKey(sessionId, taskId), pages); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	if err := s.session.SetTaskStatus(ctx, sessionId, taskId, StageOCR, TaskQueued, nil); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if _, err := s.session.RedisProvider.UpdateTask(ctx, taskId, func(t *taskstorage.TaskDTO) {
+		t.Pipeline = stages
+		t.RequestedLang = lang
+		t.MultiPage = multiPage
+	}); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	p := OCRPayload{Lang: lang, MultiPage: multiPage, Total: len(pages)}
+	if err := s.enqueueOCR(ctx, sessionId, taskId, uid, p, stages[1:]); err != nil {
+		_ = s.uploads.DeleteUpload(ctx, UploadKey(sessionId, taskId))
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	return nil
+}
+
+func (s *JobService) enqueueOCR(ctx context.Context, sessionId uuid.UUID, taskId string, uid int64, p OCRPayload, next []string) error {
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.queue.Enqueue(ctx, job_queue.Job{
+		Stage:     StageOCR,
+		SessionId: sessionId,
+		TaskId:    taskId,
+		UserId:    uid,
+		Payload:   payload,
+		Next:      next,
+	})
+
+	return err
+}
+
+// EnqueueStage runs one stage of a recognized task, e.g. to retry it after a failure.
+// The stages following it in the task pipeline are chained again.
+func (s *JobService) EnqueueStage(ctx context.Context, sessionId uuid.UUID, taskId, stage string) error {
+	const op = "service.JobService.EnqueueStage"
+
+	if !slices.Contains(manualStages, stage) {
+		return fmt.Errorf("%s:%w", op, ErrInvalidStage)
+	}
+
+	uid, err := s.authorize(ctx, sessionId)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	t, err := s.session.GetTask(ctx, sessionId, taskId)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	if err := s.session.SetTaskStatus(ctx, sessionId, taskId, stage, TaskQueued, nil); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	if _, err := s.queue.Enqueue(ctx, job_queue.Job{
+		Stage:     stage,
+		SessionId: sessionId,
+		TaskId:    taskId,
+		UserId:    uid,
+		Next:      nextStages(t.Pipeline, stage),
+	}); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	return nil
+}
+
+// Cancel stops the running or queued stage of the task, the stages after it are not started
+func (s *JobService) Cancel(ctx context.Context, sessionId uuid.UUID, taskId string) error {
+	const op = "service.JobService.Cancel"
+
+	if _, err := s.authorize(ctx, sessionId); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	t, err := s.session.GetTask(ctx, sessionId, taskId)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if t.Status != TaskQueued && t.Status != TaskProcessing {
+		return fmt.Errorf("%s:%w", op, ErrTaskNotRunning)
+	}
+
+	if err := s.queue.Cancel(ctx, taskId); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	// the worker confirms once the job is stopped, a queued job is only seen when it comes up
+	if err := s.session.SetTaskStatus(ctx, sessionId, taskId, t.Stage, TaskCancelled, nil); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	return nil
+}
+
+// Retry restarts the failed or cancelled stage of the task from its stored inputs,
+// the rest of the pipeline follows as requested by the upload
+func (s *JobService) Retry(ctx context.Context, sessionId uuid.UUID, taskId string) (string, error) {
+	const op = "service.JobService.Retry"
+
+	uid, err := s.authorize(ctx, sessionId)
+	if err != nil {
+		return "", fmt.Errorf("%s:%w", op, err)
+	}
+
+	t, err := s.session.GetTask(ctx, sessionId, taskId)
+	if err != nil {
+		return "", fmt.Errorf("%s:%w", op, err)
+	}
+	if t.Status != TaskError && t.Status != TaskCancelled {
+		return "", fmt.Errorf("%s:%w", op, ErrTaskNotFailed)
+	}
+
+	if t.Stage != StageOCR {
+		if err := s.EnqueueStage(ctx, sessionId, taskId, t.Stage); err != nil {
+			return "", fmt.Errorf("%s:%w", op, err)
+		}
+		return t.Stage, nil
+	}
+
+	pages, ok, err := s.uploads.GetUpload(ctx, UploadKey(sessionId, taskId))
+	if err != nil {
+		return "", fmt.Errorf("%s:%w", op, err)
+	}
+	if !ok {
+		return "", fmt.Errorf("%s:%w", op, ErrUploadExpired)
+	}
+
+	if err := s.session.SetTaskStatus(ctx, sessionId, taskId, StageOCR, TaskQueued, nil); err != nil {
+		return "", fmt.Errorf("%s:%w", op, err)
+	}
+
+	p := OCRPayload{Lang: t.RequestedLang, MultiPage: t.MultiPage, Total: len(pages)}
+	if err := s.enqueueOCR(ctx, sessionId, taskId, uid, p, nextStages(t.Pipeline, StageOCR)); err != nil {
+		return "", fmt.Errorf("%s:%w", op, err)
+	}
+
+	return StageOCR, nil
+}
+
+// Discard cancels the running jobs of a session that is about to be deleted and drops its uploads
+func (s *JobService) Discard(ctx context.Context, sessionId uuid.UUID) error {
+	const op = "service.JobService.Discard"
+
+	if _, err := s.authorize(ctx, sessionId); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	tasks, _, err := s.session.RedisProvider.GetBySession(ctx, sessionId)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	for _, t := range tasks {
+		if t.Status == TaskQueued || t.Status == TaskProcessing {
+			if err := s.queue.Cancel(ctx, t.Id); err != nil {
+				return fmt.Errorf("%s:%w", op, err)
+			}
+		}
+		if err := s.uploads.DeleteUpload(ctx, UploadKey(sessionId, t.Id)); err != nil {
+			return fmt.Errorf("%s:%w", op, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *JobService) authorize(ctx context.Context, sessionId uuid.UUID) (int64, error) {
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return 0, ErrUnauthorized
+	}
+	if err := s.session.authorizer.CanAccessSession(ctx, uid, sessionId); errors.Is(err, authz.ErrForbidden) {
+		return 0, ErrForbidden
+	}
+
+	return uid, nil
+}
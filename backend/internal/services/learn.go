@@ -3,6 +3,8 @@ package service
 import (
 	"context"
 	"math/rand"
+	"slices"
+	"strings"
 
 	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
 )
@@ -32,13 +34,19 @@ func (s *LearnService) QuizTest(ctx context.Context, words []entities.Word) []en
 
 }
 
+// pickOptions returns at most optcount options, the correct word among distinct wrong ones.
+// Fewer words than options give a shorter list instead of failing.
 func pickOptions(words []entities.Word, correct string, optcount int) []string {
 	var pool []string
 
+	seen := map[string]struct{}{strings.ToLower(correct): {}}
 	for _, w := range words {
-		if w.Word != correct {
-			pool = append(pool, w.Word)
+		key := strings.ToLower(w.Word)
+		if _, ok := seen[key]; ok {
+			continue
 		}
+		seen[key] = struct{}{}
+		pool = append(pool, w.Word)
 	}
 
 	rand.Shuffle(len(pool), func(i, j int) {
@@ -46,10 +54,10 @@ func pickOptions(words []entities.Word, correct string, optcount int) []string {
 	})
 
 	opts := make([]string, 0, optcount)
-	opts = append(opts, pool[:4]...)
+	opts = append(opts, pool[:max(0, min(optcount-1, len(pool)))]...)
 
-	insIdx := rand.Intn(len(opts))
-	opts[insIdx] = correct
+	insIdx := rand.Intn(len(opts) + 1)
+	opts = slices.Insert(opts, insIdx, correct)
 
 	return opts
 }
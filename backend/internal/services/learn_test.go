@@ -0,0 +1,53 @@
+package service
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
+)
+
+func TestPickOptions(t *testing.T) {
+	words := func(ws ...string) []entities.Word {
+		out := make([]entities.Word, len(ws))
+		for i, w := range ws {
+			out[i] = entities.Word{Word: w}
+		}
+		return out
+	}
+
+	tests := []struct {
+		name    string
+		words   []entities.Word
+		correct string
+		want    int
+	}{
+		{"enough words", words("die", "der", "das", "Haus", "Tisch"), "die", 4},
+		{"same word twice", words("die", "die", "der", "das", "Haus"), "die", 4},
+		{"duplicate wrong options", words("die", "der", "der", "Der", "das"), "die", 3},
+		{"only the answer", words("die", "Die"), "die", 1},
+		{"no words", nil, "die", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := pickOptions(tt.words, tt.correct, 4)
+
+			if len(opts) != tt.want {
+				t.Fatalf("got %d options %v, want %d", len(opts), opts, tt.want)
+			}
+			if !slices.Contains(opts, tt.correct) {
+				t.Fatalf("options %v miss the answer %q", opts, tt.correct)
+			}
+
+			seen := map[string]bool{}
+			for _, o := range opts {
+				if seen[strings.ToLower(o)] {
+					t.Fatalf("options %v repeat %q", opts, o)
+				}
+				seen[strings.ToLower(o)] = true
+			}
+		})
+	}
+}
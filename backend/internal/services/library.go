@@ -2,17 +2,23 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/rwrrioe/pythia/backend/internal/auth/authn"
 	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
+	"github.com/rwrrioe/pythia/backend/internal/domain/requests"
 	"github.com/rwrrioe/pythia/backend/internal/storage/postgresql"
 )
 
 type LibraryService struct {
-	session SessionProvider
+	session   SessionProvider
+	documents DocumentProvider
 
 	pool postgresql.Querier
 	txm  *postgresql.TxManager
@@ -20,17 +26,37 @@ type LibraryService struct {
 
 func NewLibraryService(
 	session SessionProvider,
+	documents DocumentProvider,
 	pool postgresql.Querier,
 	txm *postgresql.TxManager,
 ) *LibraryService {
 	return &LibraryService{
-		session: session,
-		pool:    pool,
-		txm:     txm,
+		session:   session,
+		documents: documents,
+		pool:      pool,
+		txm:       txm,
 	}
 }
 
-func (s *LibraryService) Library(ctx context.Context) ([]entities.Session, error) {
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// LibraryPage is a page of the library, NextCursor is empty on the last one
+type LibraryPage struct {
+	Sessions   []entities.Session `json:"sessions"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+// libraryCursor is the opaque cursor handed to clients, it only continues the listing it came from
+type libraryCursor struct {
+	Sort  string                   `json:"sort"`
+	After postgresql.SessionCursor `json:"after"`
+}
+
+// Library lists a page of the user sessions, archived ones are listed only when asked for
+func (s *LibraryService) Library(ctx context.Context, req requests.LibraryQuery) (*LibraryPage, error) {
 	const op = "service.Libraryservice.Library"
 
 	uid, ok := authn.UIDFromContext(ctx)
@@ -38,12 +64,135 @@ func (s *LibraryService) Library(ctx context.Context) ([]entities.Session, error
 		return nil, ErrUnauthorized
 	}
 
-	sessions, err := s.session.ListSessions(ctx, s.pool, uid)
+	f, err := libraryFilter(req)
 	if err != nil {
 		return nil, fmt.Errorf("%s:%w", op, err)
 	}
 
-	return sessions, nil
+	// one more row tells whether there is a next page
+	limit := f.Limit
+	f.Limit++
+
+	sessions, err := s.session.SearchSessions(ctx, s.pool, uid, f)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	page := &LibraryPage{Sessions: sessions}
+	if len(sessions) > limit {
+		page.Sessions = sessions[:limit]
+		page.NextCursor, err = encodeCursor(libraryCursor{
+			Sort:  f.Sort,
+			After: postgresql.CursorOf(sessions[limit-1], f.Sort),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+	}
+
+	return page, nil
+}
+
+func libraryFilter(req requests.LibraryQuery) (postgresql.SessionFilter, error) {
+	f := postgresql.SessionFilter{
+		Lang:        req.Lang,
+		Status:      req.Status,
+		MinAccuracy: req.MinAccuracy,
+		MaxAccuracy: req.MaxAccuracy,
+		Name:        strings.TrimSpace(req.Search),
+		Archived:    req.Archived,
+		Sort:        req.Sort,
+		Limit:       req.Limit,
+	}
+
+	if f.Sort == "" {
+		f.Sort = postgresql.SortNewest
+	}
+	if !postgresql.ValidSessionSort(f.Sort) {
+		return f, fmt.Errorf("%w: unknown sort %q", ErrInvalidFilter, f.Sort)
+	}
+
+	switch {
+	case f.Limit == 0:
+		f.Limit = defaultPageSize
+	case f.Limit < 0 || f.Limit > maxPageSize:
+		return f, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidFilter, maxPageSize)
+	}
+
+	if f.Status != "" && f.Status != Active && f.Status != Finished {
+		return f, fmt.Errorf("%w: unknown status %q", ErrInvalidFilter, f.Status)
+	}
+	if f.Lang < 0 {
+		return f, fmt.Errorf("%w: invalid language", ErrInvalidFilter)
+	}
+	if f.MinAccuracy != nil && f.MaxAccuracy != nil && *f.MinAccuracy > *f.MaxAccuracy {
+		return f, fmt.Errorf("%w: min_accuracy is above max_accuracy", ErrInvalidFilter)
+	}
+
+	var err error
+	if f.From, err = parseDate(req.From, false); err != nil {
+		return f, fmt.Errorf("%w: from: %v", ErrInvalidFilter, err)
+	}
+	if f.To, err = parseDate(req.To, true); err != nil {
+		return f, fmt.Errorf("%w: to: %v", ErrInvalidFilter, err)
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && !f.From.Before(f.To) {
+		return f, fmt.Errorf("%w: from must be before to", ErrInvalidFilter)
+	}
+
+	if req.Cursor != "" {
+		c, err := decodeCursor(req.Cursor)
+		if err != nil || c.Sort != f.Sort {
+			return f, ErrInvalidCursor
+		}
+		f.After = &c.After
+	}
+
+	return f, nil
+}
+
+// parseDate accepts RFC 3339 or a plain date, a plain end date includes its whole day
+func parseDate(v string, end bool) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.UTC(), nil
+	}
+
+	t, err := time.Parse(time.DateOnly, v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+
+	return t, nil
+}
+
+func encodeCursor(c libraryCursor) (string, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeCursor(v string) (libraryCursor, error) {
+	var c libraryCursor
+
+	b, err := base64.RawURLEncoding.DecodeString(v)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, err
+	}
+
+	return c, nil
 }
 
 func (s *LibraryService) GetSession(ctx context.Context, sessionId uuid.UUID) (*entities.Session, error) {
@@ -65,3 +214,48 @@ func (s *LibraryService) GetSession(ctx context.Context, sessionId uuid.UUID) (*
 
 	return ss, nil
 }
+
+// ListDocuments returns the source texts uploaded during the session, pages are omitted
+func (s *LibraryService) ListDocuments(ctx context.Context, sessionId uuid.UUID) ([]entities.Document, error) {
+	const op = "service.Libraryservice.ListDocuments"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthorized
+	}
+
+	if _, err := s.session.GetSession(ctx, s.pool, sessionId, uid); err != nil {
+		if errors.Is(err, postgresql.ErrSessionNotFound) {
+			return nil, fmt.Errorf("%s:%w", op, ErrSessionNotFound)
+		}
+
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	docs, err := s.documents.ListBySession(ctx, s.pool, sessionId, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return docs, nil
+}
+
+func (s *LibraryService) GetDocument(ctx context.Context, sessionId, documentId uuid.UUID) (*entities.Document, error) {
+	const op = "service.Libraryservice.GetDocument"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthorized
+	}
+
+	doc, err := s.documents.GetDocument(ctx, s.pool, sessionId, documentId, uid)
+	if err != nil {
+		if errors.Is(err, postgresql.ErrDocumentNotFound) {
+			return nil, fmt.Errorf("%s:%w", op, ErrDocumentNotFound)
+		}
+
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return doc, nil
+}
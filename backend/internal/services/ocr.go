@@ -2,23 +2,188 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
 
-	ocrclient "github.com/rwrrioe/pythia/backend/internal/clients/ocr/grpc"
+	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
+	preprocessor "github.com/rwrrioe/pythia/backend/internal/lib/img_preprocessor"
+	"github.com/rwrrioe/pythia/backend/internal/lib/logger/sl"
+	"github.com/rwrrioe/pythia/backend/internal/storage/postgresql"
 )
 
+type OCRProvider interface {
+	ProcessImage(ctx context.Context, imageData []byte, lang string) (*entities.OCRResult, error)
+	ProcessPages(ctx context.Context, pages [][]byte, lang string, onPage func(page int, res *entities.OCRResult)) error
+}
+
+type OCRCacheProvider interface {
+	Get(ctx context.Context, q postgresql.Querier, hash string, lang string) (*entities.OCRResult, error)
+	Save(ctx context.Context, q postgresql.Querier, hash string, lang string, res *entities.OCRResult) error
+}
+
 type OCRService struct {
-	Client *ocrclient.Client
+	log          *slog.Logger
+	Client       OCRProvider
+	preprocessor *preprocessor.Preprocessor
+	cache        OCRCacheProvider
+	// results below it are returned but not cached, a later upload gets another chance
+	minConfidence float32
+	// language of the first pass over an image whose language is unknown
+	detectLang string
+
+	pool postgresql.Querier
+}
+
+func NewOCRService(
+	log *slog.Logger,
+	cl OCRProvider,
+	pre *preprocessor.Preprocessor,
+	cache OCRCacheProvider,
+	pool postgresql.Querier,
+	minConfidence float32,
+	detectLang string,
+) *OCRService {
+	return &OCRService{
+		log:           log,
+		Client:        cl,
+		preprocessor:  pre,
+		cache:         cache,
+		minConfidence: minConfidence,
+		detectLang:    detectLang,
+		pool:          pool,
+	}
+}
+
+// DetectLang is the language an image is recognized in when its language is unknown
+func (s *OCRService) DetectLang() string {
+	return s.detectLang
+}
+
+func (s *OCRService) MaxUploadBytes() int64 {
+	return s.preprocessor.MaxBytes()
 }
 
-func NewOCRService(cl *ocrclient.Client) *OCRService {
-	return &OCRService{Client: cl}
+// Preprocess validates the uploaded image and prepares it for OCR
+func (s *OCRService) Preprocess(img []byte) ([]byte, error) {
+	const op = "service.OCRService.Preprocess"
+
+	out, err := s.preprocessor.Process(img)
+	if err != nil {
+		switch {
+		case errors.Is(err, preprocessor.ErrTooLarge):
+			return nil, fmt.Errorf("%s:%w", op, ErrImageTooLarge)
+		case errors.Is(err, preprocessor.ErrUnsupportedFormat), errors.Is(err, preprocessor.ErrCorrupted):
+			return nil, fmt.Errorf("%s:%w", op, ErrUnsupportedImage)
+		default:
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+	}
+
+	return out, nil
 }
 
-func (s *OCRService) ProcessImage(ctx context.Context, img []byte, lang string) ([]string, error) {
-	text, err := s.Client.ProcessImage(ctx, img, lang)
+// ProcessImage recognizes the preprocessed image, the bool reports whether the result came from the cache
+func (s *OCRService) ProcessImage(ctx context.Context, img []byte, lang string) (*entities.OCRResult, bool, error) {
+	hash := ImageHash(img)
+
+	// a broken cache must not block recognition, so only a hit short-circuits
+	if res, ok := s.cached(ctx, hash, lang); ok {
+		return res, true, nil
+	}
+
+	res, err := s.Client.ProcessImage(ctx, img, lang)
+	if err != nil {
+		return nil, false, err
+	}
+
+	s.save(ctx, hash, lang, res)
+	return res, false, nil
+}
+
+// ProcessPages recognizes a multi-page document, cached pages are reported first and the rest is streamed.
+// onPage may be called from another goroutine, but never concurrently.
+func (s *OCRService) ProcessPages(
+	ctx context.Context,
+	pages [][]byte,
+	lang string,
+	onPage func(page int, res *entities.OCRResult, cached bool),
+) ([]*entities.OCRResult, error) {
+	results := make([]*entities.OCRResult, len(pages))
+	hashes := make([]string, len(pages))
+
+	var (
+		pending []int
+		upload  [][]byte
+	)
+	for i, p := range pages {
+		hashes[i] = ImageHash(p)
+
+		if res, ok := s.cached(ctx, hashes[i], lang); ok {
+			results[i] = res
+			onPage(i, res, true)
+			continue
+		}
+
+		pending = append(pending, i)
+		upload = append(upload, p)
+	}
+
+	if len(pending) == 0 {
+		return results, nil
+	}
+
+	err := s.Client.ProcessPages(ctx, upload, lang, func(page int, res *entities.OCRResult) {
+		if page < 0 || page >= len(pending) {
+			return
+		}
+
+		idx := pending[page]
+		results[idx] = res
+		s.save(ctx, hashes[idx], lang, res)
+
+		onPage(idx, res, false)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return text, nil
+	return results, nil
+}
+
+// cached looks the result up in the cache, failures are logged and treated as misses
+func (s *OCRService) cached(ctx context.Context, hash, lang string) (*entities.OCRResult, bool) {
+	const op = "service.OCRService.cached"
+
+	res, err := s.cache.Get(ctx, s.pool, hash, lang)
+	if err != nil {
+		if !errors.Is(err, postgresql.ErrOCRResultNotFound) {
+			s.log.Warn("failed to read ocr cache", slog.String("op", op), slog.String("hash", hash), sl.Err(err))
+		}
+		return nil, false
+	}
+
+	return res, true
+}
+
+// save caches the result when it's confident enough, failures are logged but don't fail the recognition
+func (s *OCRService) save(ctx context.Context, hash, lang string, res *entities.OCRResult) {
+	const op = "service.OCRService.save"
+
+	if res.AvgConfidence() < s.minConfidence {
+		return
+	}
+
+	if err := s.cache.Save(ctx, s.pool, hash, lang, res); err != nil {
+		s.log.Warn("failed to save ocr cache", slog.String("op", op), slog.String("hash", hash), sl.Err(err))
+	}
+}
+
+// ImageHash is computed over the preprocessed bytes, the preprocessing is deterministic
+// so re-uploads of the same photo share a hash
+func ImageHash(img []byte) string {
+	sum := sha256.Sum256(img)
+	return hex.EncodeToString(sum[:])
 }
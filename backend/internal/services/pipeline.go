@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/rwrrioe/pythia/backend/internal/auth/authn"
+	"github.com/rwrrioe/pythia/backend/internal/auth/authz"
+	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
+	"github.com/rwrrioe/pythia/backend/internal/domain/requests"
+	taskstorage "github.com/rwrrioe/pythia/backend/internal/storage/redis/task_storage"
+)
+
+// StageExamples writes context examples for the words found by the translate stage
+const StageExamples = "examples"
+
+// pipelines accepted by the upload endpoints.
+// Every stage is a job queue stage with its own worker, a new stage needs a worker and an entry here.
+const (
+	PipelineOCR      = "ocr"
+	PipelineWords    = "words"
+	PipelineExamples = "examples"
+)
+
+var pipelines = map[string][]string{
+	PipelineOCR:      {StageOCR},
+	PipelineWords:    {StageOCR, StageTranslate},
+	PipelineExamples: {StageOCR, StageTranslate, StageExamples},
+}
+
+// manualStages may be started on their own for a recognized task
+var manualStages = []string{StageTranslate, StageExamples}
+
+// PipelineStages returns the stages of the named pipeline, the ocr pipeline when name is empty
+func PipelineStages(name string) ([]string, error) {
+	if name == "" {
+		name = PipelineOCR
+	}
+
+	stages, ok := pipelines[name]
+	if !ok {
+		return nil, ErrInvalidPipeline
+	}
+
+	return slices.Clone(stages), nil
+}
+
+// nextStages returns the stages of the task pipeline that follow stage
+func nextStages(pipeline []string, stage string) []string {
+	i := slices.Index(pipeline, stage)
+	if i < 0 {
+		return nil
+	}
+
+	return slices.Clone(pipeline[i+1:])
+}
+
+// WriteExamples generates usage examples for the words of the task
+func (s *SessionService) WriteExamples(ctx context.Context, sessionId uuid.UUID, taskId string) ([]entities.Example, error) {
+	const op = "service.SessionService.WriteExamples"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+	if err := s.authorizer.CanAccessSession(ctx, uid, sessionId); errors.Is(err, authz.ErrForbidden) {
+		return nil, fmt.Errorf("%s:%w", op, ErrForbidden)
+	}
+
+	t, ok, err := s.RedisProvider.Get(ctx, taskId)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	if !ok || t.SessionId != sessionId {
+		return nil, fmt.Errorf("%s:%w", op, ErrTaskNotFound)
+	}
+	if len(t.Words) == 0 {
+		return nil, fmt.Errorf("%s:%w", op, ErrNoWords)
+	}
+
+	ss, ok, err := s.sessions.GetSession(ctx, sessionId)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s:%w", op, ErrSessionNotFound)
+	}
+
+	examples, err := s.Translate.WriteExamples(ctx, t, requests.AnalyzeRequest{
+		Level: LevelName(ss.Level),
+		Lang:  LangsMap[ss.Language],
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	if ok, err := s.RedisProvider.UpdateTask(ctx, taskId, func(task *taskstorage.TaskDTO) {
+		task.Examples = examples
+	}); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	} else if !ok {
+		return nil, fmt.Errorf("%s:%w", op, ErrTaskNotFound)
+	}
+
+	return examples, nil
+}
@@ -3,6 +3,8 @@ package service
 import (
 	"context"
 	"errors"
+	"slices"
+	"strings"
 	"time"
 
 	"fmt"
@@ -14,6 +16,7 @@ import (
 	"github.com/rwrrioe/pythia/backend/internal/auth/authz"
 	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
 	"github.com/rwrrioe/pythia/backend/internal/domain/requests"
+	"github.com/rwrrioe/pythia/backend/internal/lib/lang_detector"
 	"github.com/rwrrioe/pythia/backend/internal/storage/postgresql"
 	taskstorage "github.com/rwrrioe/pythia/backend/internal/storage/redis/task_storage"
 )
@@ -22,9 +25,30 @@ type SessionProvider interface {
 	GetSession(ctx context.Context, q postgresql.Querier, sessionId uuid.UUID, uid int64) (*entities.Session, error)
 	ListSessions(ctx context.Context, q postgresql.Querier, uid int64) ([]entities.Session, error)
 	ListLatest(ctx context.Context, q postgresql.Querier, uid int64) ([]entities.Session, error)
+	SearchSessions(ctx context.Context, q postgresql.Querier, uid int64, f postgresql.SessionFilter) ([]entities.Session, error)
 	SaveSession(ctx context.Context, q postgresql.Querier, ss entities.Session, uid int64) (uuid.UUID, error)
 	TryMarkFinished(ctx context.Context, q postgresql.Querier, sessionId uuid.UUID, uid int64, endedAt time.Time) (bool, error)
+	SummarizedTasks(ctx context.Context, q postgresql.Querier, sessionId uuid.UUID) ([]string, error)
+	MarkSummarized(ctx context.Context, q postgresql.Querier, sessionId uuid.UUID, taskIds []string) error
 	UpdateAccuracy(ctx context.Context, q postgresql.Querier, sessionId uuid.UUID, uid int64, accuracy float64) error
+	UpdateLanguage(ctx context.Context, q postgresql.Querier, sessionId uuid.UUID, uid int64, langId int) error
+	ClaimExpired(ctx context.Context, q postgresql.Querier, now time.Time, lease time.Duration, limit int) ([]postgresql.ExpiredSession, error)
+	Reopen(ctx context.Context, q postgresql.Querier, sessionId uuid.UUID, uid int64, now time.Time, duration time.Duration) (bool, error)
+	Rename(ctx context.Context, q postgresql.Querier, sessionId uuid.UUID, uid int64, name string) error
+	SetArchived(ctx context.Context, q postgresql.Querier, sessionId uuid.UUID, uid int64, archived bool, now time.Time) error
+	DeleteSession(ctx context.Context, q postgresql.Querier, sessionId uuid.UUID, uid int64) (int64, error)
+}
+
+type UserLevelProvider interface {
+	GetLevel(ctx context.Context, q postgresql.Querier, uid int64) (int, error)
+}
+
+type DocumentProvider interface {
+	SaveDocument(ctx context.Context, q postgresql.Querier, doc entities.Document) (uuid.UUID, error)
+	SaveWords(ctx context.Context, q postgresql.Querier, sessionId uuid.UUID, taskId string, words map[int][]entities.Word) error
+	ListBySession(ctx context.Context, q postgresql.Querier, sessionId uuid.UUID, uid int64) ([]entities.Document, error)
+	GetDocument(ctx context.Context, q postgresql.Querier, sessionId, documentId uuid.UUID, uid int64) (*entities.Document, error)
+	SessionText(ctx context.Context, q postgresql.Querier, sessionId uuid.UUID, uid int64, limit int) (string, error)
 }
 
 const (
@@ -42,10 +66,24 @@ type SessionService struct {
 	txm  *postgresql.TxManager
 
 	RedisProvider      taskstorage.RedisProvider
+	sessions           *SessionRepository
 	SessionProvider    SessionProvider
 	DeckProvider       DeckProvider
 	FlashCardsProvider FlashCardProvider
+	UserProvider       UserLevelProvider
+	DocumentProvider   DocumentProvider
 	authorizer         authz.AuthorizeService
+	langDetector       *lang_detector.Detector
+}
+
+type RecognizeResult struct {
+	CacheHit     bool
+	DetectedLang string
+	SessionLang  string
+	// LangMismatch is set when the text is written in another language than the session or the OCR request
+	LangMismatch bool
+	// LangFilled is set when the session had no language and got the detected one
+	LangFilled bool
 }
 
 func NewSessionService(
@@ -54,12 +92,16 @@ func NewSessionService(
 	learn *LearnService,
 	fl *FlashCardsService,
 	redis taskstorage.RedisProvider,
+	sessions *SessionRepository,
 	txm *postgresql.TxManager,
 	pool postgresql.Querier,
 	ss SessionProvider,
 	deck DeckProvider,
 	flProvider FlashCardProvider,
+	docs DocumentProvider,
+	users UserLevelProvider,
 	authz authz.AuthorizeService,
+	detector *lang_detector.Detector,
 ) (*SessionService, error) {
 
 	return &SessionService{
@@ -67,13 +109,17 @@ func NewSessionService(
 		Translate:          transl,
 		Learn:              learn,
 		RedisProvider:      redis,
+		sessions:           sessions,
 		txm:                txm,
 		pool:               pool,
 		SessionProvider:    ss,
 		DeckProvider:       deck,
 		FlashCardsProvider: flProvider,
+		DocumentProvider:   docs,
+		UserProvider:       users,
 		Flashcards:         fl,
 		authorizer:         authz,
+		langDetector:       detector,
 	}, nil
 }
 
@@ -86,13 +132,27 @@ func (s *SessionService) StartSession(ctx context.Context, req requests.CreateSe
 		return uuid.Nil, fmt.Errorf("%s:%w", op, ErrUnauthorized)
 	}
 
+	if req.Duration < 0 {
+		return uuid.Nil, fmt.Errorf("%s:%w", op, ErrInvalidDuration)
+	}
+
+	level, err := s.sessionLevel(ctx, uid, req.LevelId)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s:%w", op, err)
+	}
+
 	ssion := entities.Session{
 		Duration:  time.Duration(req.Duration) * time.Second,
 		Status:    Active,
 		Language:  req.LangId,
+		Level:     level,
 		StartedAt: time.Now(),
 	}
 
+	if ssion.Duration > 0 {
+		ssion.ExpiresAt = ssion.StartedAt.Add(ssion.Duration)
+	}
+
 	sessionId, err := s.SessionProvider.SaveSession(ctx, s.txm.Pool, ssion, uid)
 	if err != nil {
 		return uuid.Nil, fmt.Errorf("%s:%w", op, err)
@@ -104,7 +164,10 @@ func (s *SessionService) StartSession(ctx context.Context, req requests.CreateSe
 		Name:      ssion.Name,
 		Status:    ssion.Status,
 		Language:  ssion.Language,
+		Level:     ssion.Level,
 		StartedAt: ssion.StartedAt,
+		Duration:  ssion.Duration,
+		ExpiresAt: ssion.ExpiresAt,
 	}); err != nil {
 		return uuid.Nil, fmt.Errorf("%s:%w", op, err)
 	}
@@ -112,30 +175,324 @@ func (s *SessionService) StartSession(ctx context.Context, req requests.CreateSe
 	return sessionId, nil
 }
 
-func (s *SessionService) RecognizeText(ctx context.Context, sessionId uuid.UUID, taskId string, data []byte, lang string) error {
+// sessionLevel validates the requested level, without one the profile level is used
+func (s *SessionService) sessionLevel(ctx context.Context, uid int64, level int) (int, error) {
+	if level != 0 {
+		if _, ok := LevelsMap[level]; !ok {
+			return 0, ErrInvalidLevel
+		}
+		return level, nil
+	}
+
+	level, err := s.UserProvider.GetLevel(ctx, s.txm.Pool, uid)
+	if err != nil {
+		if errors.Is(err, postgresql.ErrUserNotFound) {
+			return DefaultLevel, nil
+		}
+		return 0, err
+	}
+	if _, ok := LevelsMap[level]; !ok {
+		return DefaultLevel, nil
+	}
+
+	return level, nil
+}
+
+// RecognizeText runs OCR over the image and stores the text in the task.
+// An empty lang falls back to the session language, without one the language is detected from a first pass
+// in the detection language and the session adopts it.
+func (s *SessionService) RecognizeText(ctx context.Context, sessionId uuid.UUID, taskId string, data []byte, lang string) (*RecognizeResult, error) {
 	const op = "service.SessionService.RecognizeText"
 
 	uid, ok := authn.UIDFromContext(ctx)
 	if !ok {
-		return fmt.Errorf("%s:%w", op, ErrUnauthorized)
+		return nil, fmt.Errorf("%s:%w", op, ErrUnauthorized)
 	}
 	if err := s.authorizer.CanAccessSession(ctx, uid, sessionId); errors.Is(err, authz.ErrForbidden) {
-		return fmt.Errorf("%s:%w", op, ErrForbidden)
+		return nil, fmt.Errorf("%s:%w", op, ErrForbidden)
 	}
 
-	txt, err := s.OCR.ProcessImage(ctx, data, lang)
+	ss, ok, err := s.sessions.GetSession(ctx, sessionId)
 	if err != nil {
-		return fmt.Errorf("%s:%w", op, err)
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s:%w", op, ErrSessionNotFound)
+	}
+
+	var (
+		res    *entities.OCRResult
+		cached bool
+	)
+	if lang == "" {
+		lang = LangsMap[ss.Language]
+	}
+	if lang == "" {
+		if lang, res, cached, err = s.detectOCRLang(ctx, data); err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+	}
+
+	if res == nil {
+		if res, cached, err = s.OCR.ProcessImage(ctx, data, lang); err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+	}
+
+	out, err := s.saveRecognized(ctx, uid, sessionId, taskId, ss, lang, res, nil, [][]byte{data})
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	out.CacheHit = cached
+
+	return out, nil
+}
+
+// RecognizePages runs OCR over a multi-page document, onPage gets every page as soon as it is recognized.
+// The task stores the merged text of all pages, the language is detected once for the whole document.
+func (s *SessionService) RecognizePages(
+	ctx context.Context,
+	sessionId uuid.UUID,
+	taskId string,
+	pages [][]byte,
+	lang string,
+	onPage func(page int, res *entities.OCRResult, cached bool),
+) (*RecognizeResult, error) {
+	const op = "service.SessionService.RecognizePages"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+	if err := s.authorizer.CanAccessSession(ctx, uid, sessionId); errors.Is(err, authz.ErrForbidden) {
+		return nil, fmt.Errorf("%s:%w", op, ErrForbidden)
+	}
+
+	ss, ok, err := s.sessions.GetSession(ctx, sessionId)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s:%w", op, ErrSessionNotFound)
+	}
+
+	// the language is detected from the first page, the others are recognized in it
+	if lang == "" {
+		lang = LangsMap[ss.Language]
+	}
+	if lang == "" && len(pages) > 0 {
+		if lang, _, _, err = s.detectOCRLang(ctx, pages[0]); err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+	}
 
+	allCached := true
+	results, err := s.OCR.ProcessPages(ctx, pages, lang, func(page int, res *entities.OCRResult, cached bool) {
+		allCached = allCached && cached
+		onPage(page, res, cached)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	out, err := s.saveRecognized(ctx, uid, sessionId, taskId, ss, lang, entities.MergePages(results), results, pages)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
 	}
+	out.CacheHit = allCached
 
-	if err := s.RedisProvider.Save(ctx, taskId, taskstorage.TaskDTO{
-		SessionId: sessionId,
-		OCRText:   txt,
+	return out, nil
+}
+
+// detectOCRLang recognizes img in the detection language and detects the language of the text.
+// The first pass is returned when the text is in that language or its language is unknown or unsupported,
+// otherwise the image still has to be recognized in the detected language.
+func (s *SessionService) detectOCRLang(ctx context.Context, img []byte) (string, *entities.OCRResult, bool, error) {
+	const op = "service.SessionService.detectOCRLang"
+
+	probe := s.OCR.DetectLang()
+	res, cached, err := s.OCR.ProcessImage(ctx, img, probe)
+	if err != nil {
+		return "", nil, false, fmt.Errorf("%s:%w", op, err)
+	}
+
+	det, ok := s.langDetector.Detect(strings.Join(res.Text(), " "))
+	if !ok || det.Lang == probe || ExtractLang(det.Lang) == 0 {
+		return probe, res, cached, nil
+	}
+
+	return det.Lang, nil, false, nil
+}
+
+// saveRecognized detects the language of the recognized text, stores it in the task and keeps the source document in postgres
+func (s *SessionService) saveRecognized(
+	ctx context.Context,
+	uid int64,
+	sessionId uuid.UUID,
+	taskId string,
+	ss *taskstorage.SessionDTO,
+	lang string,
+	res *entities.OCRResult,
+	pages []*entities.OCRResult,
+	images [][]byte,
+) (*RecognizeResult, error) {
+	out := &RecognizeResult{
+		SessionLang: LangsMap[ss.Language],
+	}
+
+	if det, ok := s.langDetector.Detect(strings.Join(res.Text(), " ")); ok {
+		out.DetectedLang = det.Lang
+
+		switch {
+		case ss.Language == 0:
+			filled, err := s.fillLanguage(ctx, sessionId, uid, det.Lang)
+			if err != nil {
+				return nil, err
+			}
+			if filled {
+				out.SessionLang = det.Lang
+				out.LangFilled = true
+			}
+		case det.Lang != out.SessionLang, lang != det.Lang:
+			out.LangMismatch = true
+		}
+	}
+
+	task := taskstorage.TaskDTO{
+		SessionId:    sessionId,
+		OCRText:      res.Text(),
+		OCRLines:     res.Lines,
+		ImageWidth:   res.ImageWidth,
+		ImageHeight:  res.ImageHeight,
+		DetectedLang: out.DetectedLang,
+		Stage:        StageOCR,
+		Status:       TaskProcessing,
+	}
+	for _, p := range pages {
+		if p != nil {
+			task.Pages = append(task.Pages, *p)
+		}
+	}
+
+	// the task may already exist with its pipeline, recognizing again must not lose it
+	ok, err := s.RedisProvider.UpdateTask(ctx, taskId, func(t *taskstorage.TaskDTO) {
+		task.Id, task.Pipeline = t.Id, t.Pipeline
+		task.RequestedLang, task.MultiPage = t.RequestedLang, t.MultiPage
+		*t = task
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err := s.RedisProvider.Save(ctx, taskId, task); err != nil {
+			return nil, err
+		}
+	}
+
+	doc := entities.Document{
+		SessionId:    sessionId,
+		TaskId:       taskId,
+		Language:     ExtractLang(out.SessionLang),
+		DetectedLang: out.DetectedLang,
+	}
+	if pages == nil {
+		pages = []*entities.OCRResult{res}
+	}
+	for i, p := range pages {
+		if p == nil {
+			continue
+		}
+
+		page := entities.DocumentPage{
+			Page:  i,
+			Text:  p.Text(),
+			Lines: p.Lines,
+		}
+		if i < len(images) {
+			page.ImageHash = ImageHash(images[i])
+		}
+		doc.Pages = append(doc.Pages, page)
+	}
+
+	if err := s.txm.WithTx(ctx, func(tx pgx.Tx) error {
+		_, err := s.DocumentProvider.SaveDocument(ctx, tx, doc)
+		return err
 	}); err != nil {
-		return fmt.Errorf("%s:%w", op, err)
+		return nil, err
 	}
-	return nil
+
+	return out, nil
+}
+
+// wordsByPage assigns every word to the first page mentioning it, words not found anywhere stay on the first page
+func wordsByPage(t *taskstorage.TaskDTO, words []entities.Word) map[int][]entities.Word {
+	out := make(map[int][]entities.Word)
+	if len(t.Pages) == 0 {
+		out[0] = words
+		return out
+	}
+
+	texts := make([]string, len(t.Pages))
+	for i, p := range t.Pages {
+		texts[i] = strings.ToLower(strings.Join(p.Text(), " "))
+		out[i] = []entities.Word{}
+	}
+
+	for _, w := range words {
+		page := 0
+		for i, text := range texts {
+			if strings.Contains(text, strings.ToLower(w.Word)) {
+				page = i
+				break
+			}
+		}
+		out[page] = append(out[page], w)
+	}
+
+	return out
+}
+
+// fillLanguage sets the session language to the detected one, a language the app doesn't support is skipped
+func (s *SessionService) fillLanguage(ctx context.Context, sessionId uuid.UUID, uid int64, lang string) (bool, error) {
+	langId := ExtractLang(lang)
+	if langId == 0 {
+		return false, nil
+	}
+
+	if err := s.SessionProvider.UpdateLanguage(ctx, s.txm.Pool, sessionId, uid, langId); err != nil {
+		return false, err
+	}
+
+	_, err := s.RedisProvider.UpdateSession(ctx, sessionId, func(dto *taskstorage.SessionDTO) {
+		dto.Language = langId
+	})
+	return err == nil, err
+}
+
+func (s *SessionService) GetOCRResult(ctx context.Context, sessionId uuid.UUID, taskId string) (*entities.OCRResult, error) {
+	const op = "service.SessionService.GetOCRResult"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+	if err := s.authorizer.CanAccessSession(ctx, uid, sessionId); errors.Is(err, authz.ErrForbidden) {
+		return nil, fmt.Errorf("%s:%w", op, ErrForbidden)
+	}
+
+	t, ok, err := s.RedisProvider.Get(ctx, taskId)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	if !ok || t.SessionId != sessionId {
+		return nil, fmt.Errorf("%s:%w", op, ErrTaskNotFound)
+	}
+
+	return &entities.OCRResult{
+		Lines:       t.OCRLines,
+		ImageWidth:  t.ImageWidth,
+		ImageHeight: t.ImageHeight,
+	}, nil
 }
 
 func (s *SessionService) FindWords(ctx context.Context, sessionId uuid.UUID, taskId string) ([]entities.Word, error) {
@@ -151,32 +508,41 @@ func (s *SessionService) FindWords(ctx context.Context, sessionId uuid.UUID, tas
 
 	t, ok, err := s.RedisProvider.Get(ctx, taskId)
 	if ok != true {
-		return nil, fmt.Errorf("%s:%s", op, ErrTaskNotFound)
+		return nil, fmt.Errorf("%s:%w", op, ErrTaskNotFound)
 	}
 
 	if err != nil {
 		return nil, fmt.Errorf("%s:%w", op, err)
 	}
 
-	ss, ok, err := s.RedisProvider.GetSession(ctx, sessionId)
+	ss, ok, err := s.sessions.GetSession(ctx, sessionId)
 	if ok != true {
-		return nil, fmt.Errorf("%s:%s", op, ErrSessionNotFound)
+		return nil, fmt.Errorf("%s:%w", op, ErrSessionNotFound)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("%s:%w", op, err)
 	}
 
 	words, err := s.Translate.FindUnknownWords(ctx, t, requests.AnalyzeRequest{
-		Level: LevelsMap[ss.Level],
+		Level: LevelName(ss.Level),
 		Lang:  LangsMap[ss.Language],
 	})
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
 
 	if ok, err = s.RedisProvider.UpdateTask(ctx, taskId, func(task *taskstorage.TaskDTO) {
 		task.Words = words
 	}); err != nil {
 		return nil, fmt.Errorf("%s:%w", op, err)
 	} else if ok != true {
-		return nil, fmt.Errorf("%s:%s", op, ErrTaskNotFound)
+		return nil, fmt.Errorf("%s:%w", op, ErrTaskNotFound)
+	}
+
+	if err := s.txm.WithTx(ctx, func(tx pgx.Tx) error {
+		return s.DocumentProvider.SaveWords(ctx, tx, sessionId, taskId, wordsByPage(t, words))
+	}); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
 	}
 
 	return words, nil
@@ -194,7 +560,7 @@ func (s *SessionService) EndSession(ctx context.Context, sessionId uuid.UUID) er
 	}
 
 	//find the most important words read redis + call translate
-	ss, ok, err := s.RedisProvider.GetSession(ctx, sessionId)
+	ss, ok, err := s.sessions.GetSession(ctx, sessionId)
 	if err != nil {
 		return fmt.Errorf("%s:%w", op, err)
 	}
@@ -202,35 +568,135 @@ func (s *SessionService) EndSession(ctx context.Context, sessionId uuid.UUID) er
 		return fmt.Errorf("%s:%s", op, ErrSessionNotFound)
 	}
 
+	// a reopened session already has its deck and may be ended without new uploads
 	tasks, ok, err := s.RedisProvider.GetBySession(ctx, sessionId)
 	if err != nil {
-		if errors.Is(err, redis.Nil) {
+		if !errors.Is(err, redis.Nil) {
+			return fmt.Errorf("%s:%w", op, err)
+		}
+		if len(ss.Words) == 0 {
 			return fmt.Errorf("%s:%w", op, ErrNoWords)
 		}
+	} else if !ok && len(ss.Words) == 0 {
+		return fmt.Errorf("%s:%w", op, ErrSessionNotFound)
+	}
 
+	if _, err := s.finishSession(ctx, uid, sessionId, ss, tasks); err != nil {
 		return fmt.Errorf("%s:%w", op, err)
 	}
+
+	return nil
+}
+
+// ReopenSession moves a finished session back to active, new uploads are added to the existing deck.
+// duration is in seconds, 0 reopens the session without expiry.
+func (s *SessionService) ReopenSession(ctx context.Context, sessionId uuid.UUID, duration int) error {
+	const op = "service.SessionService.ReopenSession"
+
+	uid, ok := authn.UIDFromContext(ctx)
 	if !ok {
-		return fmt.Errorf("%s:%w", op, ErrSessionNotFound)
+		return fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+	if err := s.authorizer.CanAccessSession(ctx, uid, sessionId); errors.Is(err, authz.ErrForbidden) {
+		return fmt.Errorf("%s:%w", op, ErrForbidden)
+	}
+	if duration < 0 {
+		return fmt.Errorf("%s:%w", op, ErrInvalidDuration)
 	}
 
-	var words []entities.Word
-	for _, t := range tasks {
-		ws := t.Words
-		for _, w := range ws {
-			words = append(words, w)
+	ok, err := s.SessionProvider.Reopen(ctx, s.txm.Pool, sessionId, uid, time.Now(), time.Duration(duration)*time.Second)
+	if err != nil {
+		if errors.Is(err, postgresql.ErrSessionNotFound) {
+			return fmt.Errorf("%s:%w", op, ErrSessionNotFound)
 		}
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrSessionNotFinished)
 	}
 
-	impWords, err := s.Translate.SummarizeWords(ctx, words, requests.AnalyzeRequest{
-		Level: LevelsMap[ss.Level],
-		Lang:  LangsMap[ss.Language],
-	})
-	if err != nil {
+	// the cached copy still says finished, rebuild it with the persisted deck as the known words
+	if _, err := s.sessions.Refresh(ctx, sessionId); err != nil {
 		return fmt.Errorf("%s:%w", op, err)
 	}
 
+	return nil
+}
+
+// ExpireSession finalizes a session whose duration has elapsed the same way EndSession does.
+// Unlike EndSession it also finishes sessions without words, reports false if the session was already finished.
+func (s *SessionService) ExpireSession(ctx context.Context, sessionId uuid.UUID, uid int64) (bool, error) {
+	const op = "service.SessionService.ExpireSession"
+
+	ss, ok, err := s.sessions.GetSession(ctx, sessionId)
+	if err != nil {
+		return false, fmt.Errorf("%s:%w", op, err)
+	}
+	if !ok {
+		return false, fmt.Errorf("%s:%w", op, ErrSessionNotFound)
+	}
+
+	tasks, _, err := s.RedisProvider.GetBySession(ctx, sessionId)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return false, fmt.Errorf("%s:%w", op, err)
+	}
+
+	finished, err := s.finishSession(ctx, uid, sessionId, ss, tasks)
+	if err != nil {
+		return false, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return finished, nil
+}
+
+// finishSession summarizes the words of the tasks that are not in the deck yet, marks the session finished and saves its deck.
+// It returns false when the session was already finished by someone else.
+func (s *SessionService) finishSession(
+	ctx context.Context,
+	uid int64,
+	sessionId uuid.UUID,
+	ss *taskstorage.SessionDTO,
+	tasks []taskstorage.TaskDTO,
+) (bool, error) {
+	summarized, err := s.SessionProvider.SummarizedTasks(ctx, s.pool, sessionId)
+	if err != nil {
+		return false, err
+	}
+
+	var (
+		words []entities.Word
+		fresh []string
+	)
+	for _, t := range tasks {
+		if slices.Contains(summarized, t.Id) {
+			continue
+		}
+		words = append(words, t.Words...)
+		fresh = append(fresh, t.Id)
+	}
+
+	// examples may have been written for any task of the session
+	examples := make(map[string]string)
+	for _, t := range tasks {
+		for _, e := range t.Examples {
+			examples[strings.ToLower(e.Word)] = e.Example
+		}
+	}
+
+	var impWords []entities.Word
+	if len(words) > 0 {
+		var err error
+		impWords, err = s.Translate.SummarizeWords(ctx, words, requests.AnalyzeRequest{
+			Level: LevelName(ss.Level),
+			Lang:  LangsMap[ss.Language],
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+
 	endedAt := time.Now()
+	finished := false
 
 	//save to the db
 	err = s.txm.WithTx(ctx, func(tx pgx.Tx) error {
@@ -241,6 +707,7 @@ func (s *SessionService) EndSession(ctx context.Context, sessionId uuid.UUID) er
 		if !ok {
 			return nil
 		}
+		finished = true
 
 		deckId, err := s.DeckProvider.GetOrCreate(ctx, tx, sessionId, uid)
 		if err != nil {
@@ -249,9 +716,10 @@ func (s *SessionService) EndSession(ctx context.Context, sessionId uuid.UUID) er
 
 		for _, w := range impWords {
 			flId, err := s.FlashCardsProvider.GetOrCreate(ctx, tx, entities.FlashCard{
-				Word:   w.Word,
-				Transl: w.Translation,
-				Lang:   ExtractLang(w.Lang),
+				Word:    w.Word,
+				Transl:  w.Translation,
+				Example: examples[strings.ToLower(w.Word)],
+				Lang:    ExtractLang(w.Lang),
 			}, uid)
 
 			if err != nil {
@@ -261,22 +729,28 @@ func (s *SessionService) EndSession(ctx context.Context, sessionId uuid.UUID) er
 			if err := s.DeckProvider.AttachFlashcard(ctx, tx, deckId, flId); err != nil {
 				return err
 			}
+
+			if _, err := s.FlashCardsProvider.AddTags(ctx, tx, flId, autoTags(w, sessionId), true, uid); err != nil {
+				return err
+			}
 		}
 
-		return nil
+		return s.SessionProvider.MarkSummarized(ctx, tx, sessionId, fresh)
 	})
 	if err != nil {
-		return fmt.Errorf("%s:%w", op, err)
+		return false, err
 	}
 
 	//commit + redis update
-	_, _ = s.RedisProvider.UpdateSession(ctx, sessionId, func(dto *taskstorage.SessionDTO) {
-		dto.Status = Finished
-		dto.EndedAt = endedAt
-		dto.Words = impWords
-	})
+	if finished {
+		_, _ = s.RedisProvider.UpdateSession(ctx, sessionId, func(dto *taskstorage.SessionDTO) {
+			dto.Status = Finished
+			dto.EndedAt = endedAt
+			dto.Words = mergeWords(dto.Words, impWords)
+		})
+	}
 
-	return nil
+	return finished, nil
 }
 
 func (s *SessionService) GetFlashcards(ctx context.Context, sessionId uuid.UUID) ([]entities.FlashCardDTO, error) {
@@ -290,9 +764,9 @@ func (s *SessionService) GetFlashcards(ctx context.Context, sessionId uuid.UUID)
 		return nil, fmt.Errorf("%s:%w", op, ErrForbidden)
 	}
 
-	ss, ok, err := s.RedisProvider.GetSession(ctx, sessionId)
+	ss, ok, err := s.sessions.GetSession(ctx, sessionId)
 	if ok != true {
-		return nil, fmt.Errorf("%s:%s", op, ErrSessionNotFound)
+		return nil, fmt.Errorf("%s:%w", op, ErrSessionNotFound)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("%s:%w", op, err)
@@ -313,9 +787,9 @@ func (s *SessionService) Quiz(ctx context.Context, sessionId uuid.UUID) ([]entit
 		return nil, fmt.Errorf("%s:%w", op, ErrForbidden)
 	}
 
-	ss, ok, err := s.RedisProvider.GetSession(ctx, sessionId)
+	ss, ok, err := s.sessions.GetSession(ctx, sessionId)
 	if ok != true {
-		return nil, fmt.Errorf("%s:%s", op, ErrSessionNotFound)
+		return nil, fmt.Errorf("%s:%w", op, ErrSessionNotFound)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("%s:%w", op, err)
@@ -339,13 +813,16 @@ func (s *SessionService) SummarizeSession(ctx context.Context, sessionId uuid.UU
 	if err := s.SessionProvider.UpdateAccuracy(ctx, s.txm.Pool, sessionId, uid, accuracy); err != nil {
 		return nil, fmt.Errorf("%s:%w", op, err)
 	}
+	_, _ = s.RedisProvider.UpdateSession(ctx, sessionId, func(dto *taskstorage.SessionDTO) {
+		dto.Accuracy = accuracy
+	})
 
-	ss, ok, err := s.RedisProvider.GetSession(ctx, sessionId)
+	ss, ok, err := s.sessions.GetSession(ctx, sessionId)
 	if err != nil {
 		return nil, fmt.Errorf("%s:%w", op, err)
 	}
 	if ok != true {
-		return nil, fmt.Errorf("%s:%s", op, ErrSessionNotFound)
+		return nil, fmt.Errorf("%s:%w", op, ErrSessionNotFound)
 	}
 
 	return ss.Words, nil
@@ -373,10 +850,25 @@ func (s *SessionService) GetSession(ctx context.Context, sessionId uuid.UUID) (*
 	return session, nil
 }
 
+// LevelsMap mirrors the levels table
 var LevelsMap = map[int]string{
-	1: "A2",
-	2: "B1",
-	3: "B2",
+	1: "A1",
+	2: "A2",
+	3: "B1",
+	4: "B2",
+	5: "C1",
+	6: "C2",
+}
+
+// DefaultLevel is used when neither the session nor the user profile has a level
+const DefaultLevel = 3
+
+// LevelName returns the CEFR name of the level, unknown levels fall back to DefaultLevel
+func LevelName(level int) string {
+	if name, ok := LevelsMap[level]; ok {
+		return name
+	}
+	return LevelsMap[DefaultLevel]
 }
 
 var LangsMap = map[int]string{
@@ -386,6 +878,26 @@ var LangsMap = map[int]string{
 	4: "es",
 }
 
+// mergeWords appends the words missing from known, a word is identified by its text and language
+func mergeWords(known, words []entities.Word) []entities.Word {
+	seen := make(map[string]struct{}, len(known))
+	for _, w := range known {
+		seen[w.Lang+":"+strings.ToLower(w.Word)] = struct{}{}
+	}
+
+	out := known
+	for _, w := range words {
+		key := w.Lang + ":" + strings.ToLower(w.Word)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, w)
+	}
+
+	return out
+}
+
 // todo!! чето сделать с мапперами
 func ExtractLang(lang string) int {
 	for k, v := range LangsMap {
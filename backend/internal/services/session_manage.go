@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rwrrioe/pythia/backend/internal/auth/authn"
+	"github.com/rwrrioe/pythia/backend/internal/auth/authz"
+	"github.com/rwrrioe/pythia/backend/internal/storage/postgresql"
+	taskstorage "github.com/rwrrioe/pythia/backend/internal/storage/redis/task_storage"
+)
+
+const (
+	// maxNameLen is the size of sessions.name
+	maxNameLen = 100
+	// titleTextLimit bounds the source text sent to the model for a title
+	titleTextLimit = 4000
+)
+
+// RenameSession sets the session name, surrounding spaces are dropped
+func (s *SessionService) RenameSession(ctx context.Context, sessionId uuid.UUID, name string) (string, error) {
+	const op = "service.SessionService.RenameSession"
+
+	uid, err := s.authorizeSession(ctx, sessionId)
+	if err != nil {
+		return "", fmt.Errorf("%s:%w", op, err)
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxNameLen {
+		return "", fmt.Errorf("%s:%w", op, ErrInvalidName)
+	}
+
+	if err := s.rename(ctx, sessionId, uid, name); err != nil {
+		return "", fmt.Errorf("%s:%w", op, err)
+	}
+
+	return name, nil
+}
+
+// GenerateTitle names the session after its source text and saves the name
+func (s *SessionService) GenerateTitle(ctx context.Context, sessionId uuid.UUID) (string, error) {
+	const op = "service.SessionService.GenerateTitle"
+
+	uid, err := s.authorizeSession(ctx, sessionId)
+	if err != nil {
+		return "", fmt.Errorf("%s:%w", op, err)
+	}
+
+	ss, err := s.SessionProvider.GetSession(ctx, s.pool, sessionId, uid)
+	if err != nil {
+		if errors.Is(err, postgresql.ErrSessionNotFound) {
+			return "", fmt.Errorf("%s:%w", op, ErrSessionNotFound)
+		}
+		return "", fmt.Errorf("%s:%w", op, err)
+	}
+
+	text, err := s.DocumentProvider.SessionText(ctx, s.pool, sessionId, uid, titleTextLimit)
+	if err != nil {
+		return "", fmt.Errorf("%s:%w", op, err)
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("%s:%w", op, ErrNoText)
+	}
+
+	title, err := s.Translate.GenerateTitle(ctx, text, LangsMap[ss.Language])
+	if err != nil {
+		return "", fmt.Errorf("%s:%w", op, err)
+	}
+
+	if utf8.RuneCountInString(title) > maxNameLen {
+		title = strings.TrimSpace(string([]rune(title)[:maxNameLen]))
+	}
+	if title == "" {
+		return "", fmt.Errorf("%s: empty title generated", op)
+	}
+
+	if err := s.rename(ctx, sessionId, uid, title); err != nil {
+		return "", fmt.Errorf("%s:%w", op, err)
+	}
+
+	return title, nil
+}
+
+func (s *SessionService) rename(ctx context.Context, sessionId uuid.UUID, uid int64, name string) error {
+	if err := s.SessionProvider.Rename(ctx, s.pool, sessionId, uid, name); err != nil {
+		if errors.Is(err, postgresql.ErrSessionNotFound) {
+			return ErrSessionNotFound
+		}
+		return err
+	}
+
+	// an active session is served from redis
+	if _, err := s.RedisProvider.UpdateSession(ctx, sessionId, func(ss *taskstorage.SessionDTO) {
+		ss.Name = name
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ArchiveSession hides a finished session from the library or brings it back
+func (s *SessionService) ArchiveSession(ctx context.Context, sessionId uuid.UUID, archived bool) error {
+	const op = "service.SessionService.ArchiveSession"
+
+	uid, err := s.authorizeSession(ctx, sessionId)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	ss, err := s.SessionProvider.GetSession(ctx, s.pool, sessionId, uid)
+	if err != nil {
+		if errors.Is(err, postgresql.ErrSessionNotFound) {
+			return fmt.Errorf("%s:%w", op, ErrSessionNotFound)
+		}
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if archived && ss.Status == Active {
+		return fmt.Errorf("%s:%w", op, ErrSessionActive)
+	}
+
+	if err := s.SessionProvider.SetArchived(ctx, s.pool, sessionId, uid, archived, time.Now()); err != nil {
+		if errors.Is(err, postgresql.ErrSessionNotFound) {
+			return fmt.Errorf("%s:%w", op, ErrSessionNotFound)
+		}
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	return nil
+}
+
+// DeleteSession removes the session, its deck, documents and tasks.
+// Flashcards held by another deck are kept, the number of removed ones is returned.
+// Running jobs of the session should be discarded first, see JobService.Discard.
+func (s *SessionService) DeleteSession(ctx context.Context, sessionId uuid.UUID) (int64, error) {
+	const op = "service.SessionService.DeleteSession"
+
+	uid, err := s.authorizeSession(ctx, sessionId)
+	if err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+
+	var removed int64
+	err = s.txm.WithTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		removed, err = s.SessionProvider.DeleteSession(ctx, tx, sessionId, uid)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, postgresql.ErrSessionNotFound) {
+			return 0, fmt.Errorf("%s:%w", op, ErrSessionNotFound)
+		}
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+
+	// the cached copies would expire anyway, a failure here doesn't undo the delete
+	ctx = context.WithoutCancel(ctx)
+	tasks, _, _ := s.RedisProvider.GetBySession(ctx, sessionId)
+	for _, t := range tasks {
+		_ = s.RedisProvider.Delete(ctx, t.Id)
+	}
+	_ = s.RedisProvider.DeleteSession(ctx, sessionId)
+
+	return removed, nil
+}
+
+func (s *SessionService) authorizeSession(ctx context.Context, sessionId uuid.UUID) (int64, error) {
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return 0, ErrUnauthorized
+	}
+	if err := s.authorizer.CanAccessSession(ctx, uid, sessionId); errors.Is(err, authz.ErrForbidden) {
+		return 0, ErrForbidden
+	}
+
+	return uid, nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
+	"github.com/rwrrioe/pythia/backend/internal/storage/postgresql"
+	taskstorage "github.com/rwrrioe/pythia/backend/internal/storage/redis/task_storage"
+)
+
+type SessionFinder interface {
+	FindSession(ctx context.Context, q postgresql.Querier, sessionId uuid.UUID) (*entities.Session, int64, error)
+}
+
+// SessionRepository reads the session state from redis and rebuilds it from postgres when the key has expired.
+// Postgres is the source of truth, redis only keeps the hot copy.
+type SessionRepository struct {
+	redis      taskstorage.RedisProvider
+	sessions   SessionFinder
+	decks      DeckProvider
+	flashcards FlashCardProvider
+	pool       postgresql.Querier
+}
+
+func NewSessionRepository(
+	redis taskstorage.RedisProvider,
+	sessions SessionFinder,
+	decks DeckProvider,
+	flashcards FlashCardProvider,
+	pool postgresql.Querier,
+) *SessionRepository {
+	return &SessionRepository{
+		redis:      redis,
+		sessions:   sessions,
+		decks:      decks,
+		flashcards: flashcards,
+		pool:       pool,
+	}
+}
+
+// GetSession has the same contract as the redis one, ok is false only when the session doesn't exist at all
+func (r *SessionRepository) GetSession(ctx context.Context, sessionId uuid.UUID) (*taskstorage.SessionDTO, bool, error) {
+	const op = "service.SessionRepository.GetSession"
+
+	ss, ok, err := r.redis.GetSession(ctx, sessionId)
+	if err != nil {
+		return nil, true, fmt.Errorf("%s:%w", op, err)
+	}
+	if ok {
+		return ss, true, nil
+	}
+
+	ss, err = r.rehydrate(ctx, sessionId)
+	if err != nil {
+		if errors.Is(err, postgresql.ErrSessionNotFound) {
+			return nil, false, nil
+		}
+		return nil, true, fmt.Errorf("%s:%w", op, err)
+	}
+
+	// a failed re-cache only costs another postgres read next time
+	_ = r.redis.SaveSession(ctx, *ss)
+
+	return ss, true, nil
+}
+
+// Refresh rebuilds the redis copy from postgres, used after the session was changed in postgres directly
+func (r *SessionRepository) Refresh(ctx context.Context, sessionId uuid.UUID) (*taskstorage.SessionDTO, error) {
+	const op = "service.SessionRepository.Refresh"
+
+	ss, err := r.rehydrate(ctx, sessionId)
+	if err != nil {
+		if errors.Is(err, postgresql.ErrSessionNotFound) {
+			return nil, fmt.Errorf("%s:%w", op, ErrSessionNotFound)
+		}
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	if err := r.redis.SaveSession(ctx, *ss); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return ss, nil
+}
+
+func (r *SessionRepository) rehydrate(ctx context.Context, sessionId uuid.UUID) (*taskstorage.SessionDTO, error) {
+	pg, uid, err := r.sessions.FindSession(ctx, r.pool, sessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	ss := &taskstorage.SessionDTO{
+		Id:        pg.Id,
+		Name:      pg.Name,
+		UserId:    uid,
+		StartedAt: pg.StartedAt,
+		EndedAt:   pg.EndedAt,
+		Duration:  pg.Duration,
+		ExpiresAt: pg.ExpiresAt,
+		Status:    pg.Status,
+		Language:  pg.Language,
+		Level:     pg.Level,
+		Accuracy:  pg.Accuracy,
+	}
+
+	// the deck only exists once the session is finished
+	deck, err := r.decks.ListBySession(ctx, r.pool, sessionId, uid)
+	if err != nil {
+		if errors.Is(err, postgresql.ErrDeckNotFound) {
+			return ss, nil
+		}
+		return nil, err
+	}
+
+	cards, err := r.flashcards.ListByDeck(ctx, r.pool, deck.Id, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range cards {
+		ss.Words = append(ss.Words, entities.Word{
+			Word:        c.Word,
+			Translation: c.Transl,
+			Lang:        LangsMap[c.Lang],
+		})
+	}
+
+	return ss, nil
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rwrrioe/pythia/backend/internal/auth/authn"
+	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
+	"github.com/rwrrioe/pythia/backend/internal/domain/requests"
+	"github.com/rwrrioe/pythia/backend/internal/lib/card_query"
+	"github.com/rwrrioe/pythia/backend/internal/storage/postgresql"
+)
+
+const (
+	maxTagLen      = 50
+	maxTagsAtOnce  = 20
+	defaultQueryN  = 100
+	maxQueryResult = 500
+)
+
+// Tags lists the tags of the user's vocabulary, automatic ones included
+func (s *FlashCardsService) Tags(ctx context.Context) ([]entities.Tag, error) {
+	const op = "service.FlashcardService.Tags"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+
+	tags, err := s.flashcards.ListTags(ctx, s.pool, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return tags, nil
+}
+
+// AddTags puts user tags on the flashcard and returns the card with all its tags
+func (s *FlashCardsService) AddTags(ctx context.Context, id uuid.UUID, tags []string) (*entities.FlashCard, error) {
+	const op = "service.FlashcardService.AddTags"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+	if len(tags) > maxTagsAtOnce {
+		return nil, fmt.Errorf("%s:%w: at most %d tags at once", op, ErrInvalidTag, maxTagsAtOnce)
+	}
+
+	clean := make([]string, 0, len(tags))
+	for _, t := range tags {
+		tag, err := userTag(t)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+		clean = append(clean, tag)
+	}
+
+	if _, err := s.flashcards.Get(ctx, s.pool, id, uid); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, cardErr(err))
+	}
+	if _, err := s.flashcards.AddTags(ctx, s.pool, id, clean, false, uid); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	fl, err := s.flashcards.Get(ctx, s.pool, id, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, cardErr(err))
+	}
+
+	return fl, nil
+}
+
+// RemoveTag takes a tag off the flashcard, automatic tags may be removed as well
+func (s *FlashCardsService) RemoveTag(ctx context.Context, id uuid.UUID, tag string) error {
+	const op = "service.FlashcardService.RemoveTag"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+
+	if err := s.flashcards.RemoveTag(ctx, s.pool, id, strings.ToLower(strings.TrimSpace(tag)), uid); err != nil {
+		if errors.Is(err, postgresql.ErrTagNotFound) {
+			return fmt.Errorf("%s:%w", op, ErrTagNotFound)
+		}
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	return nil
+}
+
+// RecordReviews saves quiz and review answers, a wrong answer tags the card as a mistake.
+// It returns how many answers were saved, answers for unknown cards are dropped.
+func (s *FlashCardsService) RecordReviews(ctx context.Context, answers []requests.ReviewAnswer) (int64, error) {
+	const op = "service.FlashcardService.RecordReviews"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return 0, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+	if len(answers) > maxCardBatch {
+		return 0, fmt.Errorf("%s:%w: at most %d answers at once", op, ErrInvalidFlashcard, maxCardBatch)
+	}
+
+	now := time.Now()
+	reviews := make([]entities.Review, 0, len(answers))
+	var missed []uuid.UUID
+	for _, a := range answers {
+		reviews = append(reviews, entities.Review{
+			FlashcardId: a.CardId,
+			SessionId:   a.SessionId,
+			Correct:     a.Correct,
+			ReviewedAt:  now,
+		})
+		if !a.Correct {
+			missed = append(missed, a.CardId)
+		}
+	}
+
+	var saved int64
+	err := s.txm.WithTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		if saved, err = s.flashcards.RecordReviews(ctx, tx, reviews, uid); err != nil {
+			return err
+		}
+		if len(missed) == 0 {
+			return nil
+		}
+
+		_, err = s.flashcards.TagFlashcards(ctx, tx, missed, entities.TagMistake, true, uid)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return saved, nil
+}
+
+// Query lists the flashcards matching an ad hoc card query, newest first
+func (s *FlashCardsService) Query(ctx context.Context, query string, limit int) ([]entities.FlashCard, error) {
+	const op = "service.FlashcardService.Query"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+
+	cards, err := queryCards(ctx, s.flashcards, s.pool, uid, query, postgresql.CardsNewest, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return cards, nil
+}
+
+func queryCards(
+	ctx context.Context,
+	flashcards FlashCardProvider,
+	q postgresql.Querier,
+	uid int64,
+	query, order string,
+	limit int,
+) ([]entities.FlashCard, error) {
+	switch {
+	case limit == 0:
+		limit = defaultQueryN
+	case limit < 0 || limit > maxQueryResult:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, maxQueryResult)
+	}
+
+	node, err := ParseCardQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return flashcards.QueryCards(ctx, q, uid, node, order, limit, time.Now())
+}
+
+// ParseCardQuery parses a smart filter query and resolves the language codes in it
+func ParseCardQuery(query string) (card_query.Node, error) {
+	node, err := card_query.Parse(query)
+	if err != nil {
+		return nil, err
+	}
+
+	err = card_query.Walk(node, func(c *card_query.Cond) error {
+		if c.Field != "lang" {
+			return nil
+		}
+		c.Int = ExtractLang(strings.ToLower(c.Value))
+		if c.Int == 0 {
+			return fmt.Errorf("%w: unknown language %q", ErrInvalidQuery, c.Value)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+// userTag normalizes a tag given by the user, the automatic namespaces are reserved
+func userTag(t string) (string, error) {
+	tag := entities.NormalizeTag(t)
+
+	switch {
+	case tag == "":
+		return "", fmt.Errorf("%w: tag is empty", ErrInvalidTag)
+	case utf8.RuneCountInString(tag) > maxTagLen:
+		return "", fmt.Errorf("%w: tags are limited to %d characters", ErrInvalidTag, maxTagLen)
+	case strings.Contains(tag, ":"), tag == entities.TagMistake:
+		return "", fmt.Errorf("%w: %q is reserved for automatic tags", ErrInvalidTag, tag)
+	}
+
+	return tag, nil
+}
+
+// autoTags are the tags a summarized word gives its flashcard
+func autoTags(w entities.Word, sessionId uuid.UUID) []string {
+	tags := []string{entities.SessionTag(sessionId)}
+	if w.PartOfSpeech != "" {
+		tags = append(tags, entities.PosTag(w.PartOfSpeech))
+	}
+	if slices.Contains(card_query.Levels, strings.ToUpper(w.Level)) {
+		tags = append(tags, entities.LevelTag(w.Level))
+	}
+	if slices.Contains(genders, strings.ToLower(w.Gender)) {
+		tags = append(tags, entities.GenderTag(w.Gender))
+	}
+
+	return tags
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/rwrrioe/pythia/backend/internal/auth/authn"
+	"github.com/rwrrioe/pythia/backend/internal/auth/authz"
+	taskstorage "github.com/rwrrioe/pythia/backend/internal/storage/redis/task_storage"
+)
+
+// task stages
+const (
+	StageOCR       = "ocr"
+	StageTranslate = "translate"
+)
+
+// task statuses, written by the background goroutines so clients can poll instead of listening to the websocket
+const (
+	TaskProcessing = "processing"
+	TaskDone       = "done"
+	TaskError      = "error"
+	TaskCancelled  = "cancelled"
+)
+
+// SetTaskStatus records the progress of a stage, taskErr is stored as the task error.
+// Only the ocr stage creates the task, later stages need the recognized text anyway.
+// A task of another session is refused with ErrForbidden.
+func (s *SessionService) SetTaskStatus(ctx context.Context, sessionId uuid.UUID, taskId, stage, status string, taskErr error) error {
+	const op = "service.SessionService.SetTaskStatus"
+
+	var msg string
+	if taskErr != nil {
+		msg = taskErr.Error()
+	}
+
+	ok, err := s.RedisProvider.SetStatus(ctx, taskId, sessionId, stage, status, msg)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if ok {
+		return nil
+	}
+
+	// only a missing ocr task is created, the task of another session is never overwritten
+	t, exists, err := s.RedisProvider.Get(ctx, taskId)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if exists && t.SessionId != sessionId {
+		return fmt.Errorf("%s:%w", op, ErrForbidden)
+	}
+	if exists || stage != StageOCR {
+		return nil
+	}
+
+	if err := s.RedisProvider.Save(ctx, taskId, taskstorage.TaskDTO{
+		SessionId: sessionId,
+		Stage:     stage,
+		Status:    status,
+		Error:     msg,
+	}); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	return nil
+}
+
+// HandOverTask marks the next stage of the task as queued, unless its worker already picked it up.
+// It returns false when the task moved on from the finished stage.
+func (s *SessionService) HandOverTask(ctx context.Context, sessionId uuid.UUID, taskId, from, to string) (bool, error) {
+	const op = "service.SessionService.HandOverTask"
+
+	handed := false
+	_, err := s.RedisProvider.UpdateTask(ctx, taskId, func(task *taskstorage.TaskDTO) {
+		if task.SessionId != sessionId || task.Stage != from {
+			return
+		}
+		task.Stage = to
+		task.Status = TaskQueued
+		task.Error = ""
+		handed = true
+	})
+	if err != nil {
+		return false, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return handed, nil
+}
+
+func (s *SessionService) GetTask(ctx context.Context, sessionId uuid.UUID, taskId string) (*taskstorage.TaskDTO, error) {
+	const op = "service.SessionService.GetTask"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+	if err := s.authorizer.CanAccessSession(ctx, uid, sessionId); errors.Is(err, authz.ErrForbidden) {
+		return nil, fmt.Errorf("%s:%w", op, ErrForbidden)
+	}
+
+	t, ok, err := s.RedisProvider.Get(ctx, taskId)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	if !ok || t.SessionId != sessionId {
+		return nil, fmt.Errorf("%s:%w", op, ErrTaskNotFound)
+	}
+
+	return t, nil
+}
+
+// ListTasks returns the tasks of the session that are still kept in redis
+func (s *SessionService) ListTasks(ctx context.Context, sessionId uuid.UUID) ([]taskstorage.TaskDTO, error) {
+	const op = "service.SessionService.ListTasks"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+	if err := s.authorizer.CanAccessSession(ctx, uid, sessionId); errors.Is(err, authz.ErrForbidden) {
+		return nil, fmt.Errorf("%s:%w", op, ErrForbidden)
+	}
+
+	tasks, _, err := s.RedisProvider.GetBySession(ctx, sessionId)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return tasks, nil
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
 	"github.com/rwrrioe/pythia/backend/internal/domain/requests"
+	"github.com/rwrrioe/pythia/backend/internal/lib/card_query"
 	taskstorage "github.com/rwrrioe/pythia/backend/internal/storage/redis/task_storage"
 	"google.golang.org/genai"
 )
@@ -32,21 +33,26 @@ const findImportantPrompt string = `
 You are a language learning expert and vocabulary curator.
 
 You are given a list of words extracted from a single learning session.
-The learner is at CEFR level %s.
+The learner is at CEFR level %[1]s.
 
 Your task:
 1. Analyze all the words together as a single session context.
 2. Select ONLY 10–15 words that are the most important for active learning.
 3. Prioritize words that:
-   - are likely unknown or weakly known by an A2–B1 learner
+   - are likely unknown or weakly known by a %[1]s learner
    - are useful, high-value, or conceptually important
    - appear frequently or are central to the session topic
    - are not proper names or trivial function words
 4. Deprioritize or exclude:
-   - very basic words (A1 level)
+   - words well below level %[1]s
    - words that are obvious from context or near-synonyms of simpler words
    - names, numbers, dates, or overly specific terms
 
+For every selected word also give:
+- "pos": its part of speech
+- "level": the CEFR level at which the word is usually learned
+- "gender": the grammatical gender of a noun, omit it for other words and for languages without gender
+
 Important:
 - Think in terms of *learning value*, not raw frequency alone.
 - The goal is efficient learning, not completeness.
@@ -56,10 +62,15 @@ Do NOT include more than 15 or fewer than 10 words.
 
 Input words:
 <<<
-{{%s}}
+{{%[2]s}}
 >>>
 `
 
+// partsOfSpeech are the values the model may tag a summarized word with
+var partsOfSpeech = []string{"noun", "verb", "adjective", "adverb", "pronoun", "preposition", "conjunction", "phrase", "other"}
+
+var genders = []string{"masculine", "feminine", "neuter", "common"}
+
 const defaultPrompt string = `
 Ты профессиональный переводчик. 
 Определи сложные или неизвестные слова в тексте на основе уровня "%s" и длительности изучения "%s".
@@ -74,6 +85,23 @@ const examplePrompt string = `
 Дай перевод на русский в формате JSON [{"word": "...", "example": "..."}].
 Текст: %s, слова %s`
 
+const titlePrompt string = `
+You name the study sessions of a language learner.
+
+Below is the text the learner studied, written in %[1]s.
+Write a short title for the session that tells what the text is about.
+
+Rules:
+- 2 to 6 words, at most 60 characters
+- write it in the language of the text
+- no quotes, no trailing punctuation, no words like "session" or "lesson"
+
+Text:
+<<<
+%[2]s
+>>>
+`
+
 func (t *TranslateService) FindUnknownWords(ctx context.Context, task *taskstorage.TaskDTO, req requests.AnalyzeRequest) ([]entities.Word, error) {
 	if task.OCRText == nil {
 		return nil, errors.New("empty text in request")
@@ -89,6 +117,9 @@ func (t *TranslateService) FindUnknownWords(ctx context.Context, task *taskstora
 				Properties: map[string]*genai.Schema{
 					"word":        {Type: genai.TypeString},
 					"translation": {Type: genai.TypeString},
+					"pos":         {Type: genai.TypeString, Enum: partsOfSpeech},
+					"level":       {Type: genai.TypeString, Enum: card_query.Levels},
+					"gender":      {Type: genai.TypeString, Enum: genders},
 				},
 				Required: []string{"word", "translation"},
 			},
@@ -175,6 +206,9 @@ func (s *TranslateService) SummarizeWords(ctx context.Context, words []entities.
 				Properties: map[string]*genai.Schema{
 					"word":        {Type: genai.TypeString},
 					"translation": {Type: genai.TypeString},
+					"pos":         {Type: genai.TypeString, Enum: partsOfSpeech},
+					"level":       {Type: genai.TypeString, Enum: card_query.Levels},
+					"gender":      {Type: genai.TypeString, Enum: genders},
 				},
 				Required: []string{"word", "translation"},
 			},
@@ -208,3 +242,42 @@ func (s *TranslateService) SummarizeWords(ctx context.Context, words []entities.
 	}
 	return found, nil
 }
+
+// GenerateTitle names a session after the text studied in it
+func (s *TranslateService) GenerateTitle(ctx context.Context, text, lang string) (string, error) {
+	const op = "service.TranslateService.GenerateTitle"
+
+	if lang == "" {
+		lang = "an unknown language"
+	}
+
+	config := &genai.GenerateContentConfig{
+		ResponseMIMEType: "application/json",
+		ResponseSchema: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"title": {Type: genai.TypeString},
+			},
+			Required: []string{"title"},
+		},
+	}
+
+	prompt := fmt.Sprintf(titlePrompt, lang, text)
+	result, err := s.client.Models.GenerateContent(ctx,
+		s.model,
+		genai.Text(prompt),
+		config,
+	)
+	if err != nil {
+		return "", fmt.Errorf("%s: failed to generate AI title-response:%w", op, err)
+	}
+
+	var out struct {
+		Title string `json:"title"`
+	}
+	if err := json.Unmarshal([]byte(result.Text()), &out); err != nil {
+		return "", fmt.Errorf("%s: failed to unmarshal AI title-response: %w", op, err)
+	}
+
+	return strings.Trim(strings.TrimSpace(out.Title), `"'.`), nil
+}
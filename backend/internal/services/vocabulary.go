@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rwrrioe/pythia/backend/internal/auth/authn"
+	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
+	"github.com/rwrrioe/pythia/backend/internal/domain/requests"
+	"github.com/rwrrioe/pythia/backend/internal/storage/postgresql"
+)
+
+const (
+	maxSearchLen       = 100
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// Search answers "have I learned this word already": it ranks the user flashcards against the term
+// and tells which decks and sessions each of them belongs to
+func (s *FlashCardsService) Search(ctx context.Context, req requests.VocabularySearch) ([]entities.VocabularyHit, error) {
+	const op = "service.FlashcardService.Search"
+
+	uid, ok := authn.UIDFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%s:%w", op, ErrUnauthorized)
+	}
+
+	term := postgresql.NormalizeCard(entities.FlashCard{Word: req.Query}).Word
+	switch {
+	case term == "":
+		return nil, fmt.Errorf("%s:%w: empty query", op, ErrInvalidSearch)
+	case utf8.RuneCountInString(term) > maxSearchLen:
+		return nil, fmt.Errorf("%s:%w: query is limited to %d characters", op, ErrInvalidSearch, maxSearchLen)
+	}
+
+	if _, ok := LangsMap[req.LangId]; req.LangId != 0 && !ok {
+		return nil, fmt.Errorf("%s:%w: unknown language", op, ErrInvalidSearch)
+	}
+
+	limit := req.Limit
+	switch {
+	case limit == 0:
+		limit = defaultSearchLimit
+	case limit < 0 || limit > maxSearchLimit:
+		return nil, fmt.Errorf("%s:%w: limit must be between 1 and %d", op, ErrInvalidSearch, maxSearchLimit)
+	}
+
+	hits, err := s.flashcards.SearchVocabulary(ctx, s.pool, uid, strings.ToLower(term), req.LangId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	if err := s.flashcards.Memberships(ctx, s.pool, hits, uid); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return hits, nil
+}
@@ -0,0 +1,159 @@
+// Package disk_storage keeps the uploads waiting for their jobs on a directory shared by the api and the workers,
+// the queue only carries the id of the task they belong to.
+package disk_storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+type UploadStorage struct {
+	log *slog.Logger
+	dir string
+	// ttl bounds how long the files of an unfinished job are kept for retries
+	ttl time.Duration
+}
+
+func NewUploadStorage(log *slog.Logger, dir string, ttl time.Duration) (*UploadStorage, error) {
+	const op = "storage.disk.NewUploadStorage"
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return &UploadStorage{log: log, dir: dir, ttl: ttl}, nil
+}
+
+// path hashes the key, it holds the task id picked by the client and must not reach the file system as is
+func (s *UploadStorage) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:]))
+}
+
+// SaveUpload writes the pages to a temporary directory renamed into place, a reader never sees a partial upload
+func (s *UploadStorage) SaveUpload(ctx context.Context, key string, pages [][]byte) error {
+	const op = "storage.disk.SaveUpload"
+
+	tmp, err := os.MkdirTemp(s.dir, ".upload-*")
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	for i, p := range pages {
+		if err := ctx.Err(); err != nil {
+			os.RemoveAll(tmp)
+			return fmt.Errorf("%s:%w", op, err)
+		}
+		if err := os.WriteFile(filepath.Join(tmp, fmt.Sprintf("%04d", i)), p, 0o600); err != nil {
+			os.RemoveAll(tmp)
+			return fmt.Errorf("%s:%w", op, err)
+		}
+	}
+
+	path := s.path(key)
+	if err := os.RemoveAll(path); err != nil {
+		os.RemoveAll(tmp)
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.RemoveAll(tmp)
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	return nil
+}
+
+func (s *UploadStorage) GetUpload(ctx context.Context, key string) ([][]byte, bool, error) {
+	const op = "storage.disk.GetUpload"
+
+	path := s.path(key)
+	entries, err := os.ReadDir(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("%s:%w", op, err)
+	}
+	if len(entries) == 0 {
+		return nil, false, nil
+	}
+
+	// the pages are named by their index, padded so they sort in order
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	slices.Sort(names)
+
+	pages := make([][]byte, 0, len(names))
+	for _, n := range names {
+		if err := ctx.Err(); err != nil {
+			return nil, false, fmt.Errorf("%s:%w", op, err)
+		}
+		p, err := os.ReadFile(filepath.Join(path, n))
+		// deleted while it was read
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("%s:%w", op, err)
+		}
+		pages = append(pages, p)
+	}
+
+	return pages, true, nil
+}
+
+func (s *UploadStorage) DeleteUpload(ctx context.Context, key string) error {
+	const op = "storage.disk.DeleteUpload"
+
+	if err := os.RemoveAll(s.path(key)); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	return nil
+}
+
+// Clean removes the uploads older than the ttl every interval until ctx is done,
+// they belong to jobs that were abandoned or failed for good
+func (s *UploadStorage) Clean(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.clean()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *UploadStorage) clean() {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		s.log.Error("failed to list uploads", slog.String("error", err.Error()))
+		return
+	}
+
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || time.Since(info.ModTime()) < s.ttl {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(s.dir, e.Name())); err != nil {
+			s.log.Error("failed to remove an expired upload", slog.String("upload", e.Name()), slog.String("error", err.Error()))
+		}
+	}
+}
@@ -1,9 +1,19 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type Deck struct {
-	UserId    int       `db:"user_id"`
-	Id        uuid.UUID `db:"id"`
-	SessionId int64     `db:"session_id"`
+	UserId      int        `db:"user_id"`
+	Id          uuid.UUID  `db:"id"`
+	SessionId   *uuid.UUID `db:"session_id"`
+	Kind        string     `db:"kind"`
+	Name        string     `db:"name"`
+	Description string     `db:"description"`
+	Lang        int        `db:"lang_id"`
+	Position    int        `db:"position"`
+	CreatedAt   time.Time  `db:"created_at"`
 }
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Document struct {
+	Id           uuid.UUID `db:"id"`
+	SessionId    uuid.UUID `db:"session_id"`
+	TaskId       string    `db:"task_id"`
+	Lang         int       `db:"lang_id"`
+	DetectedLang string    `db:"detected_lang"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+type DocumentPage struct {
+	DocumentId uuid.UUID `db:"document_id"`
+	Page       int       `db:"page"`
+	Text       string    `db:"text"`
+	Lines      []byte    `db:"lines"`
+	ImageHash  string    `db:"image_hash"`
+	Words      []byte    `db:"words"`
+}
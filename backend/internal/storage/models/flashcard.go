@@ -3,8 +3,11 @@ package models
 import "github.com/google/uuid"
 
 type FlashCard struct {
-	Id     uuid.UUID `db:"id"`
-	Word   string    `db:"word"`
-	Transl string    `db:"transl"`
-	Lang   int       `db:"lang_id"`
+	Id      uuid.UUID `db:"id"`
+	Word    string    `db:"word"`
+	Transl  string    `db:"transl"`
+	Lang    int       `db:"lang_id"`
+	Desc    string    `db:"description"`
+	Example string    `db:"example"`
+	Tags    []string  `db:"tags"`
 }
@@ -7,12 +7,16 @@ import (
 )
 
 type Session struct {
-	Id        uuid.UUID `db:"id"`
-	UserId    int64     `db:"user_id"`
-	Name      string    `db:"name"`
-	Lang      int       `db:"lang_id"`
-	Status    string    `db:"status"`
-	StartedAt time.Time `db:"started_at"`
-	EndedAt   time.Time `db:"ended_at"`
-	Accuracy  float64   `db:"accuracy"`
+	Id         uuid.UUID  `db:"id"`
+	UserId     int64      `db:"user_id"`
+	Name       string     `db:"name"`
+	Lang       int        `db:"lang_id"`
+	Level      int        `db:"level_id"`
+	Status     string     `db:"status"`
+	StartedAt  time.Time  `db:"started_at"`
+	EndedAt    time.Time  `db:"ended_at"`
+	Accuracy   float64    `db:"accuracy"`
+	Duration   int        `db:"duration"`
+	ExpiresAt  *time.Time `db:"expires_at"`
+	ArchivedAt *time.Time `db:"archived_at"`
 }
@@ -0,0 +1,214 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
+	"github.com/rwrrioe/pythia/backend/internal/lib/card_query"
+	"github.com/rwrrioe/pythia/backend/internal/storage/models"
+)
+
+const (
+	CardsNewest = "newest"
+	// CardsReview puts the most missed cards first, then the ones not seen for the longest time
+	CardsReview = "review"
+	CardsRandom = "random"
+)
+
+var cardOrders = map[string]string{
+	CardsNewest: `f.created_at DESC, f.id`,
+	CardsReview: `(SELECT count(*) FROM flashcard_reviews r WHERE r.flashcard_id = f.id AND NOT r.correct) DESC,
+		(SELECT max(r.reviewed_at) FROM flashcard_reviews r WHERE r.flashcard_id = f.id) ASC NULLS FIRST,
+		f.id`,
+	CardsRandom: `random()`,
+}
+
+var sqlOps = map[string]string{
+	"=":  "=",
+	"!=": "<>",
+	"<":  "<",
+	"<=": "<=",
+	">":  ">",
+	">=": ">=",
+}
+
+// QueryCards lists the user flashcards matching a parsed card query.
+// Conditions on lang must carry the language id in Int, now anchors the time windows.
+func (s *FlashCardStorage) QueryCards(
+	ctx context.Context,
+	q Querier,
+	uid int64,
+	query card_query.Node,
+	order string,
+	limit int,
+	now time.Time,
+) ([]entities.FlashCard, error) {
+	const op = "postgresql.FlashCardStorage.QueryCards"
+
+	orderBy, ok := cardOrders[order]
+	if !ok {
+		orderBy = cardOrders[CardsNewest]
+	}
+
+	args := []any{uid}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	where, err := compileQuery(query, arg, now)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	sql := `SELECT ` + flashcardCols + `
+		FROM flashcards f
+		WHERE f.user_id = $1 AND ` + where + `
+		ORDER BY ` + orderBy + `
+		LIMIT ` + arg(limit)
+
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	defer rows.Close()
+
+	out := make([]entities.FlashCard, 0, limit)
+	for rows.Next() {
+		var m models.FlashCard
+		if err := scanFlashcard(rows, &m); err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+
+		out = append(out, toFlashcard(m))
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return out, nil
+}
+
+// compileQuery turns the query into a condition on the flashcard f, values are passed through arg only
+func compileQuery(n card_query.Node, arg func(v any) string, now time.Time) (string, error) {
+	switch n := n.(type) {
+	case *card_query.And:
+		l, err := compileQuery(n.Left, arg, now)
+		if err != nil {
+			return "", err
+		}
+		r, err := compileQuery(n.Right, arg, now)
+		if err != nil {
+			return "", err
+		}
+		return "(" + l + " AND " + r + ")", nil
+	case *card_query.Or:
+		l, err := compileQuery(n.Left, arg, now)
+		if err != nil {
+			return "", err
+		}
+		r, err := compileQuery(n.Right, arg, now)
+		if err != nil {
+			return "", err
+		}
+		return "(" + l + " OR " + r + ")", nil
+	case *card_query.Not:
+		x, err := compileQuery(n.X, arg, now)
+		if err != nil {
+			return "", err
+		}
+		return "NOT " + x, nil
+	case *card_query.Cond:
+		return compileCond(n, arg, now)
+	}
+
+	return "", fmt.Errorf("%w: unexpected node %T", card_query.ErrInvalidQuery, n)
+}
+
+func compileCond(c *card_query.Cond, arg func(v any) string, now time.Time) (string, error) {
+	cmp, ok := sqlOps[c.Op]
+	if !ok && c.Op != "~" {
+		return "", fmt.Errorf("%w: unknown operator %q", card_query.ErrInvalidQuery, c.Op)
+	}
+
+	hasTag := func(v any) string {
+		cond := "EXISTS (SELECT 1 FROM flashcard_tags t WHERE t.flashcard_id = f.id AND t.tag = " + arg(v) + ")"
+		if c.Op == "!=" {
+			return "NOT " + cond
+		}
+		return cond
+	}
+
+	switch c.Field {
+	case "lang":
+		return "f.lang_id " + cmp + " " + arg(c.Int), nil
+	case "tag":
+		return hasTag(entities.NormalizeTag(c.Value)), nil
+	case "pos":
+		return hasTag(entities.PosTag(c.Value)), nil
+	case "session":
+		return hasTag(entities.SessionTag(c.Id)), nil
+	case "level":
+		if c.Op == "=" || c.Op == "!=" {
+			return hasTag(entities.LevelTag(c.Value)), nil
+		}
+		return "EXISTS (SELECT 1 FROM flashcard_tags t WHERE t.flashcard_id = f.id AND t.tag = ANY(" +
+			arg(levelTags(c.Op, c.Value)) + "))", nil
+	case "deck":
+		cond := "EXISTS (SELECT 1 FROM decks_flashcards df WHERE df.flashcard_id = f.id AND df.deck_id = " + arg(c.Id) + ")"
+		if c.Op == "!=" {
+			return "NOT " + cond, nil
+		}
+		return cond, nil
+	case "word":
+		if c.Op == "~" {
+			return "f.word ILIKE " + arg("%"+escapeLike(c.Value)+"%"), nil
+		}
+		return "lower(f.word) " + cmp + " lower(" + arg(c.Value) + ")", nil
+	case "misses", "reviews":
+		count := "SELECT count(*) FROM flashcard_reviews r WHERE r.flashcard_id = f.id"
+		if c.Field == "misses" {
+			count += " AND NOT r.correct"
+		}
+		if c.Window > 0 {
+			count += " AND r.reviewed_at >= " + arg(now.Add(-c.Window))
+		}
+		return "(" + count + ") " + cmp + " " + arg(c.Int), nil
+	case "age":
+		// a younger card was created after the cutoff, so the comparison flips
+		flipped := map[string]string{"<": ">", "<=": ">=", ">": "<", ">=": "<="}[c.Op]
+		return "f.created_at " + flipped + " " + arg(now.Add(-c.Dur)), nil
+	}
+
+	return "", fmt.Errorf("%w: unknown field %q", card_query.ErrInvalidQuery, c.Field)
+}
+
+// levelTags returns the level tags satisfying the comparison with level
+func levelTags(op, level string) []string {
+	at := slices.Index(card_query.Levels, strings.ToUpper(level))
+
+	out := []string{}
+	for i, l := range card_query.Levels {
+		var ok bool
+		switch op {
+		case "<":
+			ok = i < at
+		case "<=":
+			ok = i <= at
+		case ">":
+			ok = i > at
+		case ">=":
+			ok = i >= at
+		}
+		if ok {
+			out = append(out, entities.LevelTag(l))
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,128 @@
+package postgresql
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rwrrioe/pythia/backend/internal/lib/card_query"
+)
+
+func TestCompileQuery(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		query    string
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			name:     "word equality",
+			query:    `word = Haus`,
+			wantSQL:  `lower(f.word) = lower($1)`,
+			wantArgs: []any{"Haus"},
+		},
+		{
+			name:     "quotes stay in the value",
+			query:    `word = "x'); DROP TABLE flashcards; --"`,
+			wantSQL:  `lower(f.word) = lower($1)`,
+			wantArgs: []any{"x'); DROP TABLE flashcards; --"},
+		},
+		{
+			name:     "like wildcards are escaped",
+			query:    `word ~ "50%_off"`,
+			wantSQL:  `f.word ILIKE $1`,
+			wantArgs: []any{`%50\%\_off%`},
+		},
+		{
+			name:     "tag with an injected condition",
+			query:    `tag = "a' OR '1'='1"`,
+			wantSQL:  `EXISTS (SELECT 1 FROM flashcard_tags t WHERE t.flashcard_id = f.id AND t.tag = $1)`,
+			wantArgs: []any{"a' or '1'='1"},
+		},
+		{
+			name:     "tag is normalized like user tags",
+			query:    "tag = \"  Cafe\u0301   Food \"",
+			wantSQL:  `EXISTS (SELECT 1 FROM flashcard_tags t WHERE t.flashcard_id = f.id AND t.tag = $1)`,
+			wantArgs: []any{"caf\u00e9 food"},
+		},
+		{
+			name:  "negated or",
+			query: `not (tag = kitchen or pos = noun)`,
+			wantSQL: `NOT (EXISTS (SELECT 1 FROM flashcard_tags t WHERE t.flashcard_id = f.id AND t.tag = $1)` +
+				` OR EXISTS (SELECT 1 FROM flashcard_tags t WHERE t.flashcard_id = f.id AND t.tag = $2))`,
+			wantArgs: []any{"kitchen", "pos:noun"},
+		},
+		{
+			name:     "level range",
+			query:    `level < b1`,
+			wantSQL:  `EXISTS (SELECT 1 FROM flashcard_tags t WHERE t.flashcard_id = f.id AND t.tag = ANY($1))`,
+			wantArgs: []any{[]string{"level:a1", "level:a2"}},
+		},
+		{
+			name:     "misses in a window",
+			query:    `misses[7d] >= 2`,
+			wantSQL:  `(SELECT count(*) FROM flashcard_reviews r WHERE r.flashcard_id = f.id AND NOT r.correct AND r.reviewed_at >= $1) >= $2`,
+			wantArgs: []any{now.Add(-7 * 24 * time.Hour), 2},
+		},
+		{
+			name:     "age flips the comparison",
+			query:    `age < 30d`,
+			wantSQL:  `f.created_at > $1`,
+			wantArgs: []any{now.Add(-30 * 24 * time.Hour)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := card_query.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("parse %q: %v", tt.query, err)
+			}
+
+			var args []any
+			arg := func(v any) string {
+				args = append(args, v)
+				return fmt.Sprintf("$%d", len(args))
+			}
+
+			sql, err := compileQuery(n, arg, now)
+			if err != nil {
+				t.Fatalf("compile %q: %v", tt.query, err)
+			}
+
+			if sql != tt.wantSQL {
+				t.Errorf("sql\n got: %s\nwant: %s", sql, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args\n got: %#v\nwant: %#v", args, tt.wantArgs)
+			}
+			if strings.Contains(sql, "'") {
+				t.Errorf("sql %q holds a literal", sql)
+			}
+		})
+	}
+}
+
+func TestCompileQueryRejectsUnknownNodes(t *testing.T) {
+	tests := []struct {
+		name string
+		node card_query.Node
+	}{
+		{"unknown field", &card_query.Cond{Field: "f.id = f.id --", Op: "="}},
+		{"unknown operator", &card_query.Cond{Field: "word", Op: "= 1 OR 1 ="}},
+		{"nil node", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compileQuery(tt.node, func(v any) string { return "$1" }, time.Now())
+			if err == nil {
+				t.Fatal("want an error")
+			}
+		})
+	}
+}
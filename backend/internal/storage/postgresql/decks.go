@@ -26,16 +26,58 @@ func (s *DeckStorage) DeckPool() *pgxpool.Pool {
 	return s.pool
 }
 
+const deckCols = `
+    d.id, d.kind, d.session_id, COALESCE(d.name, s.name, ''), d.description, COALESCE(d.lang_id, s.lang_id, 0),
+    d.position, d.created_at, (SELECT count(*) FROM decks_flashcards df WHERE df.deck_id = d.id)
+`
+
+// decksFrom joins the session, a session deck is named after it and shares its language
+const decksFrom = `
+    FROM decks d
+    LEFT JOIN sessions s ON s.id = d.session_id
+`
+
+func scanDeck(row pgx.Row) (entities.Deck, error) {
+	var (
+		m     models.Deck
+		count int
+	)
+	err := row.Scan(
+		&m.Id,
+		&m.Kind,
+		&m.SessionId,
+		&m.Name,
+		&m.Description,
+		&m.Lang,
+		&m.Position,
+		&m.CreatedAt,
+		&count,
+	)
+	if err != nil {
+		return entities.Deck{}, err
+	}
+
+	return entities.Deck{
+		Id:          m.Id,
+		Kind:        m.Kind,
+		SessionId:   m.SessionId,
+		Name:        m.Name,
+		Description: m.Description,
+		Language:    m.Lang,
+		Position:    m.Position,
+		CreatedAt:   m.CreatedAt,
+		CardsCount:  count,
+	}, nil
+}
+
 func (s *DeckStorage) ListBySession(ctx context.Context, q Querier, sessionId uuid.UUID, uid int64) (*entities.Deck, error) {
 	const op = "postgresql.DeckStorage.ListBySession"
 
-	var d models.Deck
-	err := q.QueryRow(ctx,
-		`SELECT id, user_id, session_id
-         FROM decks
-         WHERE user_id=$1 AND session_id=$2`,
+	d, err := scanDeck(q.QueryRow(ctx,
+		`SELECT `+deckCols+decksFrom+`
+         WHERE d.user_id=$1 AND d.session_id=$2`,
 		uid, sessionId,
-	).Scan(&d.Id, &d.UserId, &d.SessionId)
+	))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrDeckNotFound
@@ -43,10 +85,7 @@ func (s *DeckStorage) ListBySession(ctx context.Context, q Querier, sessionId uu
 		return nil, fmt.Errorf("%s:%w", op, err)
 	}
 
-	return &entities.Deck{
-		Id:        d.Id,
-		SessionId: d.SessionId,
-	}, nil
+	return &d, nil
 }
 
 func (s *DeckStorage) GetOrCreate(ctx context.Context, q Querier, sessionId uuid.UUID, uid int64) (uuid.UUID, error) {
@@ -55,8 +94,8 @@ func (s *DeckStorage) GetOrCreate(ctx context.Context, q Querier, sessionId uuid
 	var id uuid.UUID
 
 	err := q.QueryRow(ctx,
-		`INSERT INTO decks (user_id, session_id)
-         VALUES ($1, $2)
+		`INSERT INTO decks (user_id, session_id, kind)
+         VALUES ($1, $2, 'session')
          ON CONFLICT (user_id, session_id) DO UPDATE SET session_id=EXCLUDED.session_id
         RETURNING id
          `,
@@ -88,3 +127,229 @@ func (s *DeckStorage) AttachFlashcard(ctx context.Context, q Querier, deckId uui
 	}
 	return nil
 }
+
+func (s *DeckStorage) DeckExists(ctx context.Context, q Querier, deckId uuid.UUID, uid int64) (bool, error) {
+	const op = "postgresql.DeckStorage.DeckExists"
+
+	var ok bool
+	err := q.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM decks WHERE id=$1 AND user_id=$2)`,
+		deckId, uid,
+	).Scan(&ok)
+	if err != nil {
+		return false, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return ok, nil
+}
+
+// CopyFlashcards appends the user's flashcards to the deck in the given order.
+// Cards already in it and cards of another language than the deck are skipped, it returns the number of cards added.
+func (s *DeckStorage) CopyFlashcards(ctx context.Context, q Querier, deckId uuid.UUID, flashcardIds []uuid.UUID, uid int64) (int64, error) {
+	const op = "postgresql.DeckStorage.CopyFlashcards"
+
+	cmd, err := q.Exec(ctx,
+		`INSERT INTO decks_flashcards (deck_id, flashcard_id, position)
+         SELECT d.id, f.id,
+                COALESCE((SELECT max(df.position) FROM decks_flashcards df WHERE df.deck_id = d.id), -1)
+                    + row_number() OVER (ORDER BY o.ord)
+         FROM unnest($2::uuid[]) WITH ORDINALITY AS o(id, ord)
+         JOIN flashcards f ON f.id = o.id AND f.user_id = $3
+         JOIN decks d ON d.id = $1
+         WHERE d.lang_id IS NULL OR d.lang_id = f.lang_id
+         ON CONFLICT (deck_id, flashcard_id) DO NOTHING`,
+		deckId, flashcardIds, uid,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return cmd.RowsAffected(), nil
+}
+
+// DetachFlashcards removes the flashcards from the deck, the cards themselves are kept.
+// It returns the number of cards removed.
+func (s *DeckStorage) DetachFlashcards(ctx context.Context, q Querier, deckId uuid.UUID, flashcardIds []uuid.UUID) (int64, error) {
+	const op = "postgresql.DeckStorage.DetachFlashcards"
+
+	cmd, err := q.Exec(ctx,
+		`DELETE FROM decks_flashcards
+         WHERE deck_id = $1 AND flashcard_id = ANY($2)`,
+		deckId, flashcardIds,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return cmd.RowsAffected(), nil
+}
+
+// InDeck returns which of the flashcards are in the deck
+func (s *DeckStorage) InDeck(ctx context.Context, q Querier, deckId uuid.UUID, flashcardIds []uuid.UUID) ([]uuid.UUID, error) {
+	const op = "postgresql.DeckStorage.InDeck"
+
+	rows, err := q.Query(ctx,
+		`SELECT flashcard_id
+         FROM decks_flashcards
+         WHERE deck_id = $1 AND flashcard_id = ANY($2)`,
+		deckId, flashcardIds,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return ids, nil
+}
+
+// ListDecks lists the user decks in their order followed by the session decks, newest first.
+// An empty kind lists both.
+func (s *DeckStorage) ListDecks(ctx context.Context, q Querier, uid int64, kind string) ([]entities.Deck, error) {
+	const op = "postgresql.DeckStorage.ListDecks"
+
+	rows, err := q.Query(ctx,
+		`SELECT `+deckCols+decksFrom+`
+         WHERE d.user_id = $1 AND ($2 = '' OR d.kind = $2)
+         ORDER BY (d.kind = 'user') DESC, d.position, d.created_at DESC`,
+		uid, kind,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	defer rows.Close()
+
+	out := make([]entities.Deck, 0, 16)
+	for rows.Next() {
+		d, err := scanDeck(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+		out = append(out, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return out, nil
+}
+
+func (s *DeckStorage) GetDeck(ctx context.Context, q Querier, deckId uuid.UUID, uid int64) (*entities.Deck, error) {
+	const op = "postgresql.DeckStorage.GetDeck"
+
+	d, err := scanDeck(q.QueryRow(ctx,
+		`SELECT `+deckCols+decksFrom+`
+         WHERE d.id = $1 AND d.user_id = $2`,
+		deckId, uid,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrDeckNotFound
+		}
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return &d, nil
+}
+
+// CreateDeck adds a user deck after the existing ones
+func (s *DeckStorage) CreateDeck(ctx context.Context, q Querier, deck entities.Deck, uid int64) (uuid.UUID, error) {
+	const op = "postgresql.DeckStorage.CreateDeck"
+
+	var id uuid.UUID
+	err := q.QueryRow(ctx,
+		`INSERT INTO decks (user_id, kind, name, description, lang_id, position)
+         VALUES ($1, 'user', $2, $3, NULLIF($4, 0),
+                 (SELECT COALESCE(max(position), -1) + 1 FROM decks WHERE user_id = $1 AND kind = 'user'))
+         RETURNING id`,
+		uid, deck.Name, deck.Description, deck.Language,
+	).Scan(&id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return id, nil
+}
+
+// UpdateDeck rewrites the name, description and language of a user deck
+func (s *DeckStorage) UpdateDeck(ctx context.Context, q Querier, deck entities.Deck, uid int64) error {
+	const op = "postgresql.DeckStorage.UpdateDeck"
+
+	cmd, err := q.Exec(ctx,
+		`UPDATE decks
+         SET name = $1,
+             description = $2,
+             lang_id = NULLIF($3, 0)
+         WHERE id = $4 AND user_id = $5 AND kind = 'user'`,
+		deck.Name, deck.Description, deck.Language, deck.Id, uid,
+	)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrDeckNotFound
+	}
+
+	return nil
+}
+
+// DeleteDeck removes a user deck, its flashcards stay in the vocabulary
+func (s *DeckStorage) DeleteDeck(ctx context.Context, q Querier, deckId uuid.UUID, uid int64) error {
+	const op = "postgresql.DeckStorage.DeleteDeck"
+
+	cmd, err := q.Exec(ctx,
+		`DELETE FROM decks
+         WHERE id = $1 AND user_id = $2 AND kind = 'user'`,
+		deckId, uid,
+	)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrDeckNotFound
+	}
+
+	return nil
+}
+
+// ReorderDecks places the user decks in the given order, decks left out keep their position.
+// It returns the number of decks reordered.
+func (s *DeckStorage) ReorderDecks(ctx context.Context, q Querier, deckIds []uuid.UUID, uid int64) (int64, error) {
+	const op = "postgresql.DeckStorage.ReorderDecks"
+
+	cmd, err := q.Exec(ctx,
+		`UPDATE decks d
+         SET position = o.ord - 1
+         FROM unnest($1::uuid[]) WITH ORDINALITY AS o(id, ord)
+         WHERE d.id = o.id AND d.user_id = $2 AND d.kind = 'user'`,
+		deckIds, uid,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return cmd.RowsAffected(), nil
+}
+
+// ReorderFlashcards places the cards of the deck in the given order, cards left out keep their position.
+// It returns the number of cards reordered.
+func (s *DeckStorage) ReorderFlashcards(ctx context.Context, q Querier, deckId uuid.UUID, flashcardIds []uuid.UUID) (int64, error) {
+	const op = "postgresql.DeckStorage.ReorderFlashcards"
+
+	cmd, err := q.Exec(ctx,
+		`UPDATE decks_flashcards df
+         SET position = o.ord - 1
+         FROM unnest($2::uuid[]) WITH ORDINALITY AS o(id, ord)
+         WHERE df.deck_id = $1 AND df.flashcard_id = o.id`,
+		deckId, flashcardIds,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return cmd.RowsAffected(), nil
+}
@@ -0,0 +1,233 @@
+package postgresql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
+	"github.com/rwrrioe/pythia/backend/internal/storage/models"
+)
+
+type DocumentStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewDocumentStorage(pool *pgxpool.Pool) *DocumentStorage {
+	return &DocumentStorage{pool: pool}
+}
+
+// SaveDocument upserts the document of the task together with its pages, words of existing pages are kept
+// and the pages missing from doc are removed.
+// Run it in a transaction, a document must not be left with part of its pages.
+func (s *DocumentStorage) SaveDocument(ctx context.Context, q Querier, doc entities.Document) (uuid.UUID, error) {
+	const op = "postgresql.DocumentStorage.SaveDocument"
+
+	var id uuid.UUID
+	err := q.QueryRow(ctx, `
+		INSERT INTO documents (session_id, task_id, lang_id, detected_lang)
+		VALUES ($1, $2, NULLIF($3, 0), NULLIF($4, ''))
+		ON CONFLICT (session_id, task_id)
+		DO UPDATE SET lang_id = EXCLUDED.lang_id, detected_lang = EXCLUDED.detected_lang
+		RETURNING id
+	`, doc.SessionId, doc.TaskId, doc.Language, doc.DetectedLang).Scan(&id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	for _, p := range doc.Pages {
+		lines, err := json.Marshal(p.Lines)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("%s:%w", op, err)
+		}
+
+		_, err = q.Exec(ctx, `
+			INSERT INTO document_pages (document_id, page, text, lines, image_hash)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+			ON CONFLICT (document_id, page)
+			DO UPDATE SET text = EXCLUDED.text, lines = EXCLUDED.lines, image_hash = EXCLUDED.image_hash
+		`, id, p.Page, strings.Join(p.Text, "\n"), lines, p.ImageHash)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("%s:%w", op, err)
+		}
+	}
+
+	// an upload again with fewer pages leaves none of the old ones behind
+	pages := make([]int, 0, len(doc.Pages))
+	for _, p := range doc.Pages {
+		pages = append(pages, p.Page)
+	}
+	_, err = q.Exec(ctx, `
+		DELETE FROM document_pages
+		WHERE document_id = $1 AND NOT (page = ANY($2::int[]))
+	`, id, pages)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return id, nil
+}
+
+// SaveWords stores the extracted words per page of the task document, pages not stored are skipped
+func (s *DocumentStorage) SaveWords(ctx context.Context, q Querier, sessionId uuid.UUID, taskId string, words map[int][]entities.Word) error {
+	const op = "postgresql.DocumentStorage.SaveWords"
+
+	for page, ws := range words {
+		raw, err := json.Marshal(ws)
+		if err != nil {
+			return fmt.Errorf("%s:%w", op, err)
+		}
+
+		// tasks recognized before documents were stored have no pages, their words live in the task only
+		_, err = q.Exec(ctx, `
+			UPDATE document_pages dp
+			SET words = $1
+			FROM documents d
+			WHERE dp.document_id = d.id
+				AND d.session_id = $2
+				AND d.task_id = $3
+				AND dp.page = $4
+		`, raw, sessionId, taskId, page)
+		if err != nil {
+			return fmt.Errorf("%s:%w", op, err)
+		}
+	}
+
+	return nil
+}
+
+// ListBySession returns the documents of the session without their pages
+func (s *DocumentStorage) ListBySession(ctx context.Context, q Querier, sessionId uuid.UUID, uid int64) ([]entities.Document, error) {
+	const op = "postgresql.DocumentStorage.ListBySession"
+
+	rows, err := q.Query(ctx, `
+		SELECT d.id, d.session_id, d.task_id, COALESCE(d.lang_id, 0), COALESCE(d.detected_lang, ''), d.created_at,
+			(SELECT count(*) FROM document_pages dp WHERE dp.document_id = d.id)
+		FROM documents d
+		JOIN sessions s ON s.id = d.session_id
+		WHERE d.session_id = $1 AND s.user_id = $2
+		ORDER BY d.created_at
+	`, sessionId, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	defer rows.Close()
+
+	out := make([]entities.Document, 0, 4)
+	for rows.Next() {
+		var (
+			m     models.Document
+			pages int
+		)
+		if err := rows.Scan(&m.Id, &m.SessionId, &m.TaskId, &m.Lang, &m.DetectedLang, &m.CreatedAt, &pages); err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+
+		doc := toDocument(m)
+		doc.PagesCount = pages
+		out = append(out, doc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return out, nil
+}
+
+// GetDocument returns the document with all its pages
+func (s *DocumentStorage) GetDocument(ctx context.Context, q Querier, sessionId, documentId uuid.UUID, uid int64) (*entities.Document, error) {
+	const op = "postgresql.DocumentStorage.GetDocument"
+
+	var m models.Document
+	err := q.QueryRow(ctx, `
+		SELECT d.id, d.session_id, d.task_id, COALESCE(d.lang_id, 0), COALESCE(d.detected_lang, ''), d.created_at
+		FROM documents d
+		JOIN sessions s ON s.id = d.session_id
+		WHERE d.id = $1 AND d.session_id = $2 AND s.user_id = $3
+	`, documentId, sessionId, uid).Scan(&m.Id, &m.SessionId, &m.TaskId, &m.Lang, &m.DetectedLang, &m.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrDocumentNotFound
+		}
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	rows, err := q.Query(ctx, `
+		SELECT document_id, page, text, lines, COALESCE(image_hash, ''), words
+		FROM document_pages
+		WHERE document_id = $1
+		ORDER BY page
+	`, documentId)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	defer rows.Close()
+
+	doc := toDocument(m)
+	for rows.Next() {
+		var p models.DocumentPage
+		if err := rows.Scan(&p.DocumentId, &p.Page, &p.Text, &p.Lines, &p.ImageHash, &p.Words); err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+
+		page := entities.DocumentPage{
+			Page:      p.Page,
+			ImageHash: p.ImageHash,
+		}
+		if p.Text != "" {
+			page.Text = strings.Split(p.Text, "\n")
+		}
+		if err := json.Unmarshal(p.Lines, &page.Lines); err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+		if err := json.Unmarshal(p.Words, &page.Words); err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+
+		doc.Pages = append(doc.Pages, page)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	doc.PagesCount = len(doc.Pages)
+	return &doc, nil
+}
+
+// SessionText returns the recognized text of the session documents in upload order, cut to limit characters
+func (s *DocumentStorage) SessionText(ctx context.Context, q Querier, sessionId uuid.UUID, uid int64, limit int) (string, error) {
+	const op = "postgresql.DocumentStorage.SessionText"
+
+	var text string
+	err := q.QueryRow(ctx, `
+		SELECT COALESCE(left(string_agg(dp.text, E'\n' ORDER BY d.created_at, dp.page), $3), '')
+		FROM documents d
+		JOIN sessions s ON s.id = d.session_id
+		JOIN document_pages dp ON dp.document_id = d.id
+		WHERE d.session_id = $1 AND s.user_id = $2
+	`, sessionId, uid, limit).Scan(&text)
+	if err != nil {
+		return "", fmt.Errorf("%s:%w", op, err)
+	}
+
+	return text, nil
+}
+
+func toDocument(m models.Document) entities.Document {
+	return entities.Document{
+		Id:           m.Id,
+		SessionId:    m.SessionId,
+		TaskId:       m.TaskId,
+		Language:     m.Lang,
+		DetectedLang: m.DetectedLang,
+		CreatedAt:    m.CreatedAt,
+	}
+}
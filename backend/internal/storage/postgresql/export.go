@@ -0,0 +1,160 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
+	"github.com/rwrrioe/pythia/backend/internal/lib/card_query"
+	"github.com/rwrrioe/pythia/backend/internal/storage/models"
+)
+
+// ListReviews returns the recorded answers of the user's flashcards by card, oldest first
+func (s *FlashCardStorage) ListReviews(ctx context.Context, q Querier, flashcardIds []uuid.UUID, uid int64) (map[uuid.UUID][]entities.Review, error) {
+	const op = "postgresql.FlashCardStorage.ListReviews"
+
+	rows, err := q.Query(ctx, `
+		SELECT r.flashcard_id, r.session_id, r.correct, r.reviewed_at
+		FROM flashcard_reviews r
+		JOIN flashcards f ON f.id = r.flashcard_id
+		WHERE r.flashcard_id = ANY($1) AND f.user_id = $2
+		ORDER BY r.reviewed_at, r.id
+	`, flashcardIds, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	defer rows.Close()
+
+	out := make(map[uuid.UUID][]entities.Review, len(flashcardIds))
+	for rows.Next() {
+		var r entities.Review
+		if err := rows.Scan(&r.FlashcardId, &r.SessionId, &r.Correct, &r.ReviewedAt); err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+		out[r.FlashcardId] = append(out[r.FlashcardId], r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return out, nil
+}
+
+// Sources returns the name of the first session each flashcard was found in.
+// Cards added by hand have no source and are left out.
+func (s *FlashCardStorage) Sources(ctx context.Context, q Querier, flashcardIds []uuid.UUID, uid int64) (map[uuid.UUID]string, error) {
+	const op = "postgresql.FlashCardStorage.Sources"
+
+	rows, err := q.Query(ctx, `
+		SELECT DISTINCT ON (df.flashcard_id) df.flashcard_id, s.name
+		FROM decks_flashcards df
+		JOIN decks d ON d.id = df.deck_id
+		JOIN sessions s ON s.id = d.session_id
+		WHERE df.flashcard_id = ANY($1) AND d.user_id = $2 AND s.name <> ''
+		ORDER BY df.flashcard_id, s.started_at NULLS LAST
+	`, flashcardIds, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	defer rows.Close()
+
+	out := make(map[uuid.UUID]string, len(flashcardIds))
+	for rows.Next() {
+		var (
+			id   uuid.UUID
+			name string
+		)
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+		out[id] = name
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return out, nil
+}
+
+// StreamCards calls fn with every user flashcard matching the query and the session it was first found in,
+// a nil query matches the whole vocabulary. The cards come grouped by session in the order the sessions
+// were started, the cards of no session last, and by word within a group. Rows are read as fn consumes
+// them, an error from fn stops the iteration and is returned as is.
+func (s *FlashCardStorage) StreamCards(
+	ctx context.Context,
+	q Querier,
+	uid int64,
+	query card_query.Node,
+	now time.Time,
+	fn func(fl entities.FlashCard, src entities.CardSource) error,
+) error {
+	const op = "postgresql.FlashCardStorage.StreamCards"
+
+	args := []any{uid}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	where := "TRUE"
+	if query != nil {
+		var err error
+		if where, err = compileQuery(query, arg, now); err != nil {
+			return fmt.Errorf("%s:%w", op, err)
+		}
+	}
+
+	rows, err := q.Query(ctx, `SELECT `+flashcardCols+`, src.id, src.name, src.started_at
+		FROM flashcards f
+		LEFT JOIN LATERAL (
+			SELECT s.id, COALESCE(s.name, '') AS name, s.started_at
+			FROM decks_flashcards df
+			JOIN decks d ON d.id = df.deck_id
+			JOIN sessions s ON s.id = d.session_id
+			WHERE df.flashcard_id = f.id AND d.user_id = f.user_id
+			ORDER BY s.started_at NULLS LAST, s.id
+			LIMIT 1
+		) src ON TRUE
+		WHERE f.user_id = $1 AND `+where+`
+		ORDER BY src.started_at NULLS LAST, src.id NULLS LAST, lower(f.word), f.id`, args...)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			m         models.FlashCard
+			sessionId *uuid.UUID
+			name      *string
+			startedAt *time.Time
+		)
+		err := rows.Scan(&m.Id, &m.Word, &m.Transl, &m.Lang, &m.Desc, &m.Example, &m.Tags, &sessionId, &name, &startedAt)
+		if err != nil {
+			return fmt.Errorf("%s:%w", op, err)
+		}
+
+		var src entities.CardSource
+		if sessionId != nil {
+			src.SessionId = *sessionId
+			src.Name = *name
+		}
+		if startedAt != nil {
+			src.StartedAt = *startedAt
+		}
+
+		if err := fn(toFlashcard(m), src); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	return nil
+}
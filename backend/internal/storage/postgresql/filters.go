@@ -0,0 +1,137 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
+)
+
+// FilterStorage keeps the saved smart filters, the query is stored as written and parsed on use
+type FilterStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewFilterStorage(pool *pgxpool.Pool) *FilterStorage {
+	return &FilterStorage{pool: pool}
+}
+
+const filterCols = `id, name, query, created_at, updated_at`
+
+func scanFilter(row pgx.Row, f *entities.SmartFilter) error {
+	return row.Scan(&f.Id, &f.Name, &f.Query, &f.CreatedAt, &f.UpdatedAt)
+}
+
+func (s *FilterStorage) ListFilters(ctx context.Context, q Querier, uid int64) ([]entities.SmartFilter, error) {
+	const op = "postgresql.FilterStorage.ListFilters"
+
+	rows, err := q.Query(ctx, `
+		SELECT `+filterCols+`
+		FROM smart_filters
+		WHERE user_id = $1
+		ORDER BY name
+	`, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	defer rows.Close()
+
+	out := make([]entities.SmartFilter, 0, 8)
+	for rows.Next() {
+		var f entities.SmartFilter
+		if err := scanFilter(rows, &f); err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+		out = append(out, f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return out, nil
+}
+
+func (s *FilterStorage) GetFilter(ctx context.Context, q Querier, id uuid.UUID, uid int64) (*entities.SmartFilter, error) {
+	const op = "postgresql.FilterStorage.GetFilter"
+
+	var f entities.SmartFilter
+	err := scanFilter(q.QueryRow(ctx, `
+		SELECT `+filterCols+`
+		FROM smart_filters
+		WHERE id = $1 AND user_id = $2
+	`, id, uid), &f)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrFilterNotFound
+		}
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return &f, nil
+}
+
+func (s *FilterStorage) CreateFilter(ctx context.Context, q Querier, name, query string, uid int64) (*entities.SmartFilter, error) {
+	const op = "postgresql.FilterStorage.CreateFilter"
+
+	var f entities.SmartFilter
+	err := scanFilter(q.QueryRow(ctx, `
+		INSERT INTO smart_filters (user_id, name, query)
+		VALUES ($1, $2, $3)
+		RETURNING `+filterCols,
+		uid, name, query), &f)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("%s:%w", op, ErrFilterAlreadyExists)
+		}
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return &f, nil
+}
+
+func (s *FilterStorage) UpdateFilter(ctx context.Context, q Querier, f entities.SmartFilter, uid int64) (*entities.SmartFilter, error) {
+	const op = "postgresql.FilterStorage.UpdateFilter"
+
+	var out entities.SmartFilter
+	err := scanFilter(q.QueryRow(ctx, `
+		UPDATE smart_filters
+		SET name = $1,
+		    query = $2,
+		    updated_at = now()
+		WHERE id = $3 AND user_id = $4
+		RETURNING `+filterCols,
+		f.Name, f.Query, f.Id, uid), &out)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil, ErrFilterNotFound
+		case isUniqueViolation(err):
+			return nil, fmt.Errorf("%s:%w", op, ErrFilterAlreadyExists)
+		}
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return &out, nil
+}
+
+func (s *FilterStorage) DeleteFilter(ctx context.Context, q Querier, id uuid.UUID, uid int64) error {
+	const op = "postgresql.FilterStorage.DeleteFilter"
+
+	cmd, err := q.Exec(ctx, `
+		DELETE FROM smart_filters
+		WHERE id = $1 AND user_id = $2
+	`, id, uid)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrFilterNotFound
+	}
+
+	return nil
+}
@@ -2,11 +2,15 @@ package postgresql
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/text/unicode/norm"
 
 	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
 	"github.com/rwrrioe/pythia/backend/internal/storage/models"
@@ -20,15 +24,48 @@ func NewFlashcardStorage(pool *pgxpool.Pool) *FlashCardStorage {
 	return &FlashCardStorage{pool: pool}
 }
 
+// flashcardCols selects a flashcard aliased as f with its tags
+const flashcardCols = `f.id, f.word, f.transl, f.lang_id, f.description, f.example,
+	ARRAY(SELECT t.tag FROM flashcard_tags t WHERE t.flashcard_id = f.id ORDER BY t.tag)`
+
 func scanFlashcard(row pgx.Row, m *models.FlashCard) error {
 	return row.Scan(
 		&m.Id,
 		&m.Word,
 		&m.Transl,
 		&m.Lang,
+		&m.Desc,
+		&m.Example,
+		&m.Tags,
 	)
 }
 
+func toFlashcard(m models.FlashCard) entities.FlashCard {
+	return entities.FlashCard{
+		Id:      m.Id,
+		Word:    m.Word,
+		Transl:  m.Transl,
+		Lang:    m.Lang,
+		Desc:    m.Desc,
+		Example: m.Example,
+		Tags:    m.Tags,
+	}
+}
+
+// Cards written before it were brought to this form by migration 15, a change here needs another backfill.
+// NormalizeCard is applied to every flashcard written, a word is unique per user and language in this form
+func NormalizeCard(fl entities.FlashCard) entities.FlashCard {
+	fl.Word = normalizeText(fl.Word)
+	fl.Transl = normalizeText(fl.Transl)
+	fl.Desc = strings.TrimSpace(norm.NFC.String(fl.Desc))
+	fl.Example = strings.TrimSpace(norm.NFC.String(fl.Example))
+	return fl
+}
+
+func normalizeText(s string) string {
+	return strings.Join(strings.Fields(norm.NFC.String(s)), " ")
+}
+
 func (s *FlashCardStorage) FlashcardsPool() *pgxpool.Pool {
 	return s.pool
 }
@@ -38,11 +75,11 @@ func (s *FlashCardStorage) ListByDeck(ctx context.Context, q Querier, deckId uui
 	const op = "postgresql.FlashCardStorage.ListByDeck"
 
 	rows, err := q.Query(ctx,
-		`SELECT f.id, f.word, f.transl, f.lang_id
-         FROM decks_flashcards df 
+		`SELECT `+flashcardCols+`
+         FROM decks_flashcards df
          JOIN flashcards f ON df.flashcard_id = f.id
          WHERE f.user_id=$1 AND df.deck_id=$2
-         ORDER BY f.id`,
+         ORDER BY df.position, df.added_at, f.id`,
 		uid, deckId,
 	)
 	if err != nil {
@@ -58,13 +95,7 @@ func (s *FlashCardStorage) ListByDeck(ctx context.Context, q Querier, deckId uui
 			return nil, fmt.Errorf("%s:%w", op, err)
 		}
 
-		out = append(out, entities.FlashCard{
-			Id:     m.Id,
-			Word:   m.Word,
-			Transl: m.Transl,
-			Lang:   m.Lang,
-			Desc:   "", // description нет в БД
-		})
+		out = append(out, toFlashcard(m))
 	}
 
 	if err := rows.Err(); err != nil {
@@ -79,10 +110,10 @@ func (s *FlashCardStorage) List(ctx context.Context, q Querier, uid int64) ([]en
 	const op = "postgresql.FlashCardStorage.List"
 
 	rows, err := q.Query(ctx,
-		`SELECT id, word, transl, lang_id
-         FROM flashcards
-         WHERE user_id=$1
-         ORDER BY id DESC`,
+		`SELECT `+flashcardCols+`
+         FROM flashcards f
+         WHERE f.user_id=$1
+         ORDER BY f.id DESC`,
 		uid,
 	)
 	if err != nil {
@@ -99,13 +130,7 @@ func (s *FlashCardStorage) List(ctx context.Context, q Querier, uid int64) ([]en
 			return nil, fmt.Errorf("%s:%w", op, err)
 		}
 
-		out = append(out, entities.FlashCard{
-			Id:     m.Id,
-			Word:   m.Word,
-			Transl: m.Transl,
-			Lang:   m.Lang,
-			Desc:   "",
-		})
+		out = append(out, toFlashcard(m))
 	}
 
 	if err := rows.Err(); err != nil {
@@ -119,14 +144,16 @@ func (s *FlashCardStorage) GetOrCreate(ctx context.Context, q Querier, flCard en
 	const op = "postgresql.FlashCardStorage.GetOrCreate"
 
 	var id uuid.UUID
+	flCard = NormalizeCard(flCard)
 
 	err := q.QueryRow(ctx, `
-		INSERT INTO flashcards (user_id, word, transl, lang_id)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO flashcards (user_id, word, transl, lang_id, example)
+		VALUES ($1, $2, $3, $4, $5)
 		ON CONFLICT (user_id, lang_id, word)
-		DO UPDATE SET transl = EXCLUDED.transl
+		DO UPDATE SET transl = EXCLUDED.transl,
+		              example = CASE WHEN EXCLUDED.example <> '' THEN EXCLUDED.example ELSE flashcards.example END
 		RETURNING id
-	`, uid, flCard.Word, flCard.Transl, flCard.Lang).Scan(&id)
+	`, uid, flCard.Word, flCard.Transl, flCard.Lang, flCard.Example).Scan(&id)
 
 	if err != nil {
 		return uuid.Nil, fmt.Errorf("%s:%w", op, err)
@@ -134,3 +161,95 @@ func (s *FlashCardStorage) GetOrCreate(ctx context.Context, q Querier, flCard en
 
 	return id, nil
 }
+
+func (s *FlashCardStorage) Get(ctx context.Context, q Querier, id uuid.UUID, uid int64) (*entities.FlashCard, error) {
+	const op = "postgresql.FlashCardStorage.Get"
+
+	var m models.FlashCard
+	err := scanFlashcard(q.QueryRow(ctx, `
+		SELECT `+flashcardCols+`
+		FROM flashcards f
+		WHERE f.id = $1 AND f.user_id = $2
+	`, id, uid), &m)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrFlashcardNotFound
+		}
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	fl := toFlashcard(m)
+	return &fl, nil
+}
+
+// Create adds a flashcard, unlike GetOrCreate an existing word is reported with ErrFlashcardAlreadyExists
+func (s *FlashCardStorage) Create(ctx context.Context, q Querier, flCard entities.FlashCard, uid int64) (uuid.UUID, error) {
+	const op = "postgresql.FlashCardStorage.Create"
+
+	var id uuid.UUID
+	flCard = NormalizeCard(flCard)
+
+	err := q.QueryRow(ctx, `
+		INSERT INTO flashcards (user_id, word, transl, lang_id, description, example)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, uid, flCard.Word, flCard.Transl, flCard.Lang, flCard.Desc, flCard.Example).Scan(&id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return uuid.Nil, fmt.Errorf("%s:%w", op, ErrFlashcardAlreadyExists)
+		}
+		return uuid.Nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return id, nil
+}
+
+// Update rewrites the word, translation and description of the flashcard
+func (s *FlashCardStorage) Update(ctx context.Context, q Querier, flCard entities.FlashCard, uid int64) error {
+	const op = "postgresql.FlashCardStorage.Update"
+
+	flCard = NormalizeCard(flCard)
+
+	cmd, err := q.Exec(ctx, `
+		UPDATE flashcards
+		SET word = $1,
+		    transl = $2,
+		    description = $3,
+		    example = $4
+		WHERE id = $5 AND user_id = $6
+	`, flCard.Word, flCard.Transl, flCard.Desc, flCard.Example, flCard.Id, uid)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%s:%w", op, ErrFlashcardAlreadyExists)
+		}
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrFlashcardNotFound
+	}
+
+	return nil
+}
+
+// Delete removes the flashcard from every deck
+func (s *FlashCardStorage) Delete(ctx context.Context, q Querier, id uuid.UUID, uid int64) error {
+	const op = "postgresql.FlashCardStorage.Delete"
+
+	cmd, err := q.Exec(ctx, `
+		DELETE FROM flashcards
+		WHERE id = $1 AND user_id = $2
+	`, id, uid)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrFlashcardNotFound
+	}
+
+	return nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -0,0 +1,41 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
+	"github.com/rwrrioe/pythia/backend/internal/storage/models"
+)
+
+// FindWords returns the user flashcards matching the (word, lang) pairs, words are compared as stored
+func (s *FlashCardStorage) FindWords(ctx context.Context, q Querier, words []string, langs []int, uid int64) ([]entities.FlashCard, error) {
+	const op = "postgresql.FlashCardStorage.FindWords"
+
+	rows, err := q.Query(ctx, `
+		SELECT `+flashcardCols+`
+		FROM flashcards f
+		JOIN unnest($1::text[], $2::int[]) AS k(word, lang_id)
+		  ON f.word = k.word AND f.lang_id = k.lang_id
+		WHERE f.user_id = $3
+	`, words, langs, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	defer rows.Close()
+
+	out := make([]entities.FlashCard, 0, len(words))
+	for rows.Next() {
+		var m models.FlashCard
+		if err := scanFlashcard(rows, &m); err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+		out = append(out, toFlashcard(m))
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,67 @@
+package postgresql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
+)
+
+type OCRCacheStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewOCRCacheStorage(pool *pgxpool.Pool) *OCRCacheStorage {
+	return &OCRCacheStorage{pool: pool}
+}
+
+func (s *OCRCacheStorage) Get(ctx context.Context, q Querier, hash string, lang string) (*entities.OCRResult, error) {
+	const op = "postgresql.OCRCacheStorage.Get"
+
+	var raw []byte
+	err := q.QueryRow(ctx,
+		`SELECT result
+         FROM ocr_cache
+         WHERE image_hash=$1 AND lang=$2`,
+		hash, lang,
+	).Scan(&raw)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOCRResultNotFound
+		}
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	var res entities.OCRResult
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return &res, nil
+}
+
+func (s *OCRCacheStorage) Save(ctx context.Context, q Querier, hash string, lang string, res *entities.OCRResult) error {
+	const op = "postgresql.OCRCacheStorage.Save"
+
+	raw, err := json.Marshal(res)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	_, err = q.Exec(ctx, `
+		INSERT INTO ocr_cache (image_hash, lang, result)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (image_hash, lang)
+		DO UPDATE SET result = EXCLUDED.result, created_at = now()
+	`, hash, lang, raw)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	return nil
+}
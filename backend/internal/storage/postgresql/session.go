@@ -23,8 +23,13 @@ func NewSessionStorage(pool *pgxpool.Pool) *SessionStorage {
 	return &SessionStorage{pool: pool}
 }
 
+type ExpiredSession struct {
+	Id     uuid.UUID
+	UserId int64
+}
+
 const sessionCols = `
-    id, name, user_id, status, lang_id, started_at, ended_at, accuracy
+    id, name, user_id, status, COALESCE(lang_id, 0), COALESCE(level_id, 0), started_at, ended_at, accuracy, duration, expires_at, archived_at
 `
 
 func scanSession(row pgx.Row, m *models.Session) error {
@@ -34,12 +39,36 @@ func scanSession(row pgx.Row, m *models.Session) error {
 		&m.UserId,
 		&m.Status,
 		&m.Lang,
+		&m.Level,
 		&m.StartedAt,
 		&m.EndedAt,
 		&m.Accuracy,
+		&m.Duration,
+		&m.ExpiresAt,
+		&m.ArchivedAt,
 	)
 }
 
+func toSession(m models.Session) entities.Session {
+	ss := entities.Session{
+		Id:         m.Id,
+		Name:       m.Name,
+		Status:     m.Status,
+		Language:   m.Lang,
+		Level:      m.Level,
+		StartedAt:  m.StartedAt,
+		EndedAt:    m.EndedAt,
+		Accuracy:   m.Accuracy,
+		Duration:   time.Duration(m.Duration) * time.Second,
+		ArchivedAt: m.ArchivedAt,
+	}
+	if m.ExpiresAt != nil {
+		ss.ExpiresAt = *m.ExpiresAt
+	}
+
+	return ss
+}
+
 func (s *SessionStorage) ListSessions(ctx context.Context, q Querier, uid int64) ([]entities.Session, error) {
 	const op = "postgresql.SessionStorage.ListSessions"
 
@@ -63,15 +92,7 @@ func (s *SessionStorage) ListSessions(ctx context.Context, q Querier, uid int64)
 			return nil, fmt.Errorf("%s:%w", op, err)
 		}
 
-		out = append(out, entities.Session{
-			Id:        m.Id,
-			Name:      m.Name,
-			Status:    m.Status,
-			Language:  m.Lang,
-			StartedAt: m.StartedAt,
-			EndedAt:   m.EndedAt,
-			Accuracy:  m.Accuracy,
-		})
+		out = append(out, toSession(m))
 	}
 
 	if rows.Err() != nil {
@@ -88,7 +109,8 @@ func (s *SessionStorage) ListLatest(ctx context.Context, q Querier, uid int64) (
 		`							SELECT `+sessionCols+`
 			 						FROM sessions 
 									WHERE user_id=$1 AND 
-									ended_at >= NOW() - INTERVAL '7 days' 
+									ended_at >= NOW() - INTERVAL '7 days' AND
+									archived_at IS NULL
 			 						ORDER BY ended_at 
     								DESC LIMIT 4
 			`, uid)
@@ -106,15 +128,7 @@ func (s *SessionStorage) ListLatest(ctx context.Context, q Querier, uid int64) (
 			return nil, fmt.Errorf("%s:%w", op, err)
 		}
 
-		out = append(out, entities.Session{
-			Id:        m.Id,
-			Name:      m.Name,
-			Status:    m.Status,
-			Language:  m.Lang,
-			StartedAt: m.StartedAt,
-			EndedAt:   m.EndedAt,
-			Accuracy:  m.Accuracy,
-		})
+		out = append(out, toSession(m))
 	}
 
 	if rows.Err() != nil {
@@ -141,16 +155,30 @@ func (s *SessionStorage) GetSession(ctx context.Context, q Querier, sessionId uu
 		return nil, fmt.Errorf("%s:%w", op, err)
 	}
 
-	ss := &entities.Session{
-		Id:        m.Id,
-		Name:      m.Name,
-		Status:    m.Status,
-		Language:  m.Lang,
-		StartedAt: m.StartedAt,
-		EndedAt:   m.EndedAt,
-		Accuracy:  m.Accuracy,
+	ss := toSession(m)
+	return &ss, nil
+}
+
+// FindSession looks the session up without knowing its owner, the owner id is returned along with it
+func (s *SessionStorage) FindSession(ctx context.Context, q Querier, sessionId uuid.UUID) (*entities.Session, int64, error) {
+	const op = "postgresql.SessionStorage.FindSession"
+
+	var m models.Session
+	err := scanSession(
+		q.QueryRow(ctx,
+			`SELECT `+sessionCols+`
+             FROM sessions
+             WHERE id=$1`, sessionId),
+		&m)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, 0, ErrSessionNotFound
+		}
+		return nil, 0, fmt.Errorf("%s:%w", op, err)
 	}
-	return ss, nil
+
+	ss := toSession(m)
+	return &ss, m.UserId, nil
 }
 
 func (s *SessionStorage) SaveSession(ctx context.Context, q Querier, ss entities.Session, uid int64) (uuid.UUID, error) {
@@ -158,11 +186,18 @@ func (s *SessionStorage) SaveSession(ctx context.Context, q Querier, ss entities
 
 	var id uuid.UUID
 	sql := `
-		INSERT INTO sessions (name, user_id, status, lang_id, started_at, ended_at, accuracy)
-		VALUES ($1, $2, $3, $4, $5,$6,$7)
+		INSERT INTO sessions (name, user_id, status, lang_id, started_at, ended_at, accuracy, duration, expires_at, level_id)
+		VALUES ($1, $2, $3, NULLIF($4, 0), $5,$6,$7,$8,$9, NULLIF($10, 0))
 		RETURNING id
 	`
 
+	// sessions without a duration never expire
+	var expiresAt *time.Time
+	if ss.Duration > 0 {
+		t := ss.StartedAt.Add(ss.Duration)
+		expiresAt = &t
+	}
+
 	err := q.QueryRow(ctx, sql,
 		ss.Name,
 		uid,
@@ -170,7 +205,10 @@ func (s *SessionStorage) SaveSession(ctx context.Context, q Querier, ss entities
 		ss.Language,
 		ss.StartedAt,
 		ss.EndedAt,
-		ss.Accuracy).Scan(&id)
+		ss.Accuracy,
+		int(ss.Duration/time.Second),
+		expiresAt,
+		ss.Level).Scan(&id)
 
 	if err != nil {
 		var pgErr *pgconn.PgError
@@ -209,7 +247,7 @@ func (s *SessionStorage) TryMarkFinished(ctx context.Context, q Querier, session
 				FROM sessions
 				WHERE id = $1 
 					AND user_id= $2
-			`).Scan(&status)
+			`, sessionId, uid).Scan(&status)
 
 		if errors.Is(err, pgx.ErrNoRows) {
 			return true, fmt.Errorf("%s:%w", op, ErrSessionNotFound)
@@ -225,6 +263,44 @@ func (s *SessionStorage) TryMarkFinished(ctx context.Context, q Querier, session
 	return true, nil
 }
 
+// SummarizedTasks lists the tasks of the session whose words are already in its deck
+func (s *SessionStorage) SummarizedTasks(ctx context.Context, q Querier, sessionId uuid.UUID) ([]string, error) {
+	const op = "postgresql.SessionStorage.SummarizedTasks"
+
+	rows, err := q.Query(ctx, `
+		SELECT task_id
+		FROM summarized_tasks
+		WHERE session_id = $1
+	`, sessionId)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return ids, nil
+}
+
+// MarkSummarized records the tasks whose words went into the session deck, it's meant to run in the same transaction
+func (s *SessionStorage) MarkSummarized(ctx context.Context, q Querier, sessionId uuid.UUID, taskIds []string) error {
+	const op = "postgresql.SessionStorage.MarkSummarized"
+
+	_, err := q.Exec(ctx, `
+		INSERT INTO summarized_tasks (session_id, task_id)
+		SELECT $1, t.id
+		FROM unnest($2::text[]) AS t(id)
+		ON CONFLICT (session_id, task_id) DO NOTHING
+	`, sessionId, taskIds)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	return nil
+}
+
 func (s *SessionStorage) UpdateAccuracy(ctx context.Context, q Querier, sessionId uuid.UUID, uid int64, accuracy float64) error {
 	const op = "postgresql.SessionStorage.UpdateAccuracy"
 
@@ -241,3 +317,203 @@ func (s *SessionStorage) UpdateAccuracy(ctx context.Context, q Querier, sessionI
 	}
 	return nil
 }
+
+func (s *SessionStorage) UpdateLanguage(ctx context.Context, q Querier, sessionId uuid.UUID, uid int64, langId int) error {
+	const op = "postgresql.SessionStorage.UpdateLanguage"
+
+	cmd, err := q.Exec(ctx, `
+        UPDATE sessions
+        SET lang_id = $1
+        WHERE id = $2 AND user_id = $3
+    `, langId, sessionId, uid)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// ClaimExpired leases up to limit active sessions whose duration has elapsed.
+// A claimed session is skipped by other instances until the lease ends, so a crashed instance only delays it.
+func (s *SessionStorage) ClaimExpired(ctx context.Context, q Querier, now time.Time, lease time.Duration, limit int) ([]ExpiredSession, error) {
+	const op = "postgresql.SessionStorage.ClaimExpired"
+
+	rows, err := q.Query(ctx, `
+		UPDATE sessions
+		SET expiry_locked_until = $2
+		WHERE id IN (
+			SELECT id
+			FROM sessions
+			WHERE status = 'active'
+				AND expires_at <= $1
+				AND (expiry_locked_until IS NULL OR expiry_locked_until < $1)
+			ORDER BY expires_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, user_id
+	`, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	defer rows.Close()
+
+	var out []ExpiredSession
+	for rows.Next() {
+		var e ExpiredSession
+		if err := rows.Scan(&e.Id, &e.UserId); err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+		out = append(out, e)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("%s:%w", op, rows.Err())
+	}
+
+	return out, nil
+}
+
+// Reopen moves a finished session back to active, a positive duration starts a new expiry timer from now.
+// It reports false when the session exists but is not finished.
+func (s *SessionStorage) Reopen(ctx context.Context, q Querier, sessionId uuid.UUID, uid int64, now time.Time, duration time.Duration) (bool, error) {
+	const op = "postgresql.SessionStorage.Reopen"
+
+	var expiresAt *time.Time
+	if duration > 0 {
+		t := now.Add(duration)
+		expiresAt = &t
+	}
+
+	cmd, err := q.Exec(ctx, `
+		UPDATE sessions
+		SET status = 'active',
+		    reopened_at = $1,
+		    duration = $2,
+		    expires_at = $3,
+		    expiry_locked_until = NULL
+		WHERE id = $4
+			AND user_id = $5
+			AND status = 'finished'
+	`, now, int(duration/time.Second), expiresAt, sessionId, uid)
+	if err != nil {
+		return false, fmt.Errorf("%s:%w", op, err)
+	}
+
+	if cmd.RowsAffected() == 0 {
+		var status string
+		err := q.QueryRow(ctx, `
+			SELECT status
+			FROM sessions
+			WHERE id = $1 AND user_id = $2
+		`, sessionId, uid).Scan(&status)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return false, ErrSessionNotFound
+			}
+			return false, fmt.Errorf("%s:%w", op, err)
+		}
+
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func (s *SessionStorage) Rename(ctx context.Context, q Querier, sessionId uuid.UUID, uid int64, name string) error {
+	const op = "postgresql.SessionStorage.Rename"
+
+	cmd, err := q.Exec(ctx, `
+		UPDATE sessions
+		SET name = $1
+		WHERE id = $2 AND user_id = $3
+	`, name, sessionId, uid)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// SetArchived archives or restores the session, archiving keeps the time it was first archived at
+func (s *SessionStorage) SetArchived(ctx context.Context, q Querier, sessionId uuid.UUID, uid int64, archived bool, now time.Time) error {
+	const op = "postgresql.SessionStorage.SetArchived"
+
+	cmd, err := q.Exec(ctx, `
+		UPDATE sessions
+		SET archived_at = CASE WHEN $1 THEN COALESCE(archived_at, $2) ELSE NULL END
+		WHERE id = $3 AND user_id = $4
+	`, archived, now, sessionId, uid)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// DeleteSession removes the session together with its deck and documents.
+// Flashcards of the deck are removed as well unless another deck still holds them,
+// the number of removed flashcards is returned. It must run in a transaction.
+func (s *SessionStorage) DeleteSession(ctx context.Context, q Querier, sessionId uuid.UUID, uid int64) (int64, error) {
+	const op = "postgresql.SessionStorage.DeleteSession"
+
+	var id uuid.UUID
+	err := q.QueryRow(ctx, `
+		SELECT id
+		FROM sessions
+		WHERE id = $1 AND user_id = $2
+		FOR UPDATE
+	`, sessionId, uid).Scan(&id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrSessionNotFound
+		}
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+
+	rows, err := q.Query(ctx, `
+		SELECT df.flashcard_id
+		FROM decks_flashcards df
+		JOIN decks d ON d.id = df.deck_id
+		WHERE d.session_id = $1
+	`, sessionId)
+	if err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+
+	cards, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+
+	// the deck, its entries and the documents cascade
+	if _, err := q.Exec(ctx, `DELETE FROM sessions WHERE id = $1`, sessionId); err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+
+	if len(cards) == 0 {
+		return 0, nil
+	}
+
+	cmd, err := q.Exec(ctx, `
+		DELETE FROM flashcards f
+		WHERE f.id = ANY($1)
+			AND NOT EXISTS (
+				SELECT 1
+				FROM decks_flashcards df
+				WHERE df.flashcard_id = f.id
+			)
+	`, cards)
+	if err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return cmd.RowsAffected(), nil
+}
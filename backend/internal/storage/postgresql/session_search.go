@@ -0,0 +1,170 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
+	"github.com/rwrrioe/pythia/backend/internal/storage/models"
+)
+
+const (
+	SortNewest       = "newest"
+	SortOldest       = "oldest"
+	SortAccuracyDesc = "accuracy_desc"
+	SortAccuracyAsc  = "accuracy_asc"
+	SortName         = "name"
+)
+
+// sessionOrders maps a sort order to its column and direction, the id breaks ties
+var sessionOrders = map[string]struct {
+	col  string
+	desc bool
+}{
+	SortNewest:       {"started_at", true},
+	SortOldest:       {"started_at", false},
+	SortAccuracyDesc: {"accuracy", true},
+	SortAccuracyAsc:  {"accuracy", false},
+	SortName:         {"name", false},
+}
+
+// SessionCursor is the position after the last listed session, only the field of the sort column is set
+type SessionCursor struct {
+	StartedAt time.Time `json:"s,omitzero"`
+	Accuracy  float64   `json:"a,omitempty"`
+	Name      string    `json:"n,omitempty"`
+	Id        uuid.UUID `json:"i"`
+}
+
+// SessionFilter narrows the library listing, zero values don't filter
+type SessionFilter struct {
+	Lang        int
+	Status      string
+	From        time.Time
+	To          time.Time
+	MinAccuracy *float64
+	MaxAccuracy *float64
+	Name        string
+	Archived    bool
+	Sort        string
+	After       *SessionCursor
+	Limit       int
+}
+
+func ValidSessionSort(sort string) bool {
+	_, ok := sessionOrders[sort]
+	return ok
+}
+
+// CursorOf returns the cursor pointing after the session in the given sort order
+func CursorOf(ss entities.Session, sort string) SessionCursor {
+	c := SessionCursor{Id: ss.Id}
+	switch sessionOrders[sort].col {
+	case "started_at":
+		c.StartedAt = ss.StartedAt
+	case "accuracy":
+		c.Accuracy = ss.Accuracy
+	case "name":
+		c.Name = ss.Name
+	}
+
+	return c
+}
+
+// SearchSessions lists a page of the user sessions, the page ends after the cursor and holds at most f.Limit sessions
+func (s *SessionStorage) SearchSessions(ctx context.Context, q Querier, uid int64, f SessionFilter) ([]entities.Session, error) {
+	const op = "postgresql.SessionStorage.SearchSessions"
+
+	order, ok := sessionOrders[f.Sort]
+	if !ok {
+		order = sessionOrders[SortNewest]
+	}
+
+	args := []any{uid}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	where := []string{"user_id = $1"}
+	if f.Archived {
+		where = append(where, "archived_at IS NOT NULL")
+	} else {
+		where = append(where, "archived_at IS NULL")
+	}
+	if f.Lang != 0 {
+		where = append(where, "lang_id = "+arg(f.Lang))
+	}
+	if f.Status != "" {
+		where = append(where, "status = "+arg(f.Status))
+	}
+	if !f.From.IsZero() {
+		where = append(where, "started_at >= "+arg(f.From))
+	}
+	if !f.To.IsZero() {
+		where = append(where, "started_at < "+arg(f.To))
+	}
+	if f.MinAccuracy != nil {
+		where = append(where, "accuracy >= "+arg(*f.MinAccuracy))
+	}
+	if f.MaxAccuracy != nil {
+		where = append(where, "accuracy <= "+arg(*f.MaxAccuracy))
+	}
+	if f.Name != "" {
+		where = append(where, "name ILIKE "+arg("%"+escapeLike(f.Name)+"%"))
+	}
+
+	dir, cmp := "ASC", ">"
+	if order.desc {
+		dir, cmp = "DESC", "<"
+	}
+
+	if f.After != nil {
+		var v any
+		switch order.col {
+		case "started_at":
+			v = f.After.StartedAt
+		case "accuracy":
+			v = f.After.Accuracy
+		case "name":
+			v = f.After.Name
+		}
+		where = append(where, fmt.Sprintf("(%s, id) %s (%s, %s)", order.col, cmp, arg(v), arg(f.After.Id)))
+	}
+
+	sql := `SELECT ` + sessionCols + `
+		FROM sessions
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY ` + order.col + ` ` + dir + `, id ` + dir + `
+		LIMIT ` + arg(f.Limit)
+
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	defer rows.Close()
+
+	out := make([]entities.Session, 0, f.Limit)
+	for rows.Next() {
+		var m models.Session
+		if err := scanSession(rows, &m); err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+
+		out = append(out, toSession(m))
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("%s:%w", op, rows.Err())
+	}
+
+	return out, nil
+}
+
+// escapeLike makes the search term match literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -22,6 +22,11 @@ var (
 	ErrDeckAlreadyExists          = errors.New("deck already exists")
 	ErrFlashcardAlreadyExists     = errors.New("flashcard already exists")
 	ErrDeckFlashcardAlreadyExists = errors.New("deck-flashcards already exists")
+	ErrOCRResultNotFound          = errors.New("ocr result not found")
+	ErrDocumentNotFound           = errors.New("document not found")
+	ErrTagNotFound                = errors.New("tag not found")
+	ErrFilterNotFound             = errors.New("filter not found")
+	ErrFilterAlreadyExists        = errors.New("filter already exists")
 )
 
 type Querier interface {
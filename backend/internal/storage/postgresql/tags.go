@@ -0,0 +1,129 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
+)
+
+// AddTags tags the flashcard, tags it already has are skipped. It returns how many were added.
+func (s *FlashCardStorage) AddTags(ctx context.Context, q Querier, flashcardId uuid.UUID, tags []string, auto bool, uid int64) (int64, error) {
+	const op = "postgresql.FlashCardStorage.AddTags"
+
+	cmd, err := q.Exec(ctx, `
+		INSERT INTO flashcard_tags (flashcard_id, tag, auto)
+		SELECT f.id, t.tag, $3
+		FROM flashcards f
+		CROSS JOIN unnest($2::text[]) AS t(tag)
+		WHERE f.id = $1 AND f.user_id = $4
+		ON CONFLICT (flashcard_id, tag) DO NOTHING
+	`, flashcardId, tags, auto, uid)
+	if err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return cmd.RowsAffected(), nil
+}
+
+// TagFlashcards puts the same tag on several flashcards of the user
+func (s *FlashCardStorage) TagFlashcards(ctx context.Context, q Querier, flashcardIds []uuid.UUID, tag string, auto bool, uid int64) (int64, error) {
+	const op = "postgresql.FlashCardStorage.TagFlashcards"
+
+	cmd, err := q.Exec(ctx, `
+		INSERT INTO flashcard_tags (flashcard_id, tag, auto)
+		SELECT f.id, $2, $3
+		FROM flashcards f
+		WHERE f.id = ANY($1) AND f.user_id = $4
+		ON CONFLICT (flashcard_id, tag) DO NOTHING
+	`, flashcardIds, tag, auto, uid)
+	if err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return cmd.RowsAffected(), nil
+}
+
+func (s *FlashCardStorage) RemoveTag(ctx context.Context, q Querier, flashcardId uuid.UUID, tag string, uid int64) error {
+	const op = "postgresql.FlashCardStorage.RemoveTag"
+
+	cmd, err := q.Exec(ctx, `
+		DELETE FROM flashcard_tags t
+		USING flashcards f
+		WHERE t.flashcard_id = f.id
+		  AND f.id = $1 AND f.user_id = $2 AND t.tag = $3
+	`, flashcardId, uid, tag)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrTagNotFound
+	}
+
+	return nil
+}
+
+// ListTags returns the tags used in the user's vocabulary with their flashcard counts
+func (s *FlashCardStorage) ListTags(ctx context.Context, q Querier, uid int64) ([]entities.Tag, error) {
+	const op = "postgresql.FlashCardStorage.ListTags"
+
+	rows, err := q.Query(ctx, `
+		SELECT t.tag, bool_and(t.auto), count(*)
+		FROM flashcard_tags t
+		JOIN flashcards f ON f.id = t.flashcard_id
+		WHERE f.user_id = $1
+		GROUP BY t.tag
+		ORDER BY t.tag
+	`, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	defer rows.Close()
+
+	out := make([]entities.Tag, 0, 32)
+	for rows.Next() {
+		var t entities.Tag
+		if err := rows.Scan(&t.Name, &t.Auto, &t.Count); err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+		out = append(out, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return out, nil
+}
+
+// RecordReviews saves the answers given for the user's flashcards, answers for other cards are dropped.
+// It returns how many were saved.
+func (s *FlashCardStorage) RecordReviews(ctx context.Context, q Querier, reviews []entities.Review, uid int64) (int64, error) {
+	const op = "postgresql.FlashCardStorage.RecordReviews"
+
+	var (
+		ids      = make([]uuid.UUID, len(reviews))
+		sessions = make([]*uuid.UUID, len(reviews))
+		correct  = make([]bool, len(reviews))
+		at       = make([]time.Time, len(reviews))
+	)
+	for i, r := range reviews {
+		ids[i], sessions[i], correct[i], at[i] = r.FlashcardId, r.SessionId, r.Correct, r.ReviewedAt
+	}
+
+	cmd, err := q.Exec(ctx, `
+		INSERT INTO flashcard_reviews (flashcard_id, session_id, correct, reviewed_at)
+		SELECT r.flashcard_id, s.id, r.correct, r.reviewed_at
+		FROM unnest($1::uuid[], $2::uuid[], $3::bool[], $4::timestamp[])
+		     AS r(flashcard_id, session_id, correct, reviewed_at)
+		JOIN flashcards f ON f.id = r.flashcard_id AND f.user_id = $5
+		LEFT JOIN sessions s ON s.id = r.session_id AND s.user_id = $5
+	`, ids, sessions, correct, at, uid)
+	if err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return cmd.RowsAffected(), nil
+}
@@ -6,19 +6,18 @@ import (
 	"fmt"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rwrrioe/pythia/backend/internal/auth/authn"
 	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
 	"github.com/rwrrioe/pythia/backend/internal/storage/models"
 )
 
 type UserStorage struct {
-	conn *pgx.Conn
+	pool *pgxpool.Pool
 }
 
-func NewUserStorage(conn *pgx.Conn) (*UserStorage, error) {
-	const op = "storage.postgres.New"
-
-	return &UserStorage{conn: conn}, nil
+func NewUserStorage(pool *pgxpool.Pool) *UserStorage {
+	return &UserStorage{pool: pool}
 }
 
 func (s *UserStorage) GetUser(ctx context.Context) (*entities.User, error) {
@@ -30,7 +29,7 @@ func (s *UserStorage) GetUser(ctx context.Context) (*entities.User, error) {
 		return nil, ErrUserNotFound
 	}
 
-	if err := s.conn.QueryRow(ctx,
+	if err := s.pool.QueryRow(ctx,
 		`SELECT * 
 									FROM users u 
 									JOIN languages l ON u.lang_id = l.id
@@ -51,3 +50,23 @@ func (s *UserStorage) GetUser(ctx context.Context) (*entities.User, error) {
 		WordsPerDay: user.WordsPerDay,
 	}, nil
 }
+
+// GetLevel returns the CEFR level id from the user profile
+func (s *UserStorage) GetLevel(ctx context.Context, q Querier, uid int64) (int, error) {
+	const op = "postgresql.UserStorage.GetLevel"
+
+	var level int
+	err := q.QueryRow(ctx,
+		`SELECT level_id
+         FROM users
+         WHERE id=$1`, uid,
+	).Scan(&level)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrUserNotFound
+		}
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return level, nil
+}
@@ -0,0 +1,130 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
+	"github.com/rwrrioe/pythia/backend/internal/storage/models"
+)
+
+// SearchVocabulary ranks the user flashcards against the term. Words and translations match by trigram
+// similarity or prefix, examples and descriptions by full text, all of them ignoring case and accents.
+// An exact word comes first, lang 0 searches every language.
+func (s *FlashCardStorage) SearchVocabulary(ctx context.Context, q Querier, uid int64, term string, lang, limit int) ([]entities.VocabularyHit, error) {
+	const op = "postgresql.FlashCardStorage.SearchVocabulary"
+
+	rows, err := q.Query(ctx, `
+		WITH t AS (
+			SELECT immutable_unaccent(lower($2)) AS term,
+			       plainto_tsquery('simple', immutable_unaccent(lower($2))) AS query
+		)
+		SELECT `+flashcardCols+`,
+		       (immutable_unaccent(lower(f.word)) = t.term) AS exact,
+		       greatest(
+		           similarity(immutable_unaccent(lower(f.word)), t.term),
+		           similarity(immutable_unaccent(lower(f.transl)), t.term)
+		       ) + ts_rank(f.search_vector, t.query) AS score
+		FROM flashcards f, t
+		WHERE f.user_id = $1
+		  AND ($3 = 0 OR f.lang_id = $3)
+		  AND (
+		      immutable_unaccent(lower(f.word)) % t.term
+		   OR immutable_unaccent(lower(f.transl)) % t.term
+		   OR immutable_unaccent(lower(f.word)) LIKE immutable_unaccent(lower($4))
+		   OR immutable_unaccent(lower(f.transl)) LIKE immutable_unaccent(lower($4))
+		   OR f.search_vector @@ t.query
+		  )
+		ORDER BY exact DESC, score DESC, f.id
+		LIMIT $5
+	`, uid, term, lang, escapeLike(term)+"%", limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	defer rows.Close()
+
+	out := make([]entities.VocabularyHit, 0, limit)
+	for rows.Next() {
+		var (
+			m     models.FlashCard
+			exact bool
+			score float64
+		)
+		if err := rows.Scan(&m.Id, &m.Word, &m.Transl, &m.Lang, &m.Desc, &m.Example, &m.Tags, &exact, &score); err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+
+		out = append(out, entities.VocabularyHit{
+			Flashcard: toFlashcard(m),
+			Exact:     exact,
+			Score:     score,
+			Decks:     []entities.DeckRef{},
+			Sessions:  []entities.SessionRef{},
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	return out, nil
+}
+
+// Memberships fills in the decks holding each hit and the sessions the word was found in
+func (s *FlashCardStorage) Memberships(ctx context.Context, q Querier, hits []entities.VocabularyHit, uid int64) error {
+	const op = "postgresql.FlashCardStorage.Memberships"
+
+	if len(hits) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(hits))
+	at := make(map[uuid.UUID]int, len(hits))
+	for i, h := range hits {
+		ids[i] = h.Flashcard.Id
+		at[h.Flashcard.Id] = i
+	}
+
+	rows, err := q.Query(ctx, `
+		SELECT df.flashcard_id, d.id, d.kind, coalesce(s.name, d.name), s.id, s.started_at
+		FROM decks_flashcards df
+		JOIN decks d ON d.id = df.deck_id
+		LEFT JOIN sessions s ON s.id = d.session_id
+		WHERE df.flashcard_id = ANY($1) AND d.user_id = $2
+		ORDER BY d.kind DESC, d.position, s.started_at DESC, d.id
+	`, ids, uid)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cardId    uuid.UUID
+			deck      entities.DeckRef
+			sessionId *uuid.UUID
+			startedAt *time.Time
+		)
+		if err := rows.Scan(&cardId, &deck.Id, &deck.Kind, &deck.Name, &sessionId, &startedAt); err != nil {
+			return fmt.Errorf("%s:%w", op, err)
+		}
+
+		h := &hits[at[cardId]]
+		h.Decks = append(h.Decks, deck)
+		if sessionId != nil {
+			h.Sessions = append(h.Sessions, entities.SessionRef{
+				Id:        *sessionId,
+				Name:      deck.Name,
+				StartedAt: *startedAt,
+			})
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+
+	return nil
+}
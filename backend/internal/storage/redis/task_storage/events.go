@@ -0,0 +1,55 @@
+package redis_storage
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+// sessionEventsChannel fans session events out to every backend instance,
+// the websocket of a session may be connected to another instance than the one producing the event
+const sessionEventsChannel = "session:events"
+
+type sessionEvent struct {
+	SessionId uuid.UUID       `json:"session_id"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+func (s *RedisStorage) PublishSessionEvent(ctx context.Context, sessionId uuid.UUID, payload any) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(sessionEvent{SessionId: sessionId, Payload: raw})
+	if err != nil {
+		return err
+	}
+
+	return s.client.Publish(ctx, sessionEventsChannel, b).Err()
+}
+
+// SubscribeSessionEvents calls handle for every published session event until ctx is done
+func (s *RedisStorage) SubscribeSessionEvents(ctx context.Context, handle func(sessionId uuid.UUID, payload json.RawMessage)) error {
+	sub := s.client.Subscribe(ctx, sessionEventsChannel)
+	defer sub.Close()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+
+			var ev sessionEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &ev); err != nil {
+				continue
+			}
+			handle(ev.SessionId, ev.Payload)
+		}
+	}
+}
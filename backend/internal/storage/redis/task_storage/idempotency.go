@@ -0,0 +1,76 @@
+package redis_storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// IdempotentRecord is the request stored under an idempotency key, Done is set once its response is known
+type IdempotentRecord struct {
+	Fingerprint string `json:"fingerprint"`
+	Done        bool   `json:"done"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+func idempotencyKey(uid int64, key string) string {
+	return fmt.Sprintf("idempotency:%d:%s", uid, key)
+}
+
+// ReserveIdempotencyKey claims the key for a new request.
+// When the key is already taken the stored record is returned and reserved is false.
+func (s *RedisStorage) ReserveIdempotencyKey(ctx context.Context, uid int64, key, fingerprint string, ttl time.Duration) (*IdempotentRecord, bool, error) {
+	b, err := json.Marshal(IdempotentRecord{Fingerprint: fingerprint})
+	if err != nil {
+		return nil, false, err
+	}
+
+	// the record may expire between the two calls, one more attempt settles it
+	for range 2 {
+		ok, err := s.client.SetNX(ctx, idempotencyKey(uid, key), b, ttl).Result()
+		if err != nil {
+			return nil, false, err
+		}
+		if ok {
+			return nil, true, nil
+		}
+
+		val, err := s.client.Get(ctx, idempotencyKey(uid, key)).Bytes()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		var rec IdempotentRecord
+		if err := json.Unmarshal(val, &rec); err != nil {
+			return nil, false, err
+		}
+		return &rec, false, nil
+	}
+
+	return nil, false, fmt.Errorf("idempotency key %q is contended", key)
+}
+
+func (s *RedisStorage) SaveIdempotentResponse(ctx context.Context, uid int64, key string, rec IdempotentRecord, ttl time.Duration) error {
+	rec.Done = true
+
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	return s.client.Set(ctx, idempotencyKey(uid, key), b, ttl).Err()
+}
+
+// ReleaseIdempotencyKey frees the key of a failed request so the client may retry it
+func (s *RedisStorage) ReleaseIdempotencyKey(ctx context.Context, uid int64, key string) error {
+	return s.client.Del(ctx, idempotencyKey(uid, key)).Err()
+}
@@ -0,0 +1,103 @@
+package redis_storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
+)
+
+// importTTL is how long the report of an import stays available, as long as its upload
+const importTTL = 24 * time.Hour
+
+func importKey(importId string) string {
+	return fmt.Sprintf("import:%s", importId)
+}
+
+type ImportDTO struct {
+	UserId int64 `json:"user_id"`
+	entities.Import
+}
+
+func (s *RedisStorage) SaveImport(ctx context.Context, imp ImportDTO) error {
+	imp.UpdatedAt = time.Now()
+
+	b, err := json.Marshal(imp)
+	if err != nil {
+		return err
+	}
+
+	return s.client.Set(ctx, importKey(imp.Id), b, importTTL).Err()
+}
+
+func (s *RedisStorage) GetImport(ctx context.Context, importId string) (*ImportDTO, bool, error) {
+	b, err := s.client.Get(ctx, importKey(importId)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	var imp ImportDTO
+	if err := json.Unmarshal(b, &imp); err != nil {
+		return nil, true, err
+	}
+
+	return &imp, true, nil
+}
+
+// updateRetries bounds the attempts of an update racing with others on the same import
+const updateRetries = 10
+
+// UpdateImport applies update to the stored import atomically, the import is written only when update
+// returns true. It reports whether the update was applied, false as well when the import has expired.
+func (s *RedisStorage) UpdateImport(ctx context.Context, importId string, update func(imp *ImportDTO) bool) (bool, error) {
+	key := importKey(importId)
+
+	for range updateRetries {
+		var applied bool
+		err := s.client.Watch(ctx, func(tx *redis.Tx) error {
+			b, err := tx.Get(ctx, key).Bytes()
+			if err != nil {
+				if err == redis.Nil {
+					return nil
+				}
+				return err
+			}
+
+			var imp ImportDTO
+			if err := json.Unmarshal(b, &imp); err != nil {
+				return err
+			}
+			if !update(&imp) {
+				return nil
+			}
+
+			imp.UpdatedAt = time.Now()
+			if b, err = json.Marshal(imp); err != nil {
+				return err
+			}
+
+			_, err = tx.TxPipelined(ctx, func(p redis.Pipeliner) error {
+				p.Set(ctx, key, b, redis.KeepTTL)
+				return nil
+			})
+			if err == nil {
+				applied = true
+			}
+			return err
+		}, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+
+		return applied, err
+	}
+
+	return false, redis.TxFailedErr
+}
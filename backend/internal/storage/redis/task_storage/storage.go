@@ -17,12 +17,17 @@ type RedisProvider interface {
 	Get(ctx context.Context, taskId string) (*TaskDTO, bool, error)
 	GetBySession(ctx context.Context, sessionId uuid.UUID) ([]TaskDTO, bool, error)
 	UpdateTask(ctx context.Context, taskId string, update func(task *TaskDTO)) (bool, error)
+	SetStatus(ctx context.Context, taskId string, sessionId uuid.UUID, stage, status, errMsg string) (bool, error)
 	Delete(ctx context.Context, taskID string) error
 	SaveSession(ctx context.Context, ss SessionDTO) error
 	GetSession(ctx context.Context, sessionId uuid.UUID) (*SessionDTO, bool, error)
 	UpdateSession(ctx context.Context, sessionId uuid.UUID, update func(s *SessionDTO)) (bool, error)
+	DeleteSession(ctx context.Context, sessionId uuid.UUID) error
 }
 
+// tasksIndex indexes task documents by session, v2 switched session_id from a numeric to a tag field
+const tasksIndex = "idx:tasks:v2"
+
 type RedisStorage struct {
 	ttl    time.Duration
 	client *redis.Client
@@ -35,6 +40,7 @@ type SessionDTO struct {
 	StartedAt time.Time       `json:"started_at"`
 	EndedAt   time.Time       `json:"ended_at"`
 	Duration  time.Duration   `json:"duration"`
+	ExpiresAt time.Time       `json:"expires_at"`
 	Status    string          `json:"status"`
 	Language  int             `json:"language"`
 	Level     int             `json:"level"`
@@ -42,9 +48,25 @@ type SessionDTO struct {
 	Words     []entities.Word `json:"imp_words"`
 }
 type TaskDTO struct {
-	SessionId uuid.UUID       `json:"session_id"`
-	OCRText   []string        `json:"ocr_text"`
-	Words     []entities.Word `json:"words"`
+	Id           string               `json:"task_id"`
+	SessionId    uuid.UUID            `json:"session_id"`
+	OCRText      []string             `json:"ocr_text"`
+	OCRLines     []entities.OCRLine   `json:"ocr_lines"`
+	ImageWidth   int                  `json:"image_width"`
+	ImageHeight  int                  `json:"image_height"`
+	DetectedLang string               `json:"detected_lang"`
+	Pages        []entities.OCRResult `json:"pages,omitempty"`
+	Words        []entities.Word      `json:"words"`
+	Examples     []entities.Example   `json:"examples,omitempty"`
+	// Pipeline lists the stages requested for the task, in order
+	Pipeline []string `json:"pipeline,omitempty"`
+	// the upload options, kept so a failed ocr stage can be retried
+	RequestedLang string    `json:"requested_lang,omitempty"`
+	MultiPage     bool      `json:"multi_page,omitempty"`
+	Stage         string    `json:"stage"`
+	Status        string    `json:"status"`
+	Error         string    `json:"error,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
 func NewRedisStorage(ctx context.Context, add string, ttl time.Duration) (*RedisStorage, error) {
@@ -58,7 +80,7 @@ func NewRedisStorage(ctx context.Context, add string, ttl time.Duration) (*Redis
 	//creating index
 	_, err := rdb.FTCreate(
 		ctx,
-		"idx:tasks",
+		tasksIndex,
 		// Options:
 		&redis.FTCreateOptions{
 			OnJSON: true,
@@ -68,7 +90,7 @@ func NewRedisStorage(ctx context.Context, add string, ttl time.Duration) (*Redis
 		&redis.FieldSchema{
 			FieldName: "$.session_id",
 			As:        "sessionId",
-			FieldType: redis.SearchFieldTypeNumeric,
+			FieldType: redis.SearchFieldTypeTag,
 		},
 	).Result()
 
@@ -81,8 +103,15 @@ func NewRedisStorage(ctx context.Context, add string, ttl time.Duration) (*Redis
 	}, nil
 }
 
+// Client exposes the connection for components sharing it, such as the job queue
+func (s *RedisStorage) Client() *redis.Client {
+	return s.client
+}
+
 func (s *RedisStorage) Save(ctx context.Context, taskId string, task TaskDTO) error {
 	key := fmt.Sprintf("task:%s", taskId)
+	task.Id = taskId
+	task.UpdatedAt = time.Now()
 	if err := s.client.JSONSet(ctx, key, "$", task).Err(); err != nil {
 		return err
 	}
@@ -114,9 +143,10 @@ func (s *RedisStorage) Get(ctx context.Context, taskId string) (*TaskDTO, bool,
 }
 
 func (s *RedisStorage) GetBySession(ctx context.Context, sessionId uuid.UUID) ([]TaskDTO, bool, error) {
-	q := fmt.Sprintf("@sessionId:[%d %d]", sessionId, sessionId)
+	// uuid dashes are tag separators in the query syntax
+	q := fmt.Sprintf("@sessionId:{%s}", strings.ReplaceAll(sessionId.String(), "-", `\-`))
 
-	res, err := s.client.FTSearchWithArgs(ctx, "idx:tasks", q, &redis.FTSearchOptions{
+	res, err := s.client.FTSearchWithArgs(ctx, tasksIndex, q, &redis.FTSearchOptions{
 		DialectVersion: 2,
 		Return:         []redis.FTSearchReturn{{FieldName: "$"}},
 	}).Result()
@@ -140,6 +170,9 @@ func (s *RedisStorage) GetBySession(ctx context.Context, sessionId uuid.UUID) ([
 		if err := json.Unmarshal([]byte(raw), &t); err != nil {
 			return nil, true, err
 		}
+		if t.Id == "" {
+			t.Id = strings.TrimPrefix(doc.ID, "task:")
+		}
 
 		tasks = append(tasks, t)
 	}
@@ -165,6 +198,7 @@ func (s *RedisStorage) UpdateTask(ctx context.Context, taskId string, update fun
 	task := arr[0]
 
 	update(&task)
+	task.UpdatedAt = time.Now()
 
 	err = s.client.JSONSet(ctx, key, "$", task).Err()
 	if err != nil {
@@ -175,6 +209,23 @@ func (s *RedisStorage) UpdateTask(ctx context.Context, taskId string, update fun
 	return true, nil
 }
 
+// SetStatus records the progress of a stage, tasks of other sessions are left untouched.
+// It reports false when the task doesn't exist or belongs to another session.
+func (s *RedisStorage) SetStatus(ctx context.Context, taskId string, sessionId uuid.UUID, stage, status, errMsg string) (bool, error) {
+	owned := false
+	ok, err := s.UpdateTask(ctx, taskId, func(task *TaskDTO) {
+		if task.SessionId != sessionId {
+			return
+		}
+		task.Stage = stage
+		task.Status = status
+		task.Error = errMsg
+		owned = true
+	})
+
+	return ok && owned, err
+}
+
 func (s *RedisStorage) Delete(ctx context.Context, taskID string) error {
 	return s.client.Del(ctx, "task:"+taskID).Err()
 }
@@ -186,13 +237,22 @@ func (s *RedisStorage) SaveSession(ctx context.Context, ss SessionDTO) error {
 	if err != nil {
 		return err
 	}
-	if err := s.client.Set(ctx, key, b, s.ttl).Err(); err != nil {
+	if err := s.client.Set(ctx, key, b, s.sessionTTL(&ss)).Err(); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// sessionTTL keeps an active session in redis at least until its duration elapses
+func (s *RedisStorage) sessionTTL(ss *SessionDTO) time.Duration {
+	if ss.ExpiresAt.IsZero() || ss.Status == "finished" {
+		return s.ttl
+	}
+
+	return max(s.ttl, time.Until(ss.ExpiresAt)+s.ttl)
+}
+
 func (s *RedisStorage) GetSession(ctx context.Context, sessionId uuid.UUID) (*SessionDTO, bool, error) {
 	key := fmt.Sprintf("session:%d", sessionId)
 
@@ -233,10 +293,14 @@ func (s *RedisStorage) UpdateSession(ctx context.Context, sessionId uuid.UUID, u
 		return true, err
 	}
 
-	err = s.client.Set(ctx, key, b, s.ttl).Err()
+	err = s.client.Set(ctx, key, b, s.sessionTTL(&ss)).Err()
 	if err != nil {
 		return true, err
 	}
 
 	return true, nil
 }
+
+func (s *RedisStorage) DeleteSession(ctx context.Context, sessionId uuid.UUID) error {
+	return s.client.Del(ctx, fmt.Sprintf("session:%d", sessionId)).Err()
+}
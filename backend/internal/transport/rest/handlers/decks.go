@@ -0,0 +1,251 @@
+package rest_handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rwrrioe/pythia/backend/internal/domain/requests"
+	service "github.com/rwrrioe/pythia/backend/internal/services"
+)
+
+type DecksHandler struct {
+	decks *service.DecksService
+}
+
+func NewDecksHandler(decks *service.DecksService) *DecksHandler {
+	return &DecksHandler{decks: decks}
+}
+
+// GET /api/decks?kind=user|session
+func (h *DecksHandler) List(c *gin.Context) {
+	decks, err := h.decks.List(c.Request.Context(), c.Query("kind"))
+	if err != nil {
+		respondDeckErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"decks": decks,
+	})
+}
+
+// GET /api/decks/:deckId
+func (h *DecksHandler) Get(c *gin.Context) {
+	deckId, ok := parseDeckId(c)
+	if !ok {
+		return
+	}
+
+	deck, err := h.decks.Get(c.Request.Context(), deckId)
+	if err != nil {
+		respondDeckErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deck": deck,
+	})
+}
+
+// POST /api/decks
+func (h *DecksHandler) Create(c *gin.Context) {
+	var req requests.CreateDeck
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	deck, err := h.decks.Create(c.Request.Context(), req)
+	if err != nil {
+		respondDeckErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"deck": deck,
+	})
+}
+
+// PATCH /api/decks/:deckId
+func (h *DecksHandler) Update(c *gin.Context) {
+	var req requests.UpdateDeck
+
+	deckId, ok := parseDeckId(c)
+	if !ok {
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	deck, err := h.decks.Update(c.Request.Context(), deckId, req)
+	if err != nil {
+		respondDeckErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deck": deck,
+	})
+}
+
+// DELETE /api/decks/:deckId
+func (h *DecksHandler) Delete(c *gin.Context) {
+	deckId, ok := parseDeckId(c)
+	if !ok {
+		return
+	}
+
+	if err := h.decks.Delete(c.Request.Context(), deckId); err != nil {
+		respondDeckErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deck_id": deckId,
+		"deleted": true,
+	})
+}
+
+// PUT /api/decks/order
+func (h *DecksHandler) Reorder(c *gin.Context) {
+	var req requests.ReorderDecks
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if err := h.decks.Reorder(c.Request.Context(), req.Ids); err != nil {
+		respondDeckErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deck_ids": req.Ids,
+	})
+}
+
+// POST /api/decks/:deckId/cards
+func (h *DecksHandler) AddCards(c *gin.Context) {
+	var req requests.DeckCards
+
+	deckId, ok := parseDeckId(c)
+	if !ok {
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	added, err := h.decks.AddCards(c.Request.Context(), deckId, req.Ids)
+	if err != nil {
+		respondDeckErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deck_id": deckId,
+		"added":   added,
+	})
+}
+
+// DELETE /api/decks/:deckId/cards
+func (h *DecksHandler) RemoveCards(c *gin.Context) {
+	var req requests.DeckCards
+
+	deckId, ok := parseDeckId(c)
+	if !ok {
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	removed, err := h.decks.RemoveCards(c.Request.Context(), deckId, req.Ids)
+	if err != nil {
+		respondDeckErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deck_id": deckId,
+		"removed": removed,
+	})
+}
+
+// PUT /api/decks/:deckId/cards/order
+func (h *DecksHandler) ReorderCards(c *gin.Context) {
+	var req requests.DeckCards
+
+	deckId, ok := parseDeckId(c)
+	if !ok {
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if err := h.decks.ReorderCards(c.Request.Context(), deckId, req.Ids); err != nil {
+		respondDeckErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deck_id":       deckId,
+		"flashcard_ids": req.Ids,
+	})
+}
+
+func parseDeckId(c *gin.Context) (uuid.UUID, bool) {
+	deckId, err := uuid.Parse(c.Param("deckId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid deckId",
+			"details": err.Error(),
+		})
+		return uuid.Nil, false
+	}
+
+	return deckId, true
+}
+
+func respondDeckErr(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidDeck):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid deck",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrSessionDeck):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "session deck",
+			"details": err.Error(),
+		})
+	default:
+		respondCardErr(c, err)
+	}
+}
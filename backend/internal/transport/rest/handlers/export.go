@@ -0,0 +1,134 @@
+package rest_handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rwrrioe/pythia/backend/internal/domain/requests"
+	"github.com/rwrrioe/pythia/backend/internal/lib/anki"
+	service "github.com/rwrrioe/pythia/backend/internal/services"
+)
+
+type ExportHandler struct {
+	export *service.ExportService
+	log    *slog.Logger
+}
+
+func NewExportHandler(export *service.ExportService, log *slog.Logger) *ExportHandler {
+	return &ExportHandler{export: export, log: log}
+}
+
+// GET /api/decks/:deckId/export/anki
+func (h *ExportHandler) AnkiDeck(c *gin.Context) {
+	deckId, ok := parseDeckId(c)
+	if !ok {
+		return
+	}
+
+	h.anki(c, deckId, h.export.AnkiDeck)
+}
+
+// GET /api/filters/:filterId/export/anki
+func (h *ExportHandler) AnkiFilter(c *gin.Context) {
+	filterId, ok := parseFilterId(c)
+	if !ok {
+		return
+	}
+
+	h.anki(c, filterId, h.export.AnkiFilter)
+}
+
+func (h *ExportHandler) anki(c *gin.Context, id uuid.UUID, prepare func(ctx context.Context, id uuid.UUID) (*anki.Deck, error)) {
+	ctx := c.Request.Context()
+
+	deck, err := prepare(ctx, id)
+	if err != nil {
+		respondExportErr(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.apkg"`, fileName(deck.Name)))
+
+	if err := anki.Write(ctx, c.Writer, *deck, time.Now()); err != nil {
+		// once the package is being sent the response can't be turned into an error
+		if c.Writer.Written() {
+			h.log.Error("anki export interrupted", slog.String("error", err.Error()))
+			return
+		}
+		c.Writer.Header().Del("Content-Disposition")
+		c.Writer.Header().Del("Content-Type")
+		respondExportErr(c, err)
+	}
+}
+
+// GET /api/vocabulary/export?format=&deck_id=&filter_id=&q=&term_sep=&card_sep=
+func (h *ExportHandler) Vocabulary(c *gin.Context) {
+	var req requests.ExportVocabulary
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	export, err := h.export.Vocabulary(ctx, req)
+	if err != nil {
+		respondExportErr(c, err)
+		return
+	}
+
+	c.Header("Content-Type", export.Format.ContentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, fileName(export.Name), export.Format.Ext))
+
+	if _, err := export.Write(ctx, c.Writer); err != nil {
+		// the cards are streamed, a failure after the first bytes can only cut the file short
+		if c.Writer.Written() {
+			h.log.Error("vocabulary export interrupted", slog.String("error", err.Error()))
+			return
+		}
+		c.Writer.Header().Del("Content-Disposition")
+		c.Writer.Header().Del("Content-Type")
+		respondExportErr(c, err)
+	}
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^\p{L}\p{N}._-]+`)
+
+// fileName makes a deck name safe for the Content-Disposition header
+func fileName(name string) string {
+	name = strings.Trim(unsafeFileChars.ReplaceAllString(name, "_"), "_.")
+	if name == "" {
+		return "deck"
+	}
+
+	return name
+}
+
+func respondExportErr(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidExport):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid export",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrExportTooLarge):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "export too large",
+			"details": err.Error(),
+		})
+	default:
+		respondFilterErr(c, err)
+	}
+}
@@ -0,0 +1,216 @@
+package rest_handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
+	"github.com/rwrrioe/pythia/backend/internal/domain/requests"
+	service "github.com/rwrrioe/pythia/backend/internal/services"
+)
+
+type FiltersHandler struct {
+	filters *service.FiltersService
+}
+
+func NewFiltersHandler(filters *service.FiltersService) *FiltersHandler {
+	return &FiltersHandler{filters: filters}
+}
+
+// GET /api/filters
+func (h *FiltersHandler) List(c *gin.Context) {
+	filters, err := h.filters.List(c.Request.Context())
+	if err != nil {
+		respondFilterErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"filters": filters,
+	})
+}
+
+// GET /api/filters/:filterId
+func (h *FiltersHandler) Get(c *gin.Context) {
+	filterId, ok := parseFilterId(c)
+	if !ok {
+		return
+	}
+
+	f, err := h.filters.Get(c.Request.Context(), filterId)
+	if err != nil {
+		respondFilterErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"filter": f,
+	})
+}
+
+// POST /api/filters
+func (h *FiltersHandler) Create(c *gin.Context) {
+	var req requests.CreateSmartFilter
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	f, err := h.filters.Create(c.Request.Context(), req)
+	if err != nil {
+		respondFilterErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"filter": f,
+	})
+}
+
+// PATCH /api/filters/:filterId
+func (h *FiltersHandler) Update(c *gin.Context) {
+	var req requests.UpdateSmartFilter
+
+	filterId, ok := parseFilterId(c)
+	if !ok {
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	f, err := h.filters.Update(c.Request.Context(), filterId, req)
+	if err != nil {
+		respondFilterErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"filter": f,
+	})
+}
+
+// DELETE /api/filters/:filterId
+func (h *FiltersHandler) Delete(c *gin.Context) {
+	filterId, ok := parseFilterId(c)
+	if !ok {
+		return
+	}
+
+	if err := h.filters.Delete(c.Request.Context(), filterId); err != nil {
+		respondFilterErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"filter_id": filterId,
+		"deleted":   true,
+	})
+}
+
+// GET /api/filters/:filterId/cards?limit=
+func (h *FiltersHandler) Cards(c *gin.Context) {
+	h.cards(c, h.filters.Cards)
+}
+
+// GET /api/filters/:filterId/review?limit=
+func (h *FiltersHandler) Review(c *gin.Context) {
+	h.cards(c, h.filters.Review)
+}
+
+func (h *FiltersHandler) cards(c *gin.Context, list func(ctx context.Context, id uuid.UUID, limit int) ([]entities.FlashCard, error)) {
+	filterId, ok := parseFilterId(c)
+	if !ok {
+		return
+	}
+	limit, ok := parseLimit(c)
+	if !ok {
+		return
+	}
+
+	cards, err := list(c.Request.Context(), filterId, limit)
+	if err != nil {
+		respondFilterErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"filter_id":  filterId,
+		"flashcards": cards,
+		"count":      len(cards),
+	})
+}
+
+// GET /api/filters/:filterId/quiz?limit=
+func (h *FiltersHandler) Quiz(c *gin.Context) {
+	filterId, ok := parseFilterId(c)
+	if !ok {
+		return
+	}
+	limit, ok := parseLimit(c)
+	if !ok {
+		return
+	}
+
+	quiz, err := h.filters.Quiz(c.Request.Context(), filterId, limit)
+	if err != nil {
+		respondFilterErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"filter_id": filterId,
+		"stage":     "quiz",
+		"quiz":      quiz,
+	})
+}
+
+func parseFilterId(c *gin.Context) (uuid.UUID, bool) {
+	filterId, err := uuid.Parse(c.Param("filterId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid filterId",
+			"details": err.Error(),
+		})
+		return uuid.Nil, false
+	}
+
+	return filterId, true
+}
+
+func respondFilterErr(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrSmartFilterNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "filter not found",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrSmartFilterExists):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "filter already exists",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrInvalidSmartFilter):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid filter",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrNotEnoughCards):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "not enough cards",
+			"details": err.Error(),
+		})
+	default:
+		respondCardErr(c, err)
+	}
+}
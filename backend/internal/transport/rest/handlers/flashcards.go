@@ -3,9 +3,11 @@ package rest_handlers
 import (
 	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/rwrrioe/pythia/backend/internal/domain/requests"
 	service "github.com/rwrrioe/pythia/backend/internal/services"
 	taskstorage "github.com/rwrrioe/pythia/backend/internal/storage/redis/task_storage"
 	hub "github.com/rwrrioe/pythia/backend/internal/transport/ws/ws_hub"
@@ -14,13 +16,15 @@ import (
 type FlashCardsHandler struct {
 	storage *taskstorage.RedisStorage
 	session *service.SessionService
+	cards   *service.FlashCardsService
 	ws      *hub.WebSocketHub
 }
 
-func NewFlashCardsHandler(storage *taskstorage.RedisStorage, ws *hub.WebSocketHub, session *service.SessionService) *FlashCardsHandler {
+func NewFlashCardsHandler(storage *taskstorage.RedisStorage, ws *hub.WebSocketHub, session *service.SessionService, cards *service.FlashCardsService) *FlashCardsHandler {
 	return &FlashCardsHandler{
 		storage: storage,
 		session: session,
+		cards:   cards,
 		ws:      ws,
 	}
 }
@@ -60,3 +64,380 @@ func (h *FlashCardsHandler) FlashCards(c *gin.Context) {
 		"flashcards": flCards,
 	})
 }
+
+// GET /api/flashcards?lang_id=
+// GET /api/flashcards?q=&limit=
+func (h *FlashCardsHandler) List(c *gin.Context) {
+	if q := c.Query("q"); q != "" {
+		h.query(c, q)
+		return
+	}
+
+	var langId int
+	if v := c.Query("lang_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid lang_id",
+				"details": err.Error(),
+			})
+			return
+		}
+		langId = id
+	}
+
+	cards, err := h.cards.List(c.Request.Context(), langId)
+	if err != nil {
+		respondCardErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"flashcards": cards,
+		"count":      len(cards),
+	})
+}
+
+// query lists the flashcards matching a card query, see card_query for the syntax
+func (h *FlashCardsHandler) query(c *gin.Context, q string) {
+	limit, ok := parseLimit(c)
+	if !ok {
+		return
+	}
+
+	cards, err := h.cards.Query(c.Request.Context(), q, limit)
+	if err != nil {
+		respondCardErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"query":      q,
+		"flashcards": cards,
+		"count":      len(cards),
+	})
+}
+
+// GET /api/vocabulary/search?q=&lang_id=&limit=
+func (h *FlashCardsHandler) Search(c *gin.Context) {
+	var req requests.VocabularySearch
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	hits, err := h.cards.Search(c.Request.Context(), req)
+	if err != nil {
+		respondCardErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"query":   req.Query,
+		"results": hits,
+		"count":   len(hits),
+	})
+}
+
+// GET /api/tags
+func (h *FlashCardsHandler) Tags(c *gin.Context) {
+	tags, err := h.cards.Tags(c.Request.Context())
+	if err != nil {
+		respondCardErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tags": tags,
+	})
+}
+
+// POST /api/flashcards/:cardId/tags
+func (h *FlashCardsHandler) AddTags(c *gin.Context) {
+	var req requests.AddTags
+
+	cardId, ok := parseCardId(c)
+	if !ok {
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	card, err := h.cards.AddTags(c.Request.Context(), cardId, req.Tags)
+	if err != nil {
+		respondCardErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"flashcard": card,
+	})
+}
+
+// DELETE /api/flashcards/:cardId/tags/:tag
+func (h *FlashCardsHandler) RemoveTag(c *gin.Context) {
+	cardId, ok := parseCardId(c)
+	if !ok {
+		return
+	}
+
+	tag := c.Param("tag")
+	if err := h.cards.RemoveTag(c.Request.Context(), cardId, tag); err != nil {
+		respondCardErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"flashcard_id": cardId,
+		"tag":          tag,
+		"removed":      true,
+	})
+}
+
+// POST /api/flashcards/reviews
+func (h *FlashCardsHandler) RecordReviews(c *gin.Context) {
+	var req requests.RecordReviews
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	saved, err := h.cards.RecordReviews(c.Request.Context(), req.Answers)
+	if err != nil {
+		respondCardErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"saved": saved,
+	})
+}
+
+// GET /api/flashcards/:cardId
+func (h *FlashCardsHandler) Get(c *gin.Context) {
+	cardId, ok := parseCardId(c)
+	if !ok {
+		return
+	}
+
+	card, err := h.cards.Get(c.Request.Context(), cardId)
+	if err != nil {
+		respondCardErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"flashcard": card,
+	})
+}
+
+// POST /api/flashcards
+func (h *FlashCardsHandler) Create(c *gin.Context) {
+	var req requests.CreateFlashcard
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	card, err := h.cards.Create(c.Request.Context(), req)
+	if err != nil {
+		respondCardErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"flashcard": card,
+	})
+}
+
+// PATCH /api/flashcards/:cardId
+func (h *FlashCardsHandler) Update(c *gin.Context) {
+	var req requests.UpdateFlashcard
+
+	cardId, ok := parseCardId(c)
+	if !ok {
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	card, err := h.cards.Update(c.Request.Context(), cardId, req)
+	if err != nil {
+		respondCardErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"flashcard": card,
+	})
+}
+
+// DELETE /api/flashcards/:cardId
+func (h *FlashCardsHandler) Delete(c *gin.Context) {
+	cardId, ok := parseCardId(c)
+	if !ok {
+		return
+	}
+
+	if err := h.cards.Delete(c.Request.Context(), cardId); err != nil {
+		respondCardErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"flashcard_id": cardId,
+		"deleted":      true,
+	})
+}
+
+// POST /api/flashcards/move
+func (h *FlashCardsHandler) Move(c *gin.Context) {
+	var req requests.MoveFlashcards
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	moved, err := h.cards.Move(c.Request.Context(), req)
+	if err != nil {
+		respondCardErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from_deck_id": req.FromDeckId,
+		"to_deck_id":   req.ToDeckId,
+		"moved":        moved,
+	})
+}
+
+// POST /api/flashcards/copy
+func (h *FlashCardsHandler) Copy(c *gin.Context) {
+	var req requests.CopyFlashcards
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	copied, err := h.cards.Copy(c.Request.Context(), req)
+	if err != nil {
+		respondCardErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"to_deck_id": req.ToDeckId,
+		"copied":     copied,
+	})
+}
+
+func parseCardId(c *gin.Context) (uuid.UUID, bool) {
+	cardId, err := uuid.Parse(c.Param("cardId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid cardId",
+			"details": err.Error(),
+		})
+		return uuid.Nil, false
+	}
+
+	return cardId, true
+}
+
+func parseLimit(c *gin.Context) (int, bool) {
+	v := c.Query("limit")
+	if v == "" {
+		return 0, true
+	}
+
+	limit, err := strconv.Atoi(v)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid limit",
+			"details": err.Error(),
+		})
+		return 0, false
+	}
+
+	return limit, true
+}
+
+func respondCardErr(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnauthorized):
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "user is unauthorized",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrFlashcardNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "flashcard not found",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrDeckNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "deck not found",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrFlashcardExists):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "flashcard already exists",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrInvalidFlashcard):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid flashcard",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrTagNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "tag not found",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrInvalidTag):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid tag",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrInvalidSearch):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid search",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrInvalidQuery):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid query",
+			"details": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal error",
+			"details": err.Error(),
+		})
+	}
+}
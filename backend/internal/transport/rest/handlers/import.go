@@ -0,0 +1,156 @@
+package rest_handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rwrrioe/pythia/backend/internal/domain/requests"
+	service "github.com/rwrrioe/pythia/backend/internal/services"
+)
+
+type ImportHandler struct {
+	imports *service.ImportService
+}
+
+func NewImportHandler(imports *service.ImportService) *ImportHandler {
+	return &ImportHandler{imports: imports}
+}
+
+// POST /api/imports
+// multipart form with the file and the import options, the import runs in the background
+func (h *ImportHandler) Start(c *gin.Context) {
+	var req requests.ImportVocabulary
+
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "no file",
+			"details": err.Error(),
+		})
+		return
+	}
+	if fileHeader.Size > service.MaxImportBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": "file is too large",
+		})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "can't open file",
+			"details": err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, service.MaxImportBytes+1))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "error while reading file",
+			"details": err.Error(),
+		})
+		return
+	}
+	if len(data) > service.MaxImportBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": "file is too large",
+		})
+		return
+	}
+
+	imp, err := h.imports.Start(c.Request.Context(), req, fileHeader.Filename, data)
+	if err != nil {
+		respondImportErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"import": imp,
+	})
+}
+
+// GET /api/imports/:importId
+func (h *ImportHandler) Get(c *gin.Context) {
+	imp, err := h.imports.Get(c.Request.Context(), c.Param("importId"))
+	if err != nil {
+		respondImportErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"import": imp,
+	})
+}
+
+// POST /api/imports/:importId/confirm
+// imports the file of a finished dry run for real
+func (h *ImportHandler) Confirm(c *gin.Context) {
+	imp, err := h.imports.Confirm(c.Request.Context(), c.Param("importId"))
+	if err != nil {
+		respondImportErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"import": imp,
+	})
+}
+
+// DELETE /api/imports/:importId
+func (h *ImportHandler) Cancel(c *gin.Context) {
+	importId := c.Param("importId")
+
+	if err := h.imports.Cancel(c.Request.Context(), importId); err != nil {
+		respondImportErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"import_id": importId,
+		"status":    service.TaskCancelled,
+	})
+}
+
+func respondImportErr(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidImport):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid import",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrImportNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "import not found",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrImportConfirmed):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "import already confirmed",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrImportNotPreviewed), errors.Is(err, service.ErrTaskNotRunning):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "import can't be changed",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrUploadExpired):
+		c.JSON(http.StatusGone, gin.H{
+			"error":   "upload has expired",
+			"details": err.Error(),
+		})
+	default:
+		respondDeckErr(c, err)
+	}
+}
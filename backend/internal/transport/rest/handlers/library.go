@@ -8,6 +8,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
+	"github.com/rwrrioe/pythia/backend/internal/domain/requests"
 	service "github.com/rwrrioe/pythia/backend/internal/services"
 )
 
@@ -25,26 +26,53 @@ func NewLibraryHandler(library *service.LibraryService, flashcards *service.Flas
 	}
 }
 
-// GET /api/library/sessions
+// GET /api/library/session?cursor=&limit=&lang_id=&status=&from=&to=&min_accuracy=&max_accuracy=&sort=&q=&archived=
 func (h *LibraryHandler) ListSession(c *gin.Context) {
+	var req requests.LibraryQuery
 
-	ctx := c.Request.Context()
-	ss, err := h.library.Library(ctx)
-
-	if err != nil {
-		if errors.Is(err, service.ErrUnauthorized) {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": err.Error(),
-			})
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid query",
+			"details": err.Error(),
+		})
+		return
+	}
 
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": err.Error(),
-			})
-		}
+	ctx := c.Request.Context()
+	page, err := h.library.Library(ctx, req)
+	switch {
+	case err == nil:
+	case errors.Is(err, service.ErrUnauthorized):
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "user is unauthorized",
+			"details": err.Error(),
+		})
+		return
+	case errors.Is(err, service.ErrInvalidFilter):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid filter",
+			"details": err.Error(),
+		})
+		return
+	case errors.Is(err, service.ErrInvalidCursor):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid cursor",
+			"details": err.Error(),
+		})
+		return
+	default:
+		h.log.Error("library request failed", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal error",
+			"details": err.Error(),
+		})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"sessions": ss,
+		"sessions":    page.Sessions,
+		"next_cursor": page.NextCursor,
+		"has_more":    page.NextCursor != "",
 	})
 }
 
@@ -104,8 +132,10 @@ func (h *LibraryHandler) GetSession(c *gin.Context) {
 	var dtos []entities.FlashCardDTO
 	for _, fl := range flashcards {
 		dtos = append(dtos, entities.FlashCardDTO{
+			Id:          fl.Id,
 			Word:        fl.Word,
 			Translation: fl.Transl,
+			Description: fl.Desc,
 			Lang:        service.LangsMap[fl.Lang],
 		})
 	}
@@ -115,3 +145,85 @@ func (h *LibraryHandler) GetSession(c *gin.Context) {
 		"session":    session,
 	})
 }
+
+// GET /api/library/session/:sessionId/documents
+func (h *LibraryHandler) ListDocuments(c *gin.Context) {
+	sessionId, err := uuid.Parse(c.Param("sessionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid sessionId",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	docs, err := h.library.ListDocuments(ctx, sessionId)
+	if err != nil {
+		h.respondDocumentErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session_id": sessionId,
+		"documents":  docs,
+	})
+}
+
+// GET /api/library/session/:sessionId/documents/:documentId
+func (h *LibraryHandler) GetDocument(c *gin.Context) {
+	sessionId, err := uuid.Parse(c.Param("sessionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid sessionId",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	documentId, err := uuid.Parse(c.Param("documentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid documentId",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	doc, err := h.library.GetDocument(ctx, sessionId, documentId)
+	if err != nil {
+		h.respondDocumentErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"document": doc,
+	})
+}
+
+func (h *LibraryHandler) respondDocumentErr(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnauthorized):
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "user is unauthorized",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrSessionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "session not found",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrDocumentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "document not found",
+			"details": err.Error(),
+		})
+	default:
+		h.log.Error("library request failed", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal error",
+			"details": err.Error(),
+		})
+	}
+}
@@ -1,30 +1,29 @@
 package rest_handlers
 
 import (
-	"context"
+	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
-	"time"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/rwrrioe/pythia/backend/internal/auth/authn"
 	service "github.com/rwrrioe/pythia/backend/internal/services"
 	storage "github.com/rwrrioe/pythia/backend/internal/storage/redis/task_storage"
-	hub "github.com/rwrrioe/pythia/backend/internal/transport/ws/ws_hub"
 )
 
 type OCRHandler struct {
 	storage *storage.RedisStorage
 	session *service.SessionService
-	ws      *hub.WebSocketHub
+	jobs    *service.JobService
 }
 
-func NewOCRHandler(storage *storage.RedisStorage, ws *hub.WebSocketHub, session *service.SessionService) *OCRHandler {
+func NewOCRHandler(storage *storage.RedisStorage, jobs *service.JobService, session *service.SessionService) *OCRHandler {
 	return &OCRHandler{
 		session: session,
-		ws:      ws,
+		jobs:    jobs,
 		storage: storage,
 	}
 }
@@ -43,16 +42,15 @@ func (h *OCRHandler) Upload(c *gin.Context) {
 		return
 	}
 
-	taskID := c.PostForm("task_id")
-	if taskID == "" {
-		taskID = uuid.NewString()
+	taskID, ok := h.taskId(c)
+	if !ok {
+		return
 	}
 
+	// optional, the session language or the detected one is used when omitted
 	lang := c.PostForm("lang")
-	if lang == "" {
-		h.respondOCRErr(c, fmt.Errorf("no language"), http.StatusBadRequest, "no language")
-		return
-	}
+	// ocr, words or examples, the stages after ocr are started automatically
+	pipeline := c.PostForm("pipeline")
 
 	fileHeader, err := c.FormFile("file")
 	if err != nil {
@@ -60,66 +58,211 @@ func (h *OCRHandler) Upload(c *gin.Context) {
 		return
 	}
 
+	data, ok := h.readImage(c, fileHeader)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.jobs.EnqueueOCR(ctx, sessionId, taskID, [][]byte{data}, lang, false, pipeline); err != nil {
+		h.respondEnqueueErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"task_id":    taskID,
+		"session_id": sessionId,
+		"stage":      "ocr",
+		"status":     service.TaskQueued})
+}
+
+// maxTaskIdLen matches the task_id columns the results are saved in
+const maxTaskIdLen = 64
+
+// taskId reads the task id picked by the client or makes one, the error response is written when it's too long
+func (h *OCRHandler) taskId(c *gin.Context) (string, bool) {
+	taskID := c.PostForm("task_id")
+	if taskID == "" {
+		return uuid.NewString(), true
+	}
+	if len(taskID) > maxTaskIdLen {
+		h.respondOCRErr(c, fmt.Errorf("task_id is limited to %d bytes", maxTaskIdLen), http.StatusBadRequest, "invalid task_id")
+		return "", false
+	}
+
+	return taskID, true
+}
+
+const (
+	// MaxUploadPages limits the number of files in one multi-page upload
+	MaxUploadPages = 30
+	// MaxFormOverhead is the room a multipart body gets for the form fields and part headers
+	MaxFormOverhead = 1 << 20
+)
+
+// readImage reads and preprocesses one uploaded image, the error response is written on failure
+func (h *OCRHandler) readImage(c *gin.Context, fileHeader *multipart.FileHeader) ([]byte, bool) {
+	maxBytes := h.session.OCR.MaxUploadBytes()
+	if fileHeader.Size > maxBytes {
+		h.respondOCRErr(c, service.ErrImageTooLarge, http.StatusRequestEntityTooLarge, "file is too large")
+		return nil, false
+	}
+
 	file, err := fileHeader.Open()
 	if err != nil {
 		h.respondOCRErr(c, err, http.StatusInternalServerError, "can't open file")
-		return
+		return nil, false
 	}
+	defer file.Close()
 
-	defer func() {
-		if err := file.Close(); err != nil {
-			h.respondOCRErr(c, err, http.StatusInternalServerError, "can't close file")
-			return
+	data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil {
+		h.respondOCRErr(c, err, http.StatusInternalServerError, "error while reading file")
+		return nil, false
+	}
+
+	data, err = h.session.OCR.Preprocess(data)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrImageTooLarge):
+			h.respondOCRErr(c, err, http.StatusRequestEntityTooLarge, "file is too large")
+		case errors.Is(err, service.ErrUnsupportedImage):
+			h.respondOCRErr(c, err, http.StatusUnsupportedMediaType, "unsupported image")
+		default:
+			h.respondOCRErr(c, err, http.StatusInternalServerError, "can't preprocess image")
 		}
-	}()
+		return nil, false
+	}
 
-	data, err := io.ReadAll(file)
+	return data, true
+}
+
+// /api/session/:sessionId/upload/pages
+// every page is pushed over the websocket as soon as it is recognized
+func (h *OCRHandler) UploadPages(c *gin.Context) {
+	sessionId, err := uuid.Parse(c.Param("sessionId"))
 	if err != nil {
-		h.respondOCRErr(c, err, http.StatusInternalServerError, "error while reading file")
+		h.respondOCRErr(c, err, http.StatusBadRequest, "invalid sessionId")
 		return
 	}
 
-	ctx := c.Request.Context()
+	// the body is limited before the form is parsed, the files are spooled to disk otherwise
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxUploadPages*h.session.OCR.MaxUploadBytes()+MaxFormOverhead)
+	form, err := c.MultipartForm()
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.respondOCRErr(c, err, http.StatusRequestEntityTooLarge, "upload is too large")
+			return
+		}
+		h.respondOCRErr(c, err, http.StatusBadRequest, "no files")
+		return
+	}
 
-	uid, ok := authn.UIDFromContext(ctx)
+	taskID, ok := h.taskId(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error":   "user is unauthorized",
-			"details": "",
-		})
 		return
 	}
 
-	//creating background ctx !!TODO -> implement on gin
-	bgCtx := context.WithValue(context.Background(), "user_id", uid)
-	bgCtx, cancel := context.WithTimeout(bgCtx, 2*time.Minute)
-	go func(ctx context.Context) {
-		defer cancel()
-		h.ws.Notify(sessionId, gin.H{
-			"task_id":    taskID,
-			"session_id": sessionId,
-			"status":     "processing",
-			"stage":      "ocr",
-		})
-		err := h.session.RecognizeText(ctx, sessionId, taskID, data, lang)
-		if err != nil {
-			h.ws.Notify(sessionId, gin.H{
-				"task_id":    taskID,
-				"session_id": sessionId,
-				"status":     "error",
-				"error":      err.Error(),
-				"stage":      "ocr"})
+	lang := c.PostForm("lang")
+	pipeline := c.PostForm("pipeline")
+
+	files := form.File["files"]
+	if len(files) == 0 {
+		h.respondOCRErr(c, errors.New("files field is empty"), http.StatusBadRequest, "no files")
+		return
+	}
+	if len(files) > MaxUploadPages {
+		h.respondOCRErr(c, fmt.Errorf("at most %d pages are allowed", MaxUploadPages), http.StatusRequestEntityTooLarge, "too many pages")
+		return
+	}
+
+	pages := make([][]byte, 0, len(files))
+	for _, fh := range files {
+		data, ok := h.readImage(c, fh)
+		if !ok {
 			return
 		}
+		pages = append(pages, data)
+	}
+
+	total := len(pages)
+	ctx := c.Request.Context()
+	if err := h.jobs.EnqueueOCR(ctx, sessionId, taskID, pages, lang, true, pipeline); err != nil {
+		h.respondEnqueueErr(c, err)
+		return
+	}
 
-		h.ws.Notify(sessionId, gin.H{
-			"task_id":    taskID,
-			"session_id": sessionId,
-			"status":     "done",
-			"stage":      "ocr"})
-	}(bgCtx)
 	c.JSON(http.StatusAccepted, gin.H{
-		"task_id":    taskID,
-		"session_id": sessionId,
-		"stage":      "ocr"})
+		"task_id":     taskID,
+		"session_id":  sessionId,
+		"stage":       "ocr",
+		"status":      service.TaskQueued,
+		"total_pages": total})
+}
+
+func (h *OCRHandler) respondEnqueueErr(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnauthorized):
+		h.respondOCRErr(c, err, http.StatusUnauthorized, "user is unauthorized")
+	case errors.Is(err, service.ErrForbidden):
+		h.respondOCRErr(c, err, http.StatusForbidden, "access forbidden")
+	case errors.Is(err, service.ErrInvalidPipeline):
+		h.respondOCRErr(c, err, http.StatusBadRequest, "invalid pipeline")
+	case errors.Is(err, service.ErrTaskExists):
+		h.respondOCRErr(c, err, http.StatusConflict, "task id already in use")
+	case errors.Is(err, service.ErrSessionNotFound):
+		h.respondOCRErr(c, err, http.StatusNotFound, "session not found")
+	default:
+		h.respondOCRErr(c, err, http.StatusInternalServerError, "can't enqueue task")
+	}
+}
+
+// GET /api/session/:sessionId/task/:taskId/ocr?min_confidence=0.5
+// the boxes are in the space of the upright and downscaled image, image_width and image_height give its size
+func (h *OCRHandler) Result(c *gin.Context) {
+	sessionId, err := uuid.Parse(c.Param("sessionId"))
+	if err != nil {
+		h.respondOCRErr(c, err, http.StatusBadRequest, "invalid sessionId")
+		return
+	}
+
+	taskId := c.Param("taskId")
+
+	var minConfidence float64
+	if v := c.Query("min_confidence"); v != "" {
+		minConfidence, err = strconv.ParseFloat(v, 32)
+		if err != nil || minConfidence < 0 || minConfidence > 1 {
+			h.respondOCRErr(c, fmt.Errorf("min_confidence must be in [0, 1]"), http.StatusBadRequest, "invalid min_confidence")
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	res, err := h.session.GetOCRResult(ctx, sessionId, taskId)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnauthorized):
+			h.respondOCRErr(c, err, http.StatusUnauthorized, "user is unauthorized")
+		case errors.Is(err, service.ErrForbidden):
+			h.respondOCRErr(c, err, http.StatusForbidden, "access forbidden")
+		case errors.Is(err, service.ErrTaskNotFound):
+			h.respondOCRErr(c, err, http.StatusNotFound, "task not found")
+		default:
+			h.respondOCRErr(c, err, http.StatusInternalServerError, "internal error")
+		}
+		return
+	}
+
+	res = res.Filter(float32(minConfidence))
+
+	c.JSON(http.StatusOK, gin.H{
+		"task_id":      taskId,
+		"session_id":   sessionId,
+		"stage":        "ocr",
+		"text":         res.Text(),
+		"lines":        res.Lines,
+		"image_width":  res.ImageWidth,
+		"image_height": res.ImageHeight,
+	})
 }
@@ -16,15 +16,17 @@ import (
 type SessionHandler struct {
 	storage *taskstorage.RedisStorage
 	session *service.SessionService
+	jobs    *service.JobService
 	ws      *hub.WebSocketHub
 }
 
-func NewSessionHandler(storage *taskstorage.RedisStorage, ws *hub.WebSocketHub, session *service.SessionService) *SessionHandler {
+func NewSessionHandler(storage *taskstorage.RedisStorage, ws *hub.WebSocketHub, session *service.SessionService, jobs *service.JobService) *SessionHandler {
 
 	return &SessionHandler{
 		storage: storage,
 		ws:      ws,
 		session: session,
+		jobs:    jobs,
 	}
 }
 
@@ -43,6 +45,20 @@ func (h *SessionHandler) NewSession(c *gin.Context) {
 	ctx := c.Request.Context()
 
 	id, err := h.session.StartSession(ctx, req)
+	if err != nil && errors.Is(err, service.ErrInvalidDuration) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid duration",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err != nil && errors.Is(err, service.ErrInvalidLevel) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid level",
+			"details": err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   err,
@@ -122,6 +138,83 @@ func (h *SessionHandler) EndSession(c *gin.Context) {
 	})
 }
 
+// /api/session/:sessionId/reopen
+func (h *SessionHandler) ReopenSession(c *gin.Context) {
+	var req requests.ReopenSession
+
+	sessionId, err := uuid.Parse(c.Param("sessionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid sessionId",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// the body is optional
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	err = h.session.ReopenSession(ctx, sessionId, req.Duration)
+	switch {
+	case err == nil:
+	case errors.Is(err, service.ErrUnauthorized):
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "user is unauthorized",
+			"details": err.Error(),
+		})
+		return
+	case errors.Is(err, service.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "access forbidden",
+			"details": err.Error(),
+		})
+		return
+	case errors.Is(err, service.ErrInvalidDuration):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid duration",
+			"details": err.Error(),
+		})
+		return
+	case errors.Is(err, service.ErrSessionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "session not found",
+			"details": err.Error(),
+		})
+		return
+	case errors.Is(err, service.ErrSessionNotFinished):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "session is not finished",
+			"details": err.Error(),
+		})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal error",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	h.ws.Notify(sessionId, gin.H{
+		"session_id": sessionId,
+		"status":     service.Active,
+		"stage":      "session",
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"session_id": sessionId,
+		"status":     service.Active,
+	})
+}
+
 // /api/session/:sessionId/summary
 func (h *SessionHandler) SessionSummary(c *gin.Context) {
 	var req requests.SummarizeSession
@@ -185,3 +278,179 @@ func (h *SessionHandler) SessionSummary(c *gin.Context) {
 	})
 
 }
+
+// PATCH /api/session/:sessionId/rename
+func (h *SessionHandler) RenameSession(c *gin.Context) {
+	var req requests.RenameSession
+
+	sessionId, err := uuid.Parse(c.Param("sessionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid sessionId",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	name, err := h.session.RenameSession(c.Request.Context(), sessionId, req.Name)
+	if err != nil {
+		respondManageErr(c, err)
+		return
+	}
+
+	h.ws.Notify(sessionId, gin.H{
+		"session_id": sessionId,
+		"name":       name,
+		"stage":      "session",
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"session_id": sessionId,
+		"name":       name,
+	})
+}
+
+// POST /api/session/:sessionId/title
+func (h *SessionHandler) GenerateTitle(c *gin.Context) {
+	sessionId, err := uuid.Parse(c.Param("sessionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid sessionId",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	name, err := h.session.GenerateTitle(c.Request.Context(), sessionId)
+	if err != nil {
+		respondManageErr(c, err)
+		return
+	}
+
+	h.ws.Notify(sessionId, gin.H{
+		"session_id": sessionId,
+		"name":       name,
+		"stage":      "session",
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"session_id": sessionId,
+		"name":       name,
+	})
+}
+
+// PATCH /api/session/:sessionId/archive
+func (h *SessionHandler) ArchiveSession(c *gin.Context) {
+	h.setArchived(c, true)
+}
+
+// PATCH /api/session/:sessionId/unarchive
+func (h *SessionHandler) UnarchiveSession(c *gin.Context) {
+	h.setArchived(c, false)
+}
+
+func (h *SessionHandler) setArchived(c *gin.Context, archived bool) {
+	sessionId, err := uuid.Parse(c.Param("sessionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid sessionId",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.session.ArchiveSession(c.Request.Context(), sessionId, archived); err != nil {
+		respondManageErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session_id": sessionId,
+		"archived":   archived,
+	})
+}
+
+// DELETE /api/session/:sessionId
+func (h *SessionHandler) DeleteSession(c *gin.Context) {
+	sessionId, err := uuid.Parse(c.Param("sessionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid sessionId",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	// stop the workers before their results lose the session
+	if err := h.jobs.Discard(ctx, sessionId); err != nil {
+		respondManageErr(c, err)
+		return
+	}
+
+	removed, err := h.session.DeleteSession(ctx, sessionId)
+	if err != nil {
+		respondManageErr(c, err)
+		return
+	}
+
+	h.ws.Notify(sessionId, gin.H{
+		"session_id": sessionId,
+		"status":     "deleted",
+		"stage":      "session",
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"session_id":         sessionId,
+		"deleted":            true,
+		"flashcards_removed": removed,
+	})
+}
+
+func respondManageErr(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnauthorized):
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "user is unauthorized",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "access forbidden",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrSessionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "session not found",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrInvalidName):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid name",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrSessionActive):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "session is still active",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrNoText):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "session has no text",
+			"details": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal error",
+			"details": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package rest_handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	service "github.com/rwrrioe/pythia/backend/internal/services"
+	taskstorage "github.com/rwrrioe/pythia/backend/internal/storage/redis/task_storage"
+)
+
+type TaskHandler struct {
+	session *service.SessionService
+	jobs    *service.JobService
+}
+
+func NewTaskHandler(session *service.SessionService, jobs *service.JobService) *TaskHandler {
+	return &TaskHandler{
+		session: session,
+		jobs:    jobs,
+	}
+}
+
+// GET /api/session/:sessionId/task/:taskId
+func (h *TaskHandler) Get(c *gin.Context) {
+	sessionId, err := uuid.Parse(c.Param("sessionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid sessionId",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	taskId := c.Param("taskId")
+
+	ctx := c.Request.Context()
+	t, err := h.session.GetTask(ctx, sessionId, taskId)
+	if err != nil {
+		h.respondTaskErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, taskView(t))
+}
+
+// GET /api/session/:sessionId/tasks
+func (h *TaskHandler) List(c *gin.Context) {
+	sessionId, err := uuid.Parse(c.Param("sessionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid sessionId",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tasks, err := h.session.ListTasks(ctx, sessionId)
+	if err != nil {
+		h.respondTaskErr(c, err)
+		return
+	}
+
+	out := make([]gin.H, 0, len(tasks))
+	for i := range tasks {
+		out = append(out, taskView(&tasks[i]))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session_id": sessionId,
+		"tasks":      out,
+	})
+}
+
+// POST /api/session/:sessionId/task/:taskId/cancel
+func (h *TaskHandler) Cancel(c *gin.Context) {
+	sessionId, err := uuid.Parse(c.Param("sessionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid sessionId",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	taskId := c.Param("taskId")
+
+	ctx := c.Request.Context()
+	if err := h.jobs.Cancel(ctx, sessionId, taskId); err != nil {
+		h.respondTaskErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"session_id": sessionId,
+		"task_id":    taskId,
+		"status":     service.TaskCancelled,
+	})
+}
+
+// POST /api/session/:sessionId/task/:taskId/retry
+func (h *TaskHandler) Retry(c *gin.Context) {
+	sessionId, err := uuid.Parse(c.Param("sessionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid sessionId",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	taskId := c.Param("taskId")
+
+	ctx := c.Request.Context()
+	stage, err := h.jobs.Retry(ctx, sessionId, taskId)
+	if err != nil {
+		h.respondTaskErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"session_id": sessionId,
+		"task_id":    taskId,
+		"stage":      stage,
+		"status":     service.TaskQueued,
+	})
+}
+
+// taskView leaves out the line boxes and pages, they are served by the ocr result endpoint
+func taskView(t *taskstorage.TaskDTO) gin.H {
+	return gin.H{
+		"task_id":       t.Id,
+		"session_id":    t.SessionId,
+		"stage":         t.Stage,
+		"status":        t.Status,
+		"error":         t.Error,
+		"ocr_text":      t.OCRText,
+		"detected_lang": t.DetectedLang,
+		"words":         t.Words,
+		"examples":      t.Examples,
+		"pipeline":      t.Pipeline,
+		"updated_at":    t.UpdatedAt,
+	}
+}
+
+func (h *TaskHandler) respondTaskErr(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnauthorized):
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "user is unauthorized",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "access forbidden",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrTaskNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "task not found",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrTaskNotRunning):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "task is not running",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrTaskNotFailed):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "only failed or cancelled tasks can be retried",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrUploadExpired):
+		c.JSON(http.StatusGone, gin.H{
+			"error":   "upload has expired, upload the file again",
+			"details": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal error",
+			"details": err.Error(),
+		})
+	}
+}
@@ -1,30 +1,26 @@
 package rest_handlers
 
 import (
-	"context"
 	"errors"
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/rwrrioe/pythia/backend/internal/auth/authn"
 	"github.com/rwrrioe/pythia/backend/internal/domain/requests"
 	service "github.com/rwrrioe/pythia/backend/internal/services"
 	taskstorage "github.com/rwrrioe/pythia/backend/internal/storage/redis/task_storage"
-	hub "github.com/rwrrioe/pythia/backend/internal/transport/ws/ws_hub"
 )
 
 type TranslateHandler struct {
 	storage *taskstorage.RedisStorage
-	ws      *hub.WebSocketHub
+	jobs    *service.JobService
 	session *service.SessionService
 }
 
-func NewTranslateHandler(storage *taskstorage.RedisStorage, ws *hub.WebSocketHub, session *service.SessionService) *TranslateHandler {
+func NewTranslateHandler(storage *taskstorage.RedisStorage, jobs *service.JobService, session *service.SessionService) *TranslateHandler {
 	return &TranslateHandler{
 		storage: storage,
-		ws:      ws,
+		jobs:    jobs,
 		session: session,
 	}
 }
@@ -51,62 +47,56 @@ func (h *TranslateHandler) Translate(c *gin.Context) {
 		return
 	}
 
-	ctx := c.Request.Context()
-
-	uid, _ := authn.UIDFromContext(ctx)
+	h.enqueueStage(c, sessionId, taskId, service.StageTranslate)
+}
 
-	bgCtx := context.WithValue(context.Background(), "user_id", uid)
-	bgCtx, cancel := context.WithTimeout(bgCtx, 2*time.Minute)
-	go func(ctx context.Context) {
-		defer cancel()
-		h.ws.Notify(sessionId, gin.H{
-			"session_id": sessionId,
-			"task_id":    taskId,
-			"status":     "processing",
-			"stage":      "translate",
+// Examples post /api/session/:sessionId/task/:taskId/examples
+func (h *TranslateHandler) Examples(c *gin.Context) {
+	sessionId, err := uuid.Parse(c.Param("sessionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid sessionId",
+			"details": err.Error(),
 		})
+		return
+	}
 
-		words, err := h.session.FindWords(ctx, sessionId, taskId)
-		if err != nil {
-			if errors.Is(err, service.ErrSessionNotFound) {
-				h.ws.Notify(sessionId, gin.H{
-					"session_id": sessionId,
-					"task_id":    taskId,
-					"error":      "session not found",
-				})
-				return
-			}
-
-			if errors.Is(err, service.ErrTaskNotFound) {
-				h.ws.Notify(sessionId, gin.H{
-					"session_id": sessionId,
-					"task_id":    taskId,
-					"stage":      "translate",
-					"error":      "task not found",
-				})
-				return
-			}
+	h.enqueueStage(c, sessionId, c.Param("taskId"), service.StageExamples)
+}
 
-			h.ws.Notify(sessionId, gin.H{
-				"session_id": sessionId,
-				"task_id":    taskId,
-				"stage":      "translate",
-				"error":      err.Error(),
+// enqueueStage starts a single stage, the rest of the task pipeline follows it
+func (h *TranslateHandler) enqueueStage(c *gin.Context, sessionId uuid.UUID, taskId, stage string) {
+	ctx := c.Request.Context()
+	if err := h.jobs.EnqueueStage(ctx, sessionId, taskId, stage); err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnauthorized):
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":   "user is unauthorized",
+				"details": err.Error(),
+			})
+		case errors.Is(err, service.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "access forbidden",
+				"details": err.Error(),
+			})
+		case errors.Is(err, service.ErrTaskNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "task not found",
+				"details": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "can't enqueue task",
+				"details": err.Error(),
 			})
-			return
 		}
+		return
+	}
 
-		h.ws.Notify(sessionId, gin.H{
-			"status":     "done",
-			"stage":      "translate",
-			"session_id": sessionId,
-			"task_id":    taskId,
-			"words":      words,
-		})
-	}(bgCtx)
 	c.JSON(http.StatusAccepted, gin.H{
 		"session_id": sessionId,
 		"task_id":    taskId,
-		"stage":      "translate",
+		"stage":      stage,
+		"status":     service.TaskQueued,
 	})
 }
@@ -0,0 +1,261 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rwrrioe/pythia/backend/internal/auth/authn"
+	"github.com/rwrrioe/pythia/backend/internal/lib/logger/sl"
+	storage "github.com/rwrrioe/pythia/backend/internal/storage/redis/task_storage"
+)
+
+const (
+	Header         = "Idempotency-Key"
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLen = 255
+	// bodies up to memBody are hashed in memory, larger ones are spooled to a temporary file
+	memBody = 1 << 20
+)
+
+type Store interface {
+	ReserveIdempotencyKey(ctx context.Context, uid int64, key, fingerprint string, ttl time.Duration) (*storage.IdempotentRecord, bool, error)
+	SaveIdempotentResponse(ctx context.Context, uid int64, key string, rec storage.IdempotentRecord, ttl time.Duration) error
+	ReleaseIdempotencyKey(ctx context.Context, uid int64, key string) error
+}
+
+// New replays the stored response when a request is repeated with the same Idempotency-Key.
+// Keys are scoped to the user, the same key with another request is rejected with 409.
+// Server errors are not stored so the request may be retried with its key.
+// Bodies over maxBody are rejected with 413 before they are read in full.
+func New(
+	log *slog.Logger,
+	store Store,
+	ttl, lockTTL time.Duration,
+	maxBody int64,
+) func() gin.HandlerFunc {
+
+	return func() gin.HandlerFunc {
+		return func(c *gin.Context) {
+			key := c.GetHeader(Header)
+			if key == "" {
+				c.Next()
+				return
+			}
+
+			if len(key) > maxKeyLen {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+					"error":   "invalid idempotency key",
+					"details": fmt.Sprintf("key must be at most %d characters", maxKeyLen),
+				})
+				return
+			}
+
+			// without a user the handler rejects the request anyway
+			uid, ok := authn.UIDFromContext(c.Request.Context())
+			if !ok {
+				c.Next()
+				return
+			}
+
+			if c.Request.ContentLength > maxBody {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+					"error":   "request too large",
+					"details": fmt.Sprintf("the body is limited to %d bytes", maxBody),
+				})
+				return
+			}
+
+			body, cleanup, err := spool(http.MaxBytesReader(c.Writer, c.Request.Body, maxBody))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+						"error":   "request too large",
+						"details": fmt.Sprintf("the body is limited to %d bytes", maxBody),
+					})
+					return
+				}
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+					"error":   "can't read request",
+					"details": err.Error(),
+				})
+				return
+			}
+			defer cleanup()
+
+			fp, err := fingerprint(c.Request, body)
+			if err == nil {
+				_, err = body.Seek(0, io.SeekStart)
+			}
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+					"error":   "malformed request",
+					"details": err.Error(),
+				})
+				return
+			}
+			c.Request.Body = io.NopCloser(body)
+
+			ctx := c.Request.Context()
+			rec, reserved, err := store.ReserveIdempotencyKey(ctx, uid, key, fp, lockTTL)
+			if err != nil {
+				// the endpoints worked without keys before, a redis failure shouldn't block them
+				log.Error("failed to reserve idempotency key", sl.Err(err))
+				c.Next()
+				return
+			}
+
+			if !reserved {
+				switch {
+				case rec.Fingerprint != fp:
+					c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+						"error":   "idempotency key reused",
+						"details": "the key was already used with a different request",
+					})
+				case !rec.Done:
+					c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+						"error":   "request in progress",
+						"details": "a request with this key is still being processed",
+					})
+				default:
+					c.Header(ReplayedHeader, "true")
+					c.Data(rec.Status, rec.ContentType, rec.Body)
+					c.Abort()
+				}
+				return
+			}
+
+			rw := &recorder{ResponseWriter: c.Writer}
+			c.Writer = rw
+			c.Next()
+
+			ctx = context.WithoutCancel(ctx)
+			if rw.Status() >= http.StatusInternalServerError {
+				if err := store.ReleaseIdempotencyKey(ctx, uid, key); err != nil {
+					log.Error("failed to release idempotency key", sl.Err(err))
+				}
+				return
+			}
+
+			if err := store.SaveIdempotentResponse(ctx, uid, key, storage.IdempotentRecord{
+				Fingerprint: fp,
+				Status:      rw.Status(),
+				ContentType: rw.Header().Get("Content-Type"),
+				Body:        rw.body.Bytes(),
+			}, ttl); err != nil {
+				log.Error("failed to save idempotent response", sl.Err(err))
+			}
+		}
+	}
+}
+
+// recorder keeps a copy of the response body
+type recorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *recorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
+
+// spool keeps a copy of the body the handler can read again, small bodies stay in memory
+func spool(r io.Reader) (io.ReadSeeker, func(), error) {
+	head, err := io.ReadAll(io.LimitReader(r, memBody+1))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(head) <= memBody {
+		return bytes.NewReader(head), func() {}, nil
+	}
+
+	f, err := os.CreateTemp("", "idempotent-body")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() {
+		f.Close()
+		os.Remove(f.Name())
+	}
+
+	if _, err := f.Write(head); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	return f, cleanup, nil
+}
+
+// fingerprint hashes what identifies the request. Multipart bodies are hashed by their parts,
+// clients pick a new boundary on every retry.
+func fingerprint(r *http.Request, body io.Reader) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s?%s\n", r.Method, r.URL.Path, r.URL.RawQuery)
+
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		if _, err := io.Copy(h, body); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	mr := multipart.NewReader(body, params["boundary"])
+	var parts []string
+	for {
+		p, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		ph := sha256.New()
+		if _, err := io.Copy(ph, p); err != nil {
+			return "", err
+		}
+		parts = append(parts, fmt.Sprintf("%s|%s|%x", p.FormName(), p.FileName(), ph.Sum(nil)))
+	}
+
+	// the order of distinct fields doesn't matter, files of one field keep theirs
+	slices.SortStableFunc(parts, func(a, b string) int {
+		an, _, _ := strings.Cut(a, "|")
+		bn, _, _ := strings.Cut(b, "|")
+		return strings.Compare(an, bn)
+	})
+	for _, p := range parts {
+		fmt.Fprintln(h, p)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
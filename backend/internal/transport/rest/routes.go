@@ -20,6 +20,11 @@ type Handlers struct {
 	flashcardsHandler *rest_handlers.FlashCardsHandler
 	statsHandler      *rest_handlers.StatsHandler
 	libraryHandler    *rest_handlers.LibraryHandler
+	taskHandler       *rest_handlers.TaskHandler
+	decksHandler      *rest_handlers.DecksHandler
+	filtersHandler    *rest_handlers.FiltersHandler
+	exportHandler     *rest_handlers.ExportHandler
+	importHandler     *rest_handlers.ImportHandler
 }
 
 func New(
@@ -27,19 +32,29 @@ func New(
 	session *service.SessionService,
 	library *service.LibraryService,
 	flashcards *service.FlashCardsService,
+	decks *service.DecksService,
+	filters *service.FiltersService,
+	export *service.ExportService,
+	imports *service.ImportService,
+	jobs *service.JobService,
 	stats *service.StatsService,
 	sso authn.SSOService,
 	ws *hub.WebSocketHub,
 	storage *taskstorage.RedisStorage) *Handlers {
 
-	ocr := rest_handlers.NewOCRHandler(storage, ws, session)
-	transl := rest_handlers.NewTranslateHandler(storage, ws, session)
-	flCards := rest_handlers.NewFlashCardsHandler(storage, ws, session)
+	ocr := rest_handlers.NewOCRHandler(storage, jobs, session)
+	transl := rest_handlers.NewTranslateHandler(storage, jobs, session)
+	flCards := rest_handlers.NewFlashCardsHandler(storage, ws, session, flashcards)
 	learn := rest_handlers.NewLearnHandler(storage, ws, session)
-	ss := rest_handlers.NewSessionHandler(storage, ws, session)
+	ss := rest_handlers.NewSessionHandler(storage, ws, session, jobs)
 	authH := rest_handlers.NewAuthHandler(sso)
 	statsH := rest_handlers.NewStatsHandler(stats)
 	lib := rest_handlers.NewLibraryHandler(library, flashcards, log)
+	tasks := rest_handlers.NewTaskHandler(session, jobs)
+	decksH := rest_handlers.NewDecksHandler(decks)
+	filtersH := rest_handlers.NewFiltersHandler(filters)
+	exportH := rest_handlers.NewExportHandler(export, log)
+	importH := rest_handlers.NewImportHandler(imports)
 
 	return &Handlers{
 		ocrHandler:        ocr,
@@ -50,6 +65,11 @@ func New(
 		flashcardsHandler: flCards,
 		statsHandler:      statsH,
 		libraryHandler:    lib,
+		taskHandler:       tasks,
+		decksHandler:      decksH,
+		filtersHandler:    filtersH,
+		exportHandler:     exportH,
+		importHandler:     importH,
 	}
 }
 
@@ -57,7 +77,8 @@ func RegisterRoutes(
 	r *gin.Engine,
 	handlers *Handlers,
 	auth gin.HandlerFunc,
-	requireAuth gin.HandlerFunc) {
+	requireAuth gin.HandlerFunc,
+	idempotent gin.HandlerFunc) {
 
 	api := r.Group("/api")
 	api.Use(auth)
@@ -69,17 +90,30 @@ func RegisterRoutes(
 
 	//sessions
 	session := api.Group("/session")
-	session.POST("/new", handlers.sessionHandler.NewSession)
+	session.POST("/new", idempotent, handlers.sessionHandler.NewSession)
 
 	sessionProtected := session.Group("")
 	sessionProtected.Use(requireAuth)
 	{
-		sessionProtected.POST("/:sessionId/upload", handlers.ocrHandler.Upload)
-		sessionProtected.POST("/:sessionId/task/:taskId/translate", handlers.translateHandler.Translate)
-		sessionProtected.PATCH("/:sessionId/end", handlers.sessionHandler.EndSession)
+		sessionProtected.POST("/:sessionId/upload", idempotent, handlers.ocrHandler.Upload)
+		sessionProtected.POST("/:sessionId/upload/pages", idempotent, handlers.ocrHandler.UploadPages)
+		sessionProtected.GET("/:sessionId/tasks", handlers.taskHandler.List)
+		sessionProtected.GET("/:sessionId/task/:taskId", handlers.taskHandler.Get)
+		sessionProtected.GET("/:sessionId/task/:taskId/ocr", handlers.ocrHandler.Result)
+		sessionProtected.POST("/:sessionId/task/:taskId/cancel", idempotent, handlers.taskHandler.Cancel)
+		sessionProtected.POST("/:sessionId/task/:taskId/retry", idempotent, handlers.taskHandler.Retry)
+		sessionProtected.POST("/:sessionId/task/:taskId/translate", idempotent, handlers.translateHandler.Translate)
+		sessionProtected.POST("/:sessionId/task/:taskId/examples", idempotent, handlers.translateHandler.Examples)
+		sessionProtected.PATCH("/:sessionId/end", idempotent, handlers.sessionHandler.EndSession)
+		sessionProtected.PATCH("/:sessionId/reopen", idempotent, handlers.sessionHandler.ReopenSession)
+		sessionProtected.PATCH("/:sessionId/rename", idempotent, handlers.sessionHandler.RenameSession)
+		sessionProtected.POST("/:sessionId/title", idempotent, handlers.sessionHandler.GenerateTitle)
+		sessionProtected.PATCH("/:sessionId/archive", idempotent, handlers.sessionHandler.ArchiveSession)
+		sessionProtected.PATCH("/:sessionId/unarchive", idempotent, handlers.sessionHandler.UnarchiveSession)
+		sessionProtected.DELETE("/:sessionId", idempotent, handlers.sessionHandler.DeleteSession)
 		sessionProtected.GET("/:sessionId/learn/flashcards", handlers.flashcardsHandler.FlashCards)
 		sessionProtected.GET("/:sessionId/learn/quiz", handlers.learnHandler.Quiz)
-		sessionProtected.POST("/:sessionId/summary", handlers.sessionHandler.SessionSummary)
+		sessionProtected.POST("/:sessionId/summary", idempotent, handlers.sessionHandler.SessionSummary)
 	}
 
 	//library
@@ -87,9 +121,81 @@ func RegisterRoutes(
 	library.Use(requireAuth)
 	{
 		library.GET("/session/:sessionId", handlers.libraryHandler.GetSession)
+		library.GET("/session/:sessionId/documents", handlers.libraryHandler.ListDocuments)
+		library.GET("/session/:sessionId/documents/:documentId", handlers.libraryHandler.GetDocument)
 		library.GET("/session", handlers.libraryHandler.ListSession)
 	}
 
+	//flashcards
+	flashcards := api.Group("/flashcards")
+	flashcards.Use(requireAuth)
+	{
+		flashcards.GET("", handlers.flashcardsHandler.List)
+		flashcards.POST("", idempotent, handlers.flashcardsHandler.Create)
+		flashcards.POST("/move", idempotent, handlers.flashcardsHandler.Move)
+		flashcards.POST("/copy", idempotent, handlers.flashcardsHandler.Copy)
+		flashcards.POST("/reviews", idempotent, handlers.flashcardsHandler.RecordReviews)
+		flashcards.GET("/:cardId", handlers.flashcardsHandler.Get)
+		flashcards.PATCH("/:cardId", idempotent, handlers.flashcardsHandler.Update)
+		flashcards.DELETE("/:cardId", idempotent, handlers.flashcardsHandler.Delete)
+		flashcards.POST("/:cardId/tags", idempotent, handlers.flashcardsHandler.AddTags)
+		flashcards.DELETE("/:cardId/tags/:tag", idempotent, handlers.flashcardsHandler.RemoveTag)
+	}
+
+	//vocabulary
+	vocabulary := api.Group("/vocabulary")
+	vocabulary.Use(requireAuth)
+	{
+		vocabulary.GET("/search", handlers.flashcardsHandler.Search)
+		vocabulary.GET("/export", handlers.exportHandler.Vocabulary)
+	}
+
+	//vocabulary imports
+	imports := api.Group("/imports")
+	imports.Use(requireAuth)
+	{
+		imports.POST("", idempotent, handlers.importHandler.Start)
+		imports.GET("/:importId", handlers.importHandler.Get)
+		imports.POST("/:importId/confirm", idempotent, handlers.importHandler.Confirm)
+		imports.DELETE("/:importId", idempotent, handlers.importHandler.Cancel)
+	}
+
+	//tags
+	tags := api.Group("/tags")
+	tags.Use(requireAuth)
+	tags.GET("", handlers.flashcardsHandler.Tags)
+
+	//smart filters
+	filters := api.Group("/filters")
+	filters.Use(requireAuth)
+	{
+		filters.GET("", handlers.filtersHandler.List)
+		filters.POST("", idempotent, handlers.filtersHandler.Create)
+		filters.GET("/:filterId", handlers.filtersHandler.Get)
+		filters.PATCH("/:filterId", idempotent, handlers.filtersHandler.Update)
+		filters.DELETE("/:filterId", idempotent, handlers.filtersHandler.Delete)
+		filters.GET("/:filterId/cards", handlers.filtersHandler.Cards)
+		filters.GET("/:filterId/review", handlers.filtersHandler.Review)
+		filters.GET("/:filterId/quiz", handlers.filtersHandler.Quiz)
+		filters.GET("/:filterId/export/anki", handlers.exportHandler.AnkiFilter)
+	}
+
+	//decks
+	decks := api.Group("/decks")
+	decks.Use(requireAuth)
+	{
+		decks.GET("", handlers.decksHandler.List)
+		decks.POST("", idempotent, handlers.decksHandler.Create)
+		decks.PUT("/order", idempotent, handlers.decksHandler.Reorder)
+		decks.GET("/:deckId", handlers.decksHandler.Get)
+		decks.PATCH("/:deckId", idempotent, handlers.decksHandler.Update)
+		decks.DELETE("/:deckId", idempotent, handlers.decksHandler.Delete)
+		decks.GET("/:deckId/export/anki", handlers.exportHandler.AnkiDeck)
+		decks.POST("/:deckId/cards", idempotent, handlers.decksHandler.AddCards)
+		decks.DELETE("/:deckId/cards", idempotent, handlers.decksHandler.RemoveCards)
+		decks.PUT("/:deckId/cards/order", idempotent, handlers.decksHandler.ReorderCards)
+	}
+
 	public := r.Group("/api/auth")
 	{
 		public.POST("/login", handlers.authHandler.Login)
@@ -0,0 +1,359 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rwrrioe/pythia/backend/internal/auth/authn"
+	"github.com/rwrrioe/pythia/backend/internal/domain/entities"
+	"github.com/rwrrioe/pythia/backend/internal/lib/job_queue"
+	"github.com/rwrrioe/pythia/backend/internal/lib/logger/sl"
+	service "github.com/rwrrioe/pythia/backend/internal/services"
+)
+
+// Handlers of the session jobs. They report progress through task statuses and session events,
+// the events reach the websockets of every backend instance, so workers may run in their own process.
+
+type event map[string]any
+
+type base struct {
+	log     *slog.Logger
+	session *service.SessionService
+	events  service.SessionEventPublisher
+	stage   string
+}
+
+// userCtx carries the uid of the job owner, the services authorize by it as for http requests
+func userCtx(ctx context.Context, job *job_queue.Job) context.Context {
+	return authn.WithUserID(ctx, job.UserId)
+}
+
+// status and notify must be written even when the job ctx has timed out
+func (b *base) status(ctx context.Context, job *job_queue.Job, status string, taskErr error) {
+	ctx = context.WithoutCancel(ctx)
+	if err := b.session.SetTaskStatus(ctx, job.SessionId, job.TaskId, b.stage, status, taskErr); err != nil {
+		b.log.Error("failed to set task status", slog.String("task_id", job.TaskId), sl.Err(err))
+	}
+}
+
+func (b *base) notify(ctx context.Context, sessionId uuid.UUID, ev event) {
+	if err := b.events.PublishSessionEvent(context.WithoutCancel(ctx), sessionId, ev); err != nil {
+		b.log.Error("failed to publish session event", slog.String("session_id", sessionId.String()), sl.Err(err))
+	}
+}
+
+// done reports the finished stage, the queue enqueues the next one after Handle returns
+func (b *base) done(ctx context.Context, job *job_queue.Job, ev event) {
+	b.status(ctx, job, service.TaskDone, nil)
+	b.notify(ctx, job.SessionId, ev)
+}
+
+// Handover reports the next stage of the pipeline as queued once the queue has enqueued it,
+// its worker may have been faster and already report it running
+func (b *base) Handover(ctx context.Context, job *job_queue.Job) {
+	handed, err := b.session.HandOverTask(context.WithoutCancel(ctx), job.SessionId, job.TaskId, b.stage, job.Next[0])
+	if err != nil {
+		b.log.Error("failed to set task status", slog.String("task_id", job.TaskId), sl.Err(err))
+	}
+	if !handed {
+		return
+	}
+	b.notify(ctx, job.SessionId, event{
+		"task_id":    job.TaskId,
+		"session_id": job.SessionId,
+		"status":     service.TaskQueued,
+		"stage":      job.Next[0],
+		"previous":   b.stage,
+	})
+}
+
+func (b *base) Retry(ctx context.Context, job *job_queue.Job, delay time.Duration) {
+	b.status(ctx, job, service.TaskQueued, errors.New(job.LastError))
+	b.notify(ctx, job.SessionId, event{
+		"task_id":    job.TaskId,
+		"session_id": job.SessionId,
+		"status":     "retrying",
+		"stage":      b.stage,
+		"attempt":    job.Attempt,
+		"retry_in":   delay.Seconds(),
+		"error":      job.LastError,
+	})
+}
+
+func (b *base) Dead(ctx context.Context, job *job_queue.Job) {
+	b.status(ctx, job, service.TaskError, errors.New(job.LastError))
+	b.notify(ctx, job.SessionId, event{
+		"task_id":    job.TaskId,
+		"session_id": job.SessionId,
+		"status":     "error",
+		"stage":      b.stage,
+		"error":      job.LastError,
+		// the stages left out, they run again once the failed one is retried
+		"skipped": job.Next,
+	})
+}
+
+func (b *base) Cancelled(ctx context.Context, job *job_queue.Job) {
+	b.status(ctx, job, service.TaskCancelled, nil)
+	b.notify(ctx, job.SessionId, event{
+		"task_id":    job.TaskId,
+		"session_id": job.SessionId,
+		"status":     service.TaskCancelled,
+		"stage":      b.stage,
+		"skipped":    job.Next,
+	})
+}
+
+// permanent marks the errors a retry won't fix
+func permanent(err error) error {
+	switch {
+	case errors.Is(err, service.ErrUnauthorized),
+		errors.Is(err, service.ErrForbidden),
+		errors.Is(err, service.ErrSessionNotFound),
+		errors.Is(err, service.ErrTaskNotFound),
+		errors.Is(err, service.ErrNoWords),
+		errors.Is(err, service.ErrUnsupportedImage),
+		errors.Is(err, service.ErrImageTooLarge),
+		errors.Is(err, service.ErrInvalidImport),
+		errors.Is(err, service.ErrImportNotFound),
+		errors.Is(err, service.ErrDeckNotFound),
+		errors.Is(err, service.ErrUploadExpired):
+		return job_queue.Permanent(err)
+	}
+
+	return err
+}
+
+type OCRWorker struct {
+	base
+	uploads service.UploadStorage
+}
+
+func NewOCRWorker(log *slog.Logger, session *service.SessionService, uploads service.UploadStorage, events service.SessionEventPublisher) *OCRWorker {
+	return &OCRWorker{
+		base: base{
+			log:     log,
+			session: session,
+			events:  events,
+			stage:   service.StageOCR,
+		},
+		uploads: uploads,
+	}
+}
+
+func (w *OCRWorker) Handle(ctx context.Context, job *job_queue.Job) error {
+	const op = "workers.OCRWorker.Handle"
+
+	var p service.OCRPayload
+	if err := json.Unmarshal(job.Payload, &p); err != nil {
+		return job_queue.Permanent(fmt.Errorf("%s:%w", op, err))
+	}
+
+	pages, ok, err := w.uploads.GetUpload(ctx, service.UploadKey(job.SessionId, job.TaskId))
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if !ok {
+		return job_queue.Permanent(fmt.Errorf("%s: upload of task %s has expired", op, job.TaskId))
+	}
+
+	ctx = userCtx(ctx, job)
+	sessionId, taskId := job.SessionId, job.TaskId
+
+	w.status(ctx, job, service.TaskProcessing, nil)
+	w.notify(ctx, sessionId, event{
+		"task_id":     taskId,
+		"session_id":  sessionId,
+		"status":      "processing",
+		"stage":       w.stage,
+		"total_pages": p.Total,
+		"attempt":     job.Attempt,
+	})
+
+	var res *service.RecognizeResult
+	if p.MultiPage {
+		res, err = w.session.RecognizePages(ctx, sessionId, taskId, pages, p.Lang, func(page int, res *entities.OCRResult, cached bool) {
+			w.notify(ctx, sessionId, event{
+				"task_id":     taskId,
+				"session_id":  sessionId,
+				"status":      "partial",
+				"stage":       w.stage,
+				"page":        page,
+				"total_pages": p.Total,
+				"text":        res.Text(),
+				"lines":       res.Lines,
+				"cache_hit":   cached,
+			})
+		})
+	} else {
+		res, err = w.session.RecognizeText(ctx, sessionId, taskId, pages[0], p.Lang)
+	}
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, permanent(err))
+	}
+
+	if res.LangMismatch {
+		w.notify(ctx, sessionId, event{
+			"task_id":       taskId,
+			"session_id":    sessionId,
+			"status":        "warning",
+			"stage":         w.stage,
+			"warning":       "language mismatch",
+			"detected_lang": res.DetectedLang,
+			"session_lang":  res.SessionLang,
+		})
+	}
+
+	w.done(ctx, job, event{
+		"task_id":       taskId,
+		"session_id":    sessionId,
+		"status":        "done",
+		"stage":         w.stage,
+		"total_pages":   p.Total,
+		"cache_hit":     res.CacheHit,
+		"detected_lang": res.DetectedLang,
+		"lang_filled":   res.LangFilled,
+	})
+
+	_ = w.uploads.DeleteUpload(context.WithoutCancel(ctx), service.UploadKey(sessionId, taskId))
+	return nil
+}
+
+type TranslateWorker struct {
+	base
+}
+
+func NewTranslateWorker(log *slog.Logger, session *service.SessionService, events service.SessionEventPublisher) *TranslateWorker {
+	return &TranslateWorker{
+		base: base{
+			log:     log,
+			session: session,
+			events:  events,
+			stage:   service.StageTranslate,
+		},
+	}
+}
+
+func (w *TranslateWorker) Handle(ctx context.Context, job *job_queue.Job) error {
+	const op = "workers.TranslateWorker.Handle"
+
+	ctx = userCtx(ctx, job)
+	sessionId, taskId := job.SessionId, job.TaskId
+
+	w.status(ctx, job, service.TaskProcessing, nil)
+	w.notify(ctx, sessionId, event{
+		"session_id": sessionId,
+		"task_id":    taskId,
+		"status":     "processing",
+		"stage":      w.stage,
+		"attempt":    job.Attempt,
+	})
+
+	words, err := w.session.FindWords(ctx, sessionId, taskId)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, permanent(err))
+	}
+
+	w.done(ctx, job, event{
+		"status":     "done",
+		"stage":      w.stage,
+		"session_id": sessionId,
+		"task_id":    taskId,
+		"words":      words,
+	})
+
+	return nil
+}
+
+type ExamplesWorker struct {
+	base
+}
+
+func NewExamplesWorker(log *slog.Logger, session *service.SessionService, events service.SessionEventPublisher) *ExamplesWorker {
+	return &ExamplesWorker{
+		base: base{
+			log:     log,
+			session: session,
+			events:  events,
+			stage:   service.StageExamples,
+		},
+	}
+}
+
+func (w *ExamplesWorker) Handle(ctx context.Context, job *job_queue.Job) error {
+	const op = "workers.ExamplesWorker.Handle"
+
+	ctx = userCtx(ctx, job)
+	sessionId, taskId := job.SessionId, job.TaskId
+
+	w.status(ctx, job, service.TaskProcessing, nil)
+	w.notify(ctx, sessionId, event{
+		"session_id": sessionId,
+		"task_id":    taskId,
+		"status":     "processing",
+		"stage":      w.stage,
+		"attempt":    job.Attempt,
+	})
+
+	examples, err := w.session.WriteExamples(ctx, sessionId, taskId)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, permanent(err))
+	}
+
+	w.done(ctx, job, event{
+		"status":     "done",
+		"stage":      w.stage,
+		"session_id": sessionId,
+		"task_id":    taskId,
+		"examples":   examples,
+	})
+
+	return nil
+}
+
+// ImportWorker runs vocabulary imports. They belong to no session, the progress is kept
+// in the import itself and polled by the client.
+type ImportWorker struct {
+	log     *slog.Logger
+	imports *service.ImportService
+}
+
+func NewImportWorker(log *slog.Logger, imports *service.ImportService) *ImportWorker {
+	return &ImportWorker{log: log, imports: imports}
+}
+
+func (w *ImportWorker) Handle(ctx context.Context, job *job_queue.Job) error {
+	const op = "workers.ImportWorker.Handle"
+
+	if err := w.imports.Run(userCtx(ctx, job), job.TaskId); err != nil {
+		return fmt.Errorf("%s:%w", op, permanent(err))
+	}
+
+	return nil
+}
+
+func (w *ImportWorker) Retry(ctx context.Context, job *job_queue.Job, delay time.Duration) {
+	w.status(ctx, job, service.TaskQueued, errors.New(job.LastError))
+}
+
+func (w *ImportWorker) Dead(ctx context.Context, job *job_queue.Job) {
+	w.status(ctx, job, service.TaskError, errors.New(job.LastError))
+}
+
+func (w *ImportWorker) Cancelled(ctx context.Context, job *job_queue.Job) {
+	w.status(ctx, job, service.TaskCancelled, nil)
+}
+
+// Handover is never called, an import runs as a single stage
+func (w *ImportWorker) Handover(ctx context.Context, job *job_queue.Job) {}
+
+func (w *ImportWorker) status(ctx context.Context, job *job_queue.Job, status string, taskErr error) {
+	if err := w.imports.SetStatus(context.WithoutCancel(ctx), job.TaskId, status, taskErr); err != nil {
+		w.log.Error("failed to set import status", slog.String("import_id", job.TaskId), sl.Err(err))
+	}
+}
@@ -0,0 +1,10 @@
+BEGIN;
+
+ALTER TABLE decks_flashcards
+    DROP CONSTRAINT IF EXISTS decks_flashcards_flashcards,
+    ADD CONSTRAINT decks_flashcards_flashcards FOREIGN KEY (flashcard_id) REFERENCES flashcards(id);
+
+ALTER TABLE flashcards
+    DROP COLUMN IF EXISTS description;
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+ALTER TABLE flashcards
+    ADD COLUMN IF NOT EXISTS description text NOT NULL DEFAULT '';
+
+-- a deleted flashcard leaves every deck it was in
+ALTER TABLE decks_flashcards
+    DROP CONSTRAINT IF EXISTS decks_flashcards_flashcards,
+    ADD CONSTRAINT decks_flashcards_flashcards FOREIGN KEY (flashcard_id) REFERENCES flashcards(id) ON DELETE CASCADE;
+
+COMMIT;
@@ -0,0 +1,21 @@
+BEGIN;
+
+DELETE FROM decks WHERE kind = 'user';
+
+ALTER TABLE decks_flashcards
+    DROP COLUMN IF EXISTS added_at,
+    DROP COLUMN IF EXISTS position;
+
+DROP INDEX IF EXISTS idx_decks_user_kind_position;
+
+ALTER TABLE decks
+    DROP CONSTRAINT IF EXISTS chk_decks_kind,
+    DROP CONSTRAINT IF EXISTS fk_decks_languages,
+    DROP COLUMN IF EXISTS created_at,
+    DROP COLUMN IF EXISTS position,
+    DROP COLUMN IF EXISTS lang_id,
+    DROP COLUMN IF EXISTS description,
+    DROP COLUMN IF EXISTS name,
+    DROP COLUMN IF EXISTS kind;
+
+COMMIT;
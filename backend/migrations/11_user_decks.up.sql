@@ -0,0 +1,25 @@
+BEGIN;
+
+-- session decks are created when a session ends, user decks are curated by hand
+ALTER TABLE decks
+    ADD COLUMN IF NOT EXISTS kind        character varying(20) NOT NULL DEFAULT 'session',
+    ADD COLUMN IF NOT EXISTS name        character varying(100),
+    ADD COLUMN IF NOT EXISTS description text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS lang_id     integer,
+    ADD COLUMN IF NOT EXISTS position    integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS created_at  timestamp without time zone NOT NULL DEFAULT now(),
+    ADD CONSTRAINT fk_decks_languages FOREIGN KEY (lang_id) REFERENCES languages(id),
+    ADD CONSTRAINT chk_decks_kind CHECK (
+        (kind = 'session' AND session_id IS NOT NULL) OR
+        (kind = 'user' AND session_id IS NULL AND name IS NOT NULL)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_decks_user_kind_position
+    ON decks(user_id, kind, position);
+
+-- cards keep the order they were curated in
+ALTER TABLE decks_flashcards
+    ADD COLUMN IF NOT EXISTS position integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS added_at timestamp without time zone NOT NULL DEFAULT now();
+
+COMMIT;
@@ -0,0 +1,10 @@
+BEGIN;
+
+DROP TABLE IF EXISTS smart_filters;
+DROP TABLE IF EXISTS flashcard_reviews;
+DROP TABLE IF EXISTS flashcard_tags;
+
+ALTER TABLE flashcards
+    DROP COLUMN IF EXISTS created_at;
+
+COMMIT;
@@ -0,0 +1,48 @@
+BEGIN;
+
+ALTER TABLE flashcards
+    ADD COLUMN IF NOT EXISTS created_at timestamp without time zone NOT NULL DEFAULT now();
+
+-- user tags are plain names, automatic ones are namespaced (pos:noun, level:B1, session:<id>) or reserved (mistake)
+CREATE TABLE IF NOT EXISTS flashcard_tags (
+    flashcard_id UUID NOT NULL,
+    tag          character varying(64) NOT NULL,
+    auto         boolean NOT NULL DEFAULT false,
+    created_at   timestamp without time zone NOT NULL DEFAULT now(),
+
+    CONSTRAINT pk_flashcard_tags PRIMARY KEY (flashcard_id, tag),
+    CONSTRAINT fk_flashcard_tags_flashcards FOREIGN KEY (flashcard_id) REFERENCES flashcards(id) ON DELETE CASCADE
+    );
+
+CREATE INDEX IF NOT EXISTS idx_flashcard_tags_tag ON flashcard_tags(tag);
+
+-- every answer given for a card in a quiz or a review
+CREATE TABLE IF NOT EXISTS flashcard_reviews (
+    id           bigserial,
+    flashcard_id UUID NOT NULL,
+    session_id   UUID,
+    correct      boolean NOT NULL,
+    reviewed_at  timestamp without time zone NOT NULL DEFAULT now(),
+
+    CONSTRAINT pk_flashcard_reviews PRIMARY KEY (id),
+    CONSTRAINT fk_flashcard_reviews_flashcards FOREIGN KEY (flashcard_id) REFERENCES flashcards(id) ON DELETE CASCADE,
+    CONSTRAINT fk_flashcard_reviews_sessions FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE SET NULL
+    );
+
+CREATE INDEX IF NOT EXISTS idx_flashcard_reviews_flashcard_reviewed_at
+    ON flashcard_reviews(flashcard_id, reviewed_at);
+
+CREATE TABLE IF NOT EXISTS smart_filters (
+    id         UUID DEFAULT gen_random_uuid(),
+    user_id    UUID NOT NULL,
+    name       character varying(100) NOT NULL,
+    query      text NOT NULL,
+    created_at timestamp without time zone NOT NULL DEFAULT now(),
+    updated_at timestamp without time zone NOT NULL DEFAULT now(),
+
+    CONSTRAINT pk_smart_filters PRIMARY KEY (id),
+    CONSTRAINT uq_smart_filters_user_name UNIQUE (user_id, name),
+    CONSTRAINT fk_smart_filters_users FOREIGN KEY (user_id) REFERENCES users(id)
+    );
+
+COMMIT;
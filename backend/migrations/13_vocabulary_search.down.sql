@@ -0,0 +1,13 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_flashcards_transl_trgm;
+DROP INDEX IF EXISTS idx_flashcards_word_trgm;
+DROP INDEX IF EXISTS idx_flashcards_search_vector;
+
+ALTER TABLE flashcards
+    DROP COLUMN IF EXISTS search_vector,
+    DROP COLUMN IF EXISTS example;
+
+DROP FUNCTION IF EXISTS immutable_unaccent(text);
+
+COMMIT;
@@ -0,0 +1,33 @@
+BEGIN;
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+-- unaccent is only stable because its dictionary can change, the wrapper pins it so it can be indexed
+CREATE OR REPLACE FUNCTION immutable_unaccent(text) RETURNS text
+    LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT
+    AS $$ SELECT public.unaccent('public.unaccent'::regdictionary, $1) $$;
+
+-- the usage example written for the word during its session
+ALTER TABLE flashcards
+    ADD COLUMN IF NOT EXISTS example text NOT NULL DEFAULT '';
+
+-- the simple configuration doesn't stem, the cards are in several languages
+ALTER TABLE flashcards
+    ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', immutable_unaccent(lower(word))), 'A') ||
+        setweight(to_tsvector('simple', immutable_unaccent(lower(transl))), 'A') ||
+        setweight(to_tsvector('simple', immutable_unaccent(lower(example))), 'B') ||
+        setweight(to_tsvector('simple', immutable_unaccent(lower(description))), 'C')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_flashcards_search_vector
+    ON flashcards USING gin (search_vector);
+
+CREATE INDEX IF NOT EXISTS idx_flashcards_word_trgm
+    ON flashcards USING gin (immutable_unaccent(lower(word)) gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_flashcards_transl_trgm
+    ON flashcards USING gin (immutable_unaccent(lower(transl)) gin_trgm_ops);
+
+COMMIT;
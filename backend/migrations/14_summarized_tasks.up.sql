@@ -0,0 +1,12 @@
+BEGIN;
+
+-- the tasks whose words went into the session deck, a reopened session only summarizes the rest
+CREATE TABLE IF NOT EXISTS summarized_tasks (
+    session_id UUID NOT NULL,
+    task_id    character varying(64) NOT NULL,
+
+    CONSTRAINT pk_summarized_tasks PRIMARY KEY (session_id, task_id),
+    CONSTRAINT fk_summarized_tasks_sessions FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+    );
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+-- the original spelling of the words and the merged duplicates are not kept, the backfill can't be undone
+
+COMMIT;
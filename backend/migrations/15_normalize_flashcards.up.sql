@@ -0,0 +1,66 @@
+BEGIN;
+
+-- cards written before the words were normalized (NFC, collapsed whitespace) get the form the application writes,
+-- cards that only differed by it become one card per user, word and language
+CREATE TEMP TABLE flashcard_merges ON COMMIT DROP AS
+SELECT id, keep_id
+FROM (
+    SELECT id,
+           first_value(id) OVER (
+               PARTITION BY user_id, lang_id, btrim(regexp_replace(normalize(word, NFC), '\s+', ' ', 'g'))
+               ORDER BY created_at, id
+           ) AS keep_id
+    FROM flashcards
+    WHERE user_id IS NOT NULL AND word IS NOT NULL AND lang_id IS NOT NULL
+) f
+WHERE id <> keep_id;
+
+-- the kept card is the oldest one, the fields it lacks are taken from the oldest duplicate having them
+UPDATE flashcards k
+SET transl      = COALESCE(NULLIF(k.transl, ''), d.transl, k.transl),
+    example     = COALESCE(NULLIF(k.example, ''), d.example, k.example),
+    description = COALESCE(NULLIF(k.description, ''), d.description, k.description)
+FROM (
+    SELECT m.keep_id,
+           (array_agg(f.transl ORDER BY f.created_at, f.id) FILTER (WHERE f.transl <> ''))[1] AS transl,
+           (array_agg(f.example ORDER BY f.created_at, f.id) FILTER (WHERE f.example <> ''))[1] AS example,
+           (array_agg(f.description ORDER BY f.created_at, f.id) FILTER (WHERE f.description <> ''))[1] AS description
+    FROM flashcard_merges m
+    JOIN flashcards f ON f.id = m.id
+    GROUP BY m.keep_id
+) d
+WHERE k.id = d.keep_id;
+
+INSERT INTO decks_flashcards (deck_id, flashcard_id, position, added_at)
+SELECT df.deck_id, m.keep_id, df.position, df.added_at
+FROM decks_flashcards df
+JOIN flashcard_merges m ON m.id = df.flashcard_id
+ON CONFLICT (deck_id, flashcard_id) DO NOTHING;
+
+INSERT INTO flashcard_tags (flashcard_id, tag, auto, created_at)
+SELECT m.keep_id, t.tag, t.auto, t.created_at
+FROM flashcard_tags t
+JOIN flashcard_merges m ON m.id = t.flashcard_id
+ON CONFLICT (flashcard_id, tag) DO NOTHING;
+
+UPDATE flashcard_reviews r
+SET flashcard_id = m.keep_id
+FROM flashcard_merges m
+WHERE r.flashcard_id = m.id;
+
+-- the deck entries and tags left on the duplicates go with them
+DELETE FROM flashcards f
+USING flashcard_merges m
+WHERE f.id = m.id;
+
+UPDATE flashcards
+SET word        = btrim(regexp_replace(normalize(word, NFC), '\s+', ' ', 'g')),
+    transl      = btrim(regexp_replace(normalize(transl, NFC), '\s+', ' ', 'g')),
+    example     = regexp_replace(normalize(example, NFC), '^\s+|\s+$', '', 'g'),
+    description = regexp_replace(normalize(description, NFC), '^\s+|\s+$', '', 'g')
+WHERE word <> btrim(regexp_replace(normalize(word, NFC), '\s+', ' ', 'g'))
+   OR transl <> btrim(regexp_replace(normalize(transl, NFC), '\s+', ' ', 'g'))
+   OR example <> regexp_replace(normalize(example, NFC), '^\s+|\s+$', '', 'g')
+   OR description <> regexp_replace(normalize(description, NFC), '^\s+|\s+$', '', 'g');
+
+COMMIT;
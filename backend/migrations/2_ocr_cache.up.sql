@@ -0,0 +1,12 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS ocr_cache (
+    image_hash character(64) NOT NULL,
+    lang       character varying(10) NOT NULL,
+    result     jsonb NOT NULL,
+    created_at timestamp without time zone NOT NULL DEFAULT now(),
+
+    CONSTRAINT pk_ocr_cache PRIMARY KEY (image_hash, lang)
+    );
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+DELETE FROM languages WHERE id IN (3, 4);
+
+COMMIT;
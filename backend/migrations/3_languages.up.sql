@@ -0,0 +1,10 @@
+BEGIN;
+
+INSERT INTO languages (id, language)
+VALUES
+    (3, 'french'),
+    (4, 'spanish')
+    ON CONFLICT (id) DO UPDATE
+    SET language = EXCLUDED.language;
+
+COMMIT;
@@ -0,0 +1,10 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_sessions_active_expires_at;
+
+ALTER TABLE sessions
+    DROP COLUMN IF EXISTS expiry_locked_until,
+    DROP COLUMN IF EXISTS expires_at,
+    DROP COLUMN IF EXISTS duration;
+
+COMMIT;
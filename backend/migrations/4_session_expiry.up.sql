@@ -0,0 +1,14 @@
+BEGIN;
+
+ALTER TABLE sessions
+    -- seconds, 0 means the session never expires
+    ADD COLUMN IF NOT EXISTS duration            integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS expires_at          timestamp without time zone,
+    -- lease taken by the instance that finalizes the expired session
+    ADD COLUMN IF NOT EXISTS expiry_locked_until timestamp without time zone;
+
+CREATE INDEX IF NOT EXISTS idx_sessions_active_expires_at
+    ON sessions(expires_at)
+    WHERE status = 'active' AND expires_at IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS document_pages;
+DROP TABLE IF EXISTS documents;
+
+COMMIT;
@@ -0,0 +1,30 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS documents (
+    id            UUID DEFAULT gen_random_uuid(),
+    session_id    UUID NOT NULL,
+    task_id       character varying(64) NOT NULL,
+    lang_id       integer,
+    detected_lang character varying(10),
+    created_at    timestamp without time zone NOT NULL DEFAULT now(),
+
+    CONSTRAINT pk_documents PRIMARY KEY (id),
+    CONSTRAINT uq_documents_session_task UNIQUE (session_id, task_id),
+    CONSTRAINT fk_documents_sessions FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE,
+    CONSTRAINT fk_documents_languages FOREIGN KEY (lang_id) REFERENCES languages(id)
+    );
+
+CREATE TABLE IF NOT EXISTS document_pages (
+    document_id UUID NOT NULL,
+    page        integer NOT NULL,
+    text        text NOT NULL DEFAULT '',
+    lines       jsonb NOT NULL DEFAULT '[]',
+    -- sha256 of the preprocessed image, the key of ocr_cache
+    image_hash  character(64),
+    words       jsonb NOT NULL DEFAULT '[]',
+
+    CONSTRAINT pk_document_pages PRIMARY KEY (document_id, page),
+    CONSTRAINT fk_document_pages_documents FOREIGN KEY (document_id) REFERENCES documents(id) ON DELETE CASCADE
+    );
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE sessions
+    ADD COLUMN IF NOT EXISTS reopened_at timestamp without time zone;
+
+COMMIT;
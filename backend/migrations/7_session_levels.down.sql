@@ -0,0 +1,9 @@
+BEGIN;
+
+ALTER TABLE sessions
+    DROP CONSTRAINT IF EXISTS fk_sessions_levels,
+    DROP COLUMN IF EXISTS level_id;
+
+DELETE FROM levels WHERE id > 2;
+
+COMMIT;
@@ -0,0 +1,18 @@
+BEGIN;
+
+INSERT INTO levels (id, level)
+VALUES
+    (1, 'A1'),
+    (2, 'A2'),
+    (3, 'B1'),
+    (4, 'B2'),
+    (5, 'C1'),
+    (6, 'C2')
+    ON CONFLICT (id) DO UPDATE
+    SET level = EXCLUDED.level;
+
+ALTER TABLE sessions
+    ADD COLUMN IF NOT EXISTS level_id integer,
+    ADD CONSTRAINT fk_sessions_levels FOREIGN KEY (level_id) REFERENCES levels(id);
+
+COMMIT;
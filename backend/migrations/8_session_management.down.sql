@@ -0,0 +1,16 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_decks_flashcards_flashcard_id;
+
+ALTER TABLE decks_flashcards
+    DROP CONSTRAINT IF EXISTS decks_flashcards_decks,
+    ADD CONSTRAINT decks_flashcards_decks FOREIGN KEY (deck_id) REFERENCES decks(id);
+
+ALTER TABLE decks
+    DROP CONSTRAINT IF EXISTS decks_sessions,
+    ADD CONSTRAINT decks_sessions FOREIGN KEY (session_id) REFERENCES sessions(id);
+
+ALTER TABLE sessions
+    DROP COLUMN IF EXISTS archived_at;
+
+COMMIT;
@@ -0,0 +1,18 @@
+BEGIN;
+
+ALTER TABLE sessions
+    ADD COLUMN IF NOT EXISTS archived_at timestamp without time zone;
+
+-- a deleted session takes its deck and the deck entries with it,
+-- the flashcards themselves are shared between decks and removed by the application
+ALTER TABLE decks
+    DROP CONSTRAINT IF EXISTS decks_sessions,
+    ADD CONSTRAINT decks_sessions FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE;
+
+ALTER TABLE decks_flashcards
+    DROP CONSTRAINT IF EXISTS decks_flashcards_decks,
+    ADD CONSTRAINT decks_flashcards_decks FOREIGN KEY (deck_id) REFERENCES decks(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_decks_flashcards_flashcard_id ON decks_flashcards(flashcard_id);
+
+COMMIT;
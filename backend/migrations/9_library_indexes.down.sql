@@ -0,0 +1,10 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_documents_session_id;
+DROP INDEX IF EXISTS idx_decks_session_id;
+DROP INDEX IF EXISTS idx_sessions_name_trgm;
+DROP INDEX IF EXISTS idx_sessions_user_name;
+DROP INDEX IF EXISTS idx_sessions_user_accuracy;
+DROP INDEX IF EXISTS idx_sessions_user_started_at;
+
+COMMIT;
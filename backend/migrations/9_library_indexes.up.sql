@@ -0,0 +1,25 @@
+BEGIN;
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- library listing, one index per sort order, the id breaks ties for the cursor
+CREATE INDEX IF NOT EXISTS idx_sessions_user_started_at
+    ON sessions(user_id, started_at, id);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_accuracy
+    ON sessions(user_id, accuracy, id);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_name
+    ON sessions(user_id, name, id);
+
+-- name search
+CREATE INDEX IF NOT EXISTS idx_sessions_name_trgm
+    ON sessions USING gin (name gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_decks_session_id
+    ON decks(session_id);
+
+CREATE INDEX IF NOT EXISTS idx_documents_session_id
+    ON documents(session_id, created_at);
+
+COMMIT;
@@ -1,5 +1,6 @@
 import grpc
 from concurrent import futures
+from grpc_health.v1 import health, health_pb2, health_pb2_grpc
 from processer.ocr_processer import OCRServiceServicer
 from shared.gen.python.ocr import ocr_pb2_grpc
 
@@ -8,6 +9,10 @@ def serve():
     server = grpc.server(futures.ThreadPoolExecutor(max_workers=4))
     ocr_pb2_grpc.add_OCRServiceServicer_to_server(OCRServiceServicer(), server)
 
+    health_servicer = health.HealthServicer()
+    health_pb2_grpc.add_HealthServicer_to_server(health_servicer, server)
+    health_servicer.set("ocrv1.OCRService", health_pb2.HealthCheckResponse.SERVING)
+
     port = 50051
     server.add_insecure_port(f"[::]:{port}")
 
@@ -1,4 +1,6 @@
 import io
+import os
+import threading
 import grpc
 from PIL import Image
 import numpy as np
@@ -6,27 +8,96 @@ from paddleocr import PaddleOCR
 from shared.gen.python.ocr import ocr_pb2, ocr_pb2_grpc
 
 
-class OCRServiceServicer(ocr_pb2_grpc.OCRServiceServicer):
-    def __init__(self):
-        self.ocr = PaddleOCR(use_angle_cls=True, lang='de')
+DEFAULT_LANG = os.getenv("OCR_DEFAULT_LANG", "de")
+SUPPORTED_LANGS = {
+    lang.strip() for lang in os.getenv("OCR_LANGS", DEFAULT_LANG).split(",") if lang.strip()
+}
 
-    def Recognize(self, request, context):
-        try:
-            with io.BytesIO(request.image_data) as img_buf:
-                img = Image.open(img_buf).convert("RGB")
-                img = np.array(img)
 
-            results = self.ocr.predict(img)
-    
-            if not results:
-                return ocr_pb2.OCRResponse(text=[])
+class OCRServiceServicer(ocr_pb2_grpc.OCRServiceServicer):
+    def __init__(self):
+        self._engines = {}
+        self._lock = threading.Lock()
+        # load the default model eagerly so the first request is not slow
+        self._engine(DEFAULT_LANG)
 
-            texts = results[0].get("rec_texts", [])
+    def _engine(self, lang):
+        with self._lock:
+            if lang not in self._engines:
+                self._engines[lang] = PaddleOCR(use_angle_cls=True, lang=lang)
+            return self._engines[lang]
 
+    def Recognize(self, request, context):
+        lang = request.lang or DEFAULT_LANG
+        if lang not in SUPPORTED_LANGS:
+            context.set_details(f"language {lang} is not supported by this engine")
+            context.set_code(grpc.StatusCode.INVALID_ARGUMENT)
+            return ocr_pb2.OCRResponse(text=[])
 
-            return ocr_pb2.OCRResponse(text=texts)
+        try:
+            return self._recognize(request.image_data, lang)
 
         except Exception as e:
             context.set_details(str(e))
             context.set_code(grpc.StatusCode.INTERNAL)
             return ocr_pb2.OCRResponse(text=[])
+
+    def RecognizeStream(self, request_iterator, context):
+        lang = None
+        buf = bytearray()
+
+        for chunk in request_iterator:
+            if lang is None:
+                lang = chunk.lang or DEFAULT_LANG
+                if lang not in SUPPORTED_LANGS:
+                    context.abort(grpc.StatusCode.INVALID_ARGUMENT,
+                                  f"language {lang} is not supported by this engine")
+
+            buf.extend(chunk.data)
+            if not chunk.page_end:
+                continue
+
+            try:
+                result = self._recognize(bytes(buf), lang)
+            except Exception as e:
+                context.abort(grpc.StatusCode.INTERNAL, f"page {chunk.page}: {e}")
+
+            buf.clear()
+            yield ocr_pb2.OCRPageResult(page=chunk.page, result=result)
+
+    def _recognize(self, image_data, lang):
+        with io.BytesIO(image_data) as img_buf:
+            img = Image.open(img_buf).convert("RGB")
+            img = np.array(img)
+
+        height, width = img.shape[:2]
+        results = self._engine(lang).predict(img)
+
+        if not results:
+            return ocr_pb2.OCRResponse(text=[], image_width=width, image_height=height)
+
+        texts = results[0].get("rec_texts", [])
+        scores = results[0].get("rec_scores", [])
+        boxes = results[0].get("rec_boxes", [])
+
+        lines = []
+        for i, text in enumerate(texts):
+            line = ocr_pb2.OCRLine(text=text)
+            if i < len(scores):
+                line.confidence = float(scores[i])
+            if i < len(boxes):
+                x_min, y_min, x_max, y_max = (int(v) for v in boxes[i])
+                line.box.CopyFrom(ocr_pb2.BoundingBox(
+                    x=x_min,
+                    y=y_min,
+                    width=x_max - x_min,
+                    height=y_max - y_min,
+                ))
+            lines.append(line)
+
+        return ocr_pb2.OCRResponse(
+            text=texts,
+            lines=lines,
+            image_width=width,
+            image_height=height,
+        )
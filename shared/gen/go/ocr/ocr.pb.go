@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.36.10
+// 	protoc-gen-go v1.36.11
 // 	protoc        v6.32.1
 // source: proto/ocr/ocr.proto
 
@@ -74,8 +74,12 @@ func (x *OCRRequest) GetLang() string {
 }
 
 type OCRResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Text          []string               `protobuf:"bytes,1,rep,name=text,proto3" json:"text,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// plain recognized lines, kept for clients that don't read lines
+	Text          []string   `protobuf:"bytes,1,rep,name=text,proto3" json:"text,omitempty"`
+	Lines         []*OCRLine `protobuf:"bytes,2,rep,name=lines,proto3" json:"lines,omitempty"`
+	ImageWidth    int32      `protobuf:"varint,3,opt,name=image_width,json=imageWidth,proto3" json:"image_width,omitempty"`
+	ImageHeight   int32      `protobuf:"varint,4,opt,name=image_height,json=imageHeight,proto3" json:"image_height,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -117,6 +121,288 @@ func (x *OCRResponse) GetText() []string {
 	return nil
 }
 
+func (x *OCRResponse) GetLines() []*OCRLine {
+	if x != nil {
+		return x.Lines
+	}
+	return nil
+}
+
+func (x *OCRResponse) GetImageWidth() int32 {
+	if x != nil {
+		return x.ImageWidth
+	}
+	return 0
+}
+
+func (x *OCRResponse) GetImageHeight() int32 {
+	if x != nil {
+		return x.ImageHeight
+	}
+	return 0
+}
+
+type OCRLine struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Text  string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	// recognition score in [0, 1]
+	Confidence    float32      `protobuf:"fixed32,2,opt,name=confidence,proto3" json:"confidence,omitempty"`
+	Box           *BoundingBox `protobuf:"bytes,3,opt,name=box,proto3" json:"box,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OCRLine) Reset() {
+	*x = OCRLine{}
+	mi := &file_proto_ocr_ocr_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OCRLine) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OCRLine) ProtoMessage() {}
+
+func (x *OCRLine) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_ocr_ocr_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OCRLine.ProtoReflect.Descriptor instead.
+func (*OCRLine) Descriptor() ([]byte, []int) {
+	return file_proto_ocr_ocr_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *OCRLine) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *OCRLine) GetConfidence() float32 {
+	if x != nil {
+		return x.Confidence
+	}
+	return 0
+}
+
+func (x *OCRLine) GetBox() *BoundingBox {
+	if x != nil {
+		return x.Box
+	}
+	return nil
+}
+
+// axis-aligned box in pixels of the image sent in OCRRequest
+type BoundingBox struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	X             int32                  `protobuf:"varint,1,opt,name=x,proto3" json:"x,omitempty"`
+	Y             int32                  `protobuf:"varint,2,opt,name=y,proto3" json:"y,omitempty"`
+	Width         int32                  `protobuf:"varint,3,opt,name=width,proto3" json:"width,omitempty"`
+	Height        int32                  `protobuf:"varint,4,opt,name=height,proto3" json:"height,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BoundingBox) Reset() {
+	*x = BoundingBox{}
+	mi := &file_proto_ocr_ocr_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BoundingBox) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BoundingBox) ProtoMessage() {}
+
+func (x *BoundingBox) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_ocr_ocr_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BoundingBox.ProtoReflect.Descriptor instead.
+func (*BoundingBox) Descriptor() ([]byte, []int) {
+	return file_proto_ocr_ocr_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BoundingBox) GetX() int32 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *BoundingBox) GetY() int32 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+func (x *BoundingBox) GetWidth() int32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *BoundingBox) GetHeight() int32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+type OCRChunk struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// set on the first chunk of the stream
+	Lang       string `protobuf:"bytes,1,opt,name=lang,proto3" json:"lang,omitempty"`
+	TotalPages int32  `protobuf:"varint,2,opt,name=total_pages,json=totalPages,proto3" json:"total_pages,omitempty"`
+	// zero-based page index, chunks of one page are sent in order
+	Page int32  `protobuf:"varint,3,opt,name=page,proto3" json:"page,omitempty"`
+	Data []byte `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	// marks the last chunk of the page
+	PageEnd       bool `protobuf:"varint,5,opt,name=page_end,json=pageEnd,proto3" json:"page_end,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OCRChunk) Reset() {
+	*x = OCRChunk{}
+	mi := &file_proto_ocr_ocr_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OCRChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OCRChunk) ProtoMessage() {}
+
+func (x *OCRChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_ocr_ocr_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OCRChunk.ProtoReflect.Descriptor instead.
+func (*OCRChunk) Descriptor() ([]byte, []int) {
+	return file_proto_ocr_ocr_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *OCRChunk) GetLang() string {
+	if x != nil {
+		return x.Lang
+	}
+	return ""
+}
+
+func (x *OCRChunk) GetTotalPages() int32 {
+	if x != nil {
+		return x.TotalPages
+	}
+	return 0
+}
+
+func (x *OCRChunk) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *OCRChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *OCRChunk) GetPageEnd() bool {
+	if x != nil {
+		return x.PageEnd
+	}
+	return false
+}
+
+type OCRPageResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Page          int32                  `protobuf:"varint,1,opt,name=page,proto3" json:"page,omitempty"`
+	Result        *OCRResponse           `protobuf:"bytes,2,opt,name=result,proto3" json:"result,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OCRPageResult) Reset() {
+	*x = OCRPageResult{}
+	mi := &file_proto_ocr_ocr_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OCRPageResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OCRPageResult) ProtoMessage() {}
+
+func (x *OCRPageResult) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_ocr_ocr_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OCRPageResult.ProtoReflect.Descriptor instead.
+func (*OCRPageResult) Descriptor() ([]byte, []int) {
+	return file_proto_ocr_ocr_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *OCRPageResult) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *OCRPageResult) GetResult() *OCRResponse {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
 var File_proto_ocr_ocr_proto protoreflect.FileDescriptor
 
 const file_proto_ocr_ocr_proto_rawDesc = "" +
@@ -126,12 +412,38 @@ const file_proto_ocr_ocr_proto_rawDesc = "" +
 	"OCRRequest\x12\x1d\n" +
 	"\n" +
 	"image_data\x18\x01 \x01(\fR\timageData\x12\x12\n" +
-	"\x04lang\x18\x02 \x01(\tR\x04lang\"!\n" +
+	"\x04lang\x18\x02 \x01(\tR\x04lang\"\x8b\x01\n" +
 	"\vOCRResponse\x12\x12\n" +
-	"\x04text\x18\x01 \x03(\tR\x04text2@\n" +
+	"\x04text\x18\x01 \x03(\tR\x04text\x12$\n" +
+	"\x05lines\x18\x02 \x03(\v2\x0e.ocrv1.OCRLineR\x05lines\x12\x1f\n" +
+	"\vimage_width\x18\x03 \x01(\x05R\n" +
+	"imageWidth\x12!\n" +
+	"\fimage_height\x18\x04 \x01(\x05R\vimageHeight\"c\n" +
+	"\aOCRLine\x12\x12\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text\x12\x1e\n" +
+	"\n" +
+	"confidence\x18\x02 \x01(\x02R\n" +
+	"confidence\x12$\n" +
+	"\x03box\x18\x03 \x01(\v2\x12.ocrv1.BoundingBoxR\x03box\"W\n" +
+	"\vBoundingBox\x12\f\n" +
+	"\x01x\x18\x01 \x01(\x05R\x01x\x12\f\n" +
+	"\x01y\x18\x02 \x01(\x05R\x01y\x12\x14\n" +
+	"\x05width\x18\x03 \x01(\x05R\x05width\x12\x16\n" +
+	"\x06height\x18\x04 \x01(\x05R\x06height\"\x82\x01\n" +
+	"\bOCRChunk\x12\x12\n" +
+	"\x04lang\x18\x01 \x01(\tR\x04lang\x12\x1f\n" +
+	"\vtotal_pages\x18\x02 \x01(\x05R\n" +
+	"totalPages\x12\x12\n" +
+	"\x04page\x18\x03 \x01(\x05R\x04page\x12\x12\n" +
+	"\x04data\x18\x04 \x01(\fR\x04data\x12\x19\n" +
+	"\bpage_end\x18\x05 \x01(\bR\apageEnd\"O\n" +
+	"\rOCRPageResult\x12\x12\n" +
+	"\x04page\x18\x01 \x01(\x05R\x04page\x12*\n" +
+	"\x06result\x18\x02 \x01(\v2\x12.ocrv1.OCRResponseR\x06result2~\n" +
 	"\n" +
 	"OCRService\x122\n" +
-	"\tRecognize\x12\x11.ocrv1.OCRRequest\x1a\x12.ocrv1.OCRResponseB3Z1github.com/rwrrioe/shared/protos/gen/go/ocr;ocrv1b\x06proto3"
+	"\tRecognize\x12\x11.ocrv1.OCRRequest\x1a\x12.ocrv1.OCRResponse\x12<\n" +
+	"\x0fRecognizeStream\x12\x0f.ocrv1.OCRChunk\x1a\x14.ocrv1.OCRPageResult(\x010\x01B3Z1github.com/rwrrioe/shared/protos/gen/go/ocr;ocrv1b\x06proto3"
 
 var (
 	file_proto_ocr_ocr_proto_rawDescOnce sync.Once
@@ -145,19 +457,28 @@ func file_proto_ocr_ocr_proto_rawDescGZIP() []byte {
 	return file_proto_ocr_ocr_proto_rawDescData
 }
 
-var file_proto_ocr_ocr_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_proto_ocr_ocr_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
 var file_proto_ocr_ocr_proto_goTypes = []any{
-	(*OCRRequest)(nil),  // 0: ocrv1.OCRRequest
-	(*OCRResponse)(nil), // 1: ocrv1.OCRResponse
+	(*OCRRequest)(nil),    // 0: ocrv1.OCRRequest
+	(*OCRResponse)(nil),   // 1: ocrv1.OCRResponse
+	(*OCRLine)(nil),       // 2: ocrv1.OCRLine
+	(*BoundingBox)(nil),   // 3: ocrv1.BoundingBox
+	(*OCRChunk)(nil),      // 4: ocrv1.OCRChunk
+	(*OCRPageResult)(nil), // 5: ocrv1.OCRPageResult
 }
 var file_proto_ocr_ocr_proto_depIdxs = []int32{
-	0, // 0: ocrv1.OCRService.Recognize:input_type -> ocrv1.OCRRequest
-	1, // 1: ocrv1.OCRService.Recognize:output_type -> ocrv1.OCRResponse
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	2, // 0: ocrv1.OCRResponse.lines:type_name -> ocrv1.OCRLine
+	3, // 1: ocrv1.OCRLine.box:type_name -> ocrv1.BoundingBox
+	1, // 2: ocrv1.OCRPageResult.result:type_name -> ocrv1.OCRResponse
+	0, // 3: ocrv1.OCRService.Recognize:input_type -> ocrv1.OCRRequest
+	4, // 4: ocrv1.OCRService.RecognizeStream:input_type -> ocrv1.OCRChunk
+	1, // 5: ocrv1.OCRService.Recognize:output_type -> ocrv1.OCRResponse
+	5, // 6: ocrv1.OCRService.RecognizeStream:output_type -> ocrv1.OCRPageResult
+	5, // [5:7] is the sub-list for method output_type
+	3, // [3:5] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
 }
 
 func init() { file_proto_ocr_ocr_proto_init() }
@@ -171,7 +492,7 @@ func file_proto_ocr_ocr_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_ocr_ocr_proto_rawDesc), len(file_proto_ocr_ocr_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   6,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -19,7 +19,8 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	OCRService_Recognize_FullMethodName = "/ocrv1.OCRService/Recognize"
+	OCRService_Recognize_FullMethodName       = "/ocrv1.OCRService/Recognize"
+	OCRService_RecognizeStream_FullMethodName = "/ocrv1.OCRService/RecognizeStream"
 )
 
 // OCRServiceClient is the client API for OCRService service.
@@ -27,6 +28,8 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type OCRServiceClient interface {
 	Recognize(ctx context.Context, in *OCRRequest, opts ...grpc.CallOption) (*OCRResponse, error)
+	// Pages are uploaded in chunks, every page is answered as soon as it is recognized.
+	RecognizeStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[OCRChunk, OCRPageResult], error)
 }
 
 type oCRServiceClient struct {
@@ -47,11 +50,26 @@ func (c *oCRServiceClient) Recognize(ctx context.Context, in *OCRRequest, opts .
 	return out, nil
 }
 
+func (c *oCRServiceClient) RecognizeStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[OCRChunk, OCRPageResult], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &OCRService_ServiceDesc.Streams[0], OCRService_RecognizeStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[OCRChunk, OCRPageResult]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OCRService_RecognizeStreamClient = grpc.BidiStreamingClient[OCRChunk, OCRPageResult]
+
 // OCRServiceServer is the server API for OCRService service.
 // All implementations must embed UnimplementedOCRServiceServer
 // for forward compatibility.
 type OCRServiceServer interface {
 	Recognize(context.Context, *OCRRequest) (*OCRResponse, error)
+	// Pages are uploaded in chunks, every page is answered as soon as it is recognized.
+	RecognizeStream(grpc.BidiStreamingServer[OCRChunk, OCRPageResult]) error
 	mustEmbedUnimplementedOCRServiceServer()
 }
 
@@ -65,6 +83,9 @@ type UnimplementedOCRServiceServer struct{}
 func (UnimplementedOCRServiceServer) Recognize(context.Context, *OCRRequest) (*OCRResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Recognize not implemented")
 }
+func (UnimplementedOCRServiceServer) RecognizeStream(grpc.BidiStreamingServer[OCRChunk, OCRPageResult]) error {
+	return status.Errorf(codes.Unimplemented, "method RecognizeStream not implemented")
+}
 func (UnimplementedOCRServiceServer) mustEmbedUnimplementedOCRServiceServer() {}
 func (UnimplementedOCRServiceServer) testEmbeddedByValue()                    {}
 
@@ -104,6 +125,13 @@ func _OCRService_Recognize_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _OCRService_RecognizeStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(OCRServiceServer).RecognizeStream(&grpc.GenericServerStream[OCRChunk, OCRPageResult]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OCRService_RecognizeStreamServer = grpc.BidiStreamingServer[OCRChunk, OCRPageResult]
+
 // OCRService_ServiceDesc is the grpc.ServiceDesc for OCRService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -116,6 +144,13 @@ var OCRService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _OCRService_Recognize_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "RecognizeStream",
+			Handler:       _OCRService_RecognizeStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
 	Metadata: "proto/ocr/ocr.proto",
 }
@@ -24,7 +24,7 @@ _sym_db = _symbol_database.Default()
 
 
 
-DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\rocr/ocr.proto\x12\x05ocrv1\".\n\nOCRRequest\x12\x12\n\nimage_data\x18\x01 \x01(\x0c\x12\x0c\n\x04lang\x18\x02 \x01(\t\"\x1b\n\x0bOCRResponse\x12\x0c\n\x04text\x18\x01 \x03(\t2@\n\nOCRService\x12\x32\n\tRecognize\x12\x11.ocrv1.OCRRequest\x1a\x12.ocrv1.OCRResponseB3Z1github.com/rwrrioe/shared/protos/gen/go/ocr;ocrv1b\x06proto3')
+DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\rocr/ocr.proto\x12\x05ocrv1\".\n\nOCRRequest\x12\x12\n\nimage_data\x18\x01 \x01(\x0c\x12\x0c\n\x04lang\x18\x02 \x01(\t\"e\n\x0bOCRResponse\x12\x0c\n\x04text\x18\x01 \x03(\t\x12\x1d\n\x05lines\x18\x02 \x03(\x0b\x32\x0e.ocrv1.OCRLine\x12\x13\n\x0bimage_width\x18\x03 \x01(\x05\x12\x14\n\x0cimage_height\x18\x04 \x01(\x05\"L\n\x07OCRLine\x12\x0c\n\x04text\x18\x01 \x01(\t\x12\x12\n\nconfidence\x18\x02 \x01(\x02\x12\x1f\n\x03\x62ox\x18\x03 \x01(\x0b\x32\x12.ocrv1.BoundingBox\"B\n\x0b\x42oundingBox\x12\t\n\x01x\x18\x01 \x01(\x05\x12\t\n\x01y\x18\x02 \x01(\x05\x12\r\n\x05width\x18\x03 \x01(\x05\x12\x0e\n\x06height\x18\x04 \x01(\x05\"[\n\x08OCRChunk\x12\x0c\n\x04lang\x18\x01 \x01(\t\x12\x13\n\x0btotal_pages\x18\x02 \x01(\x05\x12\x0c\n\x04page\x18\x03 \x01(\x05\x12\x0c\n\x04\x64ata\x18\x04 \x01(\x0c\x12\x10\n\x08page_end\x18\x05 \x01(\x08\"A\n\rOCRPageResult\x12\x0c\n\x04page\x18\x01 \x01(\x05\x12\"\n\x06result\x18\x02 \x01(\x0b\x32\x12.ocrv1.OCRResponse2~\n\nOCRService\x12\x32\n\tRecognize\x12\x11.ocrv1.OCRRequest\x1a\x12.ocrv1.OCRResponse\x12<\n\x0fRecognizeStream\x12\x0f.ocrv1.OCRChunk\x1a\x14.ocrv1.OCRPageResult(\x01\x30\x01\x423Z1github.com/rwrrioe/shared/protos/gen/go/ocr;ocrv1b\x06proto3')
 
 _globals = globals()
 _builder.BuildMessageAndEnumDescriptors(DESCRIPTOR, _globals)
@@ -35,7 +35,15 @@ if not _descriptor._USE_C_DESCRIPTORS:
   _globals['_OCRREQUEST']._serialized_start=24
   _globals['_OCRREQUEST']._serialized_end=70
   _globals['_OCRRESPONSE']._serialized_start=72
-  _globals['_OCRRESPONSE']._serialized_end=99
-  _globals['_OCRSERVICE']._serialized_start=101
-  _globals['_OCRSERVICE']._serialized_end=165
+  _globals['_OCRRESPONSE']._serialized_end=173
+  _globals['_OCRLINE']._serialized_start=175
+  _globals['_OCRLINE']._serialized_end=251
+  _globals['_BOUNDINGBOX']._serialized_start=253
+  _globals['_BOUNDINGBOX']._serialized_end=319
+  _globals['_OCRCHUNK']._serialized_start=321
+  _globals['_OCRCHUNK']._serialized_end=412
+  _globals['_OCRPAGERESULT']._serialized_start=414
+  _globals['_OCRPAGERESULT']._serialized_end=479
+  _globals['_OCRSERVICE']._serialized_start=481
+  _globals['_OCRSERVICE']._serialized_end=607
 # @@protoc_insertion_point(module_scope)
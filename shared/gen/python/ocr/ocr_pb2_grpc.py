@@ -39,6 +39,11 @@ class OCRServiceStub(object):
                 request_serializer=ocr_dot_ocr__pb2.OCRRequest.SerializeToString,
                 response_deserializer=ocr_dot_ocr__pb2.OCRResponse.FromString,
                 _registered_method=True)
+        self.RecognizeStream = channel.stream_stream(
+                '/ocrv1.OCRService/RecognizeStream',
+                request_serializer=ocr_dot_ocr__pb2.OCRChunk.SerializeToString,
+                response_deserializer=ocr_dot_ocr__pb2.OCRPageResult.FromString,
+                _registered_method=True)
 
 
 class OCRServiceServicer(object):
@@ -50,6 +55,13 @@ class OCRServiceServicer(object):
         context.set_details('Method not implemented!')
         raise NotImplementedError('Method not implemented!')
 
+    def RecognizeStream(self, request_iterator, context):
+        """Pages are uploaded in chunks, every page is answered as soon as it is recognized.
+        """
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details('Method not implemented!')
+        raise NotImplementedError('Method not implemented!')
+
 
 def add_OCRServiceServicer_to_server(servicer, server):
     rpc_method_handlers = {
@@ -58,6 +70,11 @@ def add_OCRServiceServicer_to_server(servicer, server):
                     request_deserializer=ocr_dot_ocr__pb2.OCRRequest.FromString,
                     response_serializer=ocr_dot_ocr__pb2.OCRResponse.SerializeToString,
             ),
+            'RecognizeStream': grpc.stream_stream_rpc_method_handler(
+                    servicer.RecognizeStream,
+                    request_deserializer=ocr_dot_ocr__pb2.OCRChunk.FromString,
+                    response_serializer=ocr_dot_ocr__pb2.OCRPageResult.SerializeToString,
+            ),
     }
     generic_handler = grpc.method_handlers_generic_handler(
             'ocrv1.OCRService', rpc_method_handlers)
@@ -95,3 +112,30 @@ class OCRService(object):
             timeout,
             metadata,
             _registered_method=True)
+
+    @staticmethod
+    def RecognizeStream(request_iterator,
+            target,
+            options=(),
+            channel_credentials=None,
+            call_credentials=None,
+            insecure=False,
+            compression=None,
+            wait_for_ready=None,
+            timeout=None,
+            metadata=None):
+        return grpc.experimental.stream_stream(
+            request_iterator,
+            target,
+            '/ocrv1.OCRService/RecognizeStream',
+            ocr_dot_ocr__pb2.OCRChunk.SerializeToString,
+            ocr_dot_ocr__pb2.OCRPageResult.FromString,
+            options,
+            channel_credentials,
+            insecure,
+            call_credentials,
+            compression,
+            wait_for_ready,
+            timeout,
+            metadata,
+            _registered_method=True)